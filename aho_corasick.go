@@ -0,0 +1,130 @@
+package pirsch
+
+// ahoCorasick is an Aho-Corasick automaton to find which of a list of keywords a string contains in a single pass.
+// The automaton is compiled into a deterministic state machine over the bytes used in the keywords,
+// so that matching requires a single table lookup per byte of the input.
+type ahoCorasick struct {
+	keywords []string
+
+	// classes maps each byte to its index in the transition table, 0 is used for bytes not contained in any keyword
+	classes    [256]uint16
+	classCount int
+
+	// transitions contains the next state for each state and class (state*classCount+class)
+	transitions []int32
+
+	// matches contains the lowest index of a keyword ending in each state, or -1 if none
+	matches []int32
+}
+
+// newAhoCorasick compiles an ahoCorasick automaton for given keywords.
+// Empty keywords are ignored.
+func newAhoCorasick(keywords []string) *ahoCorasick {
+	ac := &ahoCorasick{
+		keywords:   keywords,
+		classCount: 1,
+	}
+
+	for _, keyword := range keywords {
+		for i := 0; i < len(keyword); i++ {
+			if ac.classes[keyword[i]] == 0 {
+				ac.classes[keyword[i]] = uint16(ac.classCount)
+				ac.classCount++
+			}
+		}
+	}
+
+	// build the trie, -1 marks missing transitions
+	ac.addState()
+
+	for i, keyword := range keywords {
+		if keyword == "" {
+			continue
+		}
+
+		state := int32(0)
+
+		for j := 0; j < len(keyword); j++ {
+			i := int(state)*ac.classCount + int(ac.classes[keyword[j]])
+
+			if ac.transitions[i] == -1 {
+				next := ac.addState()
+				ac.transitions[i] = next
+			}
+
+			state = ac.transitions[i]
+		}
+
+		if ac.matches[state] == -1 {
+			ac.matches[state] = int32(i)
+		}
+	}
+
+	// turn the trie into a state machine by following the failure links in breadth-first order
+	fail := make([]int32, len(ac.matches))
+	queue := make([]int32, 0, len(ac.matches))
+
+	for c := 0; c < ac.classCount; c++ {
+		if next := ac.transitions[c]; next == -1 {
+			ac.transitions[c] = 0
+		} else {
+			queue = append(queue, next)
+		}
+	}
+
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		if m := ac.matches[fail[state]]; m != -1 && (ac.matches[state] == -1 || m < ac.matches[state]) {
+			ac.matches[state] = m
+		}
+
+		for c := 0; c < ac.classCount; c++ {
+			i := int(state)*ac.classCount + c
+			fallback := ac.transitions[int(fail[state])*ac.classCount+c]
+
+			if next := ac.transitions[i]; next == -1 {
+				ac.transitions[i] = fallback
+			} else {
+				fail[next] = fallback
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return ac
+}
+
+func (ac *ahoCorasick) addState() int32 {
+	for c := 0; c < ac.classCount; c++ {
+		ac.transitions = append(ac.transitions, -1)
+	}
+
+	ac.matches = append(ac.matches, -1)
+	return int32(len(ac.matches) - 1)
+}
+
+// match returns the first keyword (in order of the list) contained in given string, or an empty string if none matches.
+func (ac *ahoCorasick) match(str string) string {
+	best := int32(-1)
+	state := int32(0)
+
+	for i := 0; i < len(str); i++ {
+		state = ac.transitions[int(state)*ac.classCount+int(ac.classes[str[i]])]
+
+		if m := ac.matches[state]; m != -1 && (best == -1 || m < best) {
+			best = m
+
+			if best == 0 {
+				break
+			}
+		}
+	}
+
+	if best == -1 {
+		return ""
+	}
+
+	return ac.keywords[best]
+}
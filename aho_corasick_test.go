@@ -0,0 +1,65 @@
+package pirsch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestAhoCorasick(t *testing.T) {
+	ac := newAhoCorasick([]string{"hers", "he", "she", "his", "", "he"})
+	assert.Equal(t, "hers", ac.match("ushers"), "the first keyword in the list must be returned")
+	assert.Equal(t, "he", ac.match("ashe"))
+	assert.Equal(t, "she", newAhoCorasick([]string{"she", "he"}).match("ashe"))
+	assert.Equal(t, "his", ac.match("ahisb"))
+	assert.Equal(t, "hers", ac.match("hers"))
+	assert.Empty(t, ac.match("h"))
+	assert.Empty(t, ac.match(""))
+	assert.Empty(t, newAhoCorasick(nil).match("test"))
+}
+
+func TestAhoCorasickUserAgentBlacklist(t *testing.T) {
+	ac := newAhoCorasick(userAgentBlacklist)
+	userAgents := make([]string, 0, len(userAgentBlacklist)+len(userAgentsAll)+len(userAgentsRules))
+
+	for _, keyword := range userAgentBlacklist {
+		userAgents = append(userAgents, "prefix "+keyword+" suffix")
+	}
+
+	for _, ua := range append(userAgentsAll, userAgentsRules...) {
+		userAgents = append(userAgents, strings.ToLower(ua.ua))
+	}
+
+	for _, ua := range userAgents {
+		assert.Equal(t, matchBotUserAgentContains(ua), ac.match(ua), ua)
+	}
+}
+
+func BenchmarkMatchBotUserAgent(b *testing.B) {
+	userAgents := []string{
+		strings.ToLower("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36"),
+		strings.ToLower("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"),
+		strings.ToLower("Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"),
+	}
+	b.Run("Contains", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			matchBotUserAgentContains(userAgents[i%len(userAgents)])
+		}
+	})
+	b.Run("AhoCorasick", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			matchBotUserAgent(userAgents[i%len(userAgents)])
+		}
+	})
+}
+
+// matchBotUserAgentContains is the reference implementation of matchBotUserAgent.
+func matchBotUserAgentContains(userAgent string) string {
+	for _, botUserAgent := range userAgentBlacklist {
+		if strings.Contains(userAgent, botUserAgent) {
+			return botUserAgent
+		}
+	}
+
+	return ""
+}
@@ -2,33 +2,28 @@ package pirsch
 
 import (
 	"errors"
-	"fmt"
+	"reflect"
+	"sort"
 	"time"
 )
 
-const (
-	byAttributeQuery = `SELECT "%s", count(DISTINCT fingerprint) visitors, visitors / greatest((
-			SELECT count(DISTINCT fingerprint)
-			FROM hit
-			WHERE %s
-		), 1) relative_visitors
-		FROM %s
-		WHERE %s
-		GROUP BY "%s"
-		ORDER BY visitors DESC, "%s" ASC
-		%s`
-)
-
 var (
 	// ErrNoPeriodOrDay is returned in case no period or day was specified to calculate the growth rate.
 	ErrNoPeriodOrDay = errors.New("no period or day specified")
 )
 
 type growthStats struct {
+	Day       time.Time `json:"day"`
+	Visitors  int       `json:"visitors"`
+	Views     int       `json:"views"`
+	Sessions  int       `json:"sessions"`
+	Bounces   int       `json:"bounces"`
+	TimeSpent int       `db:"-" json:"time_spent"`
+}
+
+type totalStats struct {
 	Visitors int `json:"visitors"`
 	Views    int `json:"views"`
-	Sessions int `json:"sessions"`
-	Bounces  int `json:"bounces"`
 }
 
 // Analyzer provides an interface to analyze statistics.
@@ -48,67 +43,52 @@ func NewAnalyzer(store Store) *Analyzer {
 func (analyzer *Analyzer) ActiveVisitors(filter *Filter, duration time.Duration) ([]ActiveVisitorStats, int, error) {
 	filter = analyzer.getFilter(filter)
 	filter.Start = time.Now().UTC().Add(-duration)
-	args, filterQuery := filter.query()
-	title, orderByTitle := "", ""
+	query := &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionPath},
+		Metrics:    []Metric{MetricVisitors},
+		OrderBy:    []Order{{Metric: MetricVisitors, Desc: true}, {Dimension: DimensionPath}},
+	}
 
 	if filter.IncludeTitle {
-		title = ",title"
-		orderByTitle = ",title ASC"
+		query.Dimensions = append(query.Dimensions, DimensionTitle)
+		query.OrderBy = append(query.OrderBy, Order{Dimension: DimensionTitle})
 	}
 
-	query := fmt.Sprintf(`SELECT path %s, count(DISTINCT fingerprint) visitors
-		FROM hit
-		WHERE %s
-		GROUP BY path %s
-		ORDER BY visitors DESC, path ASC %s`, title, filterQuery, title, orderByTitle)
 	var stats []ActiveVisitorStats
 
-	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+	if err := analyzer.store.Query(&stats, query); err != nil {
 		return nil, 0, err
 	}
 
-	query = fmt.Sprintf(`SELECT count(DISTINCT fingerprint) visitors FROM hit WHERE %s`, filterQuery)
-	count, err := analyzer.store.Count(query, args...)
+	total, err := analyzer.total(filter)
 
 	if err != nil {
 		return nil, 0, err
 	}
 
-	return stats, count, nil
+	return stats, total.Visitors, nil
 }
 
 // Visitors returns the visitor count, session count, bounce rate, views, and average session duration grouped by day.
 func (analyzer *Analyzer) Visitors(filter *Filter) ([]VisitorStats, error) {
 	filter = analyzer.getFilter(filter)
-	args, filterQuery := filter.query()
-	withFillArgs, withFillQuery := filter.withFill()
-	args = append(args, withFillArgs...)
-	timezone := filter.Timezone.String()
-	query := fmt.Sprintf(`SELECT day,
-		sum(visitors) visitors,
-		sum(sessions) sessions,
-		sum(views) views,
-		countIf(bounce = 1) bounces,
-		bounces / IF(visitors = 0, 1, visitors) bounce_rate
-		FROM (
-			SELECT toDate(time, '%s') day,
-			count(DISTINCT fingerprint) visitors,
-			count(DISTINCT(fingerprint, session)) sessions,
-			count(*) views,
-			length(groupArray(path)) = 1 bounce
-			FROM %s
-			WHERE %s
-			GROUP BY toDate(time, '%s'), fingerprint
-		)
-		GROUP BY day
-		ORDER BY day ASC %s, visitors DESC`, timezone, filter.table(), filterQuery, timezone, withFillQuery)
 	var stats []VisitorStats
 
-	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+	if err := analyzer.store.Query(&stats, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionDay},
+		Metrics:    []Metric{MetricVisitors, MetricSessions, MetricViews, MetricBounces},
+		OrderBy:    []Order{{Dimension: DimensionDay}, {Metric: MetricVisitors, Desc: true}},
+	}); err != nil {
 		return nil, err
 	}
 
-	return stats, nil
+	for i := range stats {
+		stats[i].BounceRate = relative(stats[i].Bounces, stats[i].Visitors)
+	}
+
+	return fillVisitorStats(stats, filter), nil
 }
 
 // Growth returns the growth rate for visitor count, session count, bounces, views, and average session duration or average time on page (if path is set).
@@ -121,52 +101,7 @@ func (analyzer *Analyzer) Growth(filter *Filter) (*Growth, error) {
 		return nil, ErrNoPeriodOrDay
 	}
 
-	table := filter.table()
-	args, filterQuery := filter.query()
-	var query string
-
-	if table == "hit" {
-		query = fmt.Sprintf(`SELECT sum(visitors) visitors,
-			sum(sessions) sessions,
-			sum(views) views,
-			countIf(bounce = 1) bounces
-			FROM (
-				SELECT count(DISTINCT fingerprint) visitors,
-				count(DISTINCT(fingerprint, session)) sessions,
-				count(*) views,
-				length(groupArray(path)) = 1 bounce
-				FROM hit
-				WHERE %s
-				GROUP BY toDate(time, '%s'), fingerprint
-			)`, filterQuery, filter.Timezone.String())
-	} else {
-		query = fmt.Sprintf(`SELECT sum(visitors) visitors,
-			sum(views) views
-			FROM (
-				SELECT count(DISTINCT fingerprint) visitors,
-				count(*) views
-				FROM event
-				WHERE %s
-				GROUP BY toDate(time, '%s'), fingerprint
-			)`, filterQuery, filter.Timezone.String())
-	}
-
-	current := new(growthStats)
-
-	if err := analyzer.store.Get(current, query, args...); err != nil {
-		return nil, err
-	}
-
-	var currentTimeSpent int
-	var err error
-
-	if table == "hit" {
-		if filter.Path == "" {
-			currentTimeSpent, err = analyzer.TotalSessionDuration(filter)
-		} else {
-			currentTimeSpent, err = analyzer.TotalTimeOnPage(filter)
-		}
-	}
+	current, err := analyzer.growthStats(filter)
 
 	if err != nil {
 		return nil, err
@@ -180,22 +115,7 @@ func (analyzer *Analyzer) Growth(filter *Filter) (*Growth, error) {
 		filter.Day = filter.Day.Add(-time.Hour * 24)
 	}
 
-	args, _ = filter.query()
-	previous := new(growthStats)
-
-	if err := analyzer.store.Get(previous, query, args...); err != nil {
-		return nil, err
-	}
-
-	var previousTimeSpent int
-
-	if table == "hit" {
-		if filter.Path == "" {
-			previousTimeSpent, err = analyzer.TotalSessionDuration(filter)
-		} else {
-			previousTimeSpent, err = analyzer.TotalTimeOnPage(filter)
-		}
-	}
+	previous, err := analyzer.growthStats(filter)
 
 	if err != nil {
 		return nil, err
@@ -206,86 +126,66 @@ func (analyzer *Analyzer) Growth(filter *Filter) (*Growth, error) {
 		ViewsGrowth:     analyzer.calculateGrowth(current.Views, previous.Views),
 		SessionsGrowth:  analyzer.calculateGrowth(current.Sessions, previous.Sessions),
 		BouncesGrowth:   analyzer.calculateGrowth(current.Bounces, previous.Bounces),
-		TimeSpentGrowth: analyzer.calculateGrowth(currentTimeSpent, previousTimeSpent),
+		TimeSpentGrowth: analyzer.calculateGrowth(current.TimeSpent, previous.TimeSpent),
 	}, nil
 }
 
 // VisitorHours returns the visitor count grouped by time of day.
 func (analyzer *Analyzer) VisitorHours(filter *Filter) ([]VisitorHourStats, error) {
 	filter = analyzer.getFilter(filter)
-	args, filterQuery := filter.query()
-	query := fmt.Sprintf(`SELECT toHour(time, '%s') hour, count(DISTINCT fingerprint) visitors
-		FROM %s
-		WHERE %s
-		GROUP BY hour
-		ORDER BY hour WITH FILL FROM 0 TO 24`, filter.Timezone.String(), filter.table(), filterQuery)
 	var stats []VisitorHourStats
 
-	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+	if err := analyzer.store.Query(&stats, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionHour},
+		Metrics:    []Metric{MetricVisitors},
+		OrderBy:    []Order{{Dimension: DimensionHour}},
+	}); err != nil {
 		return nil, err
 	}
 
-	return stats, nil
+	return fillVisitorHourStats(stats), nil
 }
 
 // Pages returns the visitor count, session count, bounce rate, views, and average time on page grouped by path and (optional) page title.
 func (analyzer *Analyzer) Pages(filter *Filter) ([]PageStats, error) {
 	filter = analyzer.getFilter(filter)
-	table := filter.table()
-	filterArgs, filterQuery := filter.query()
-	filter.EventName = ""
-	relativeFilterArgs, relativeFilterQuery := filter.query()
-	title, titleGroupBy, titleOrderBy := "", "", ""
+	query := &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionPath},
+		Metrics:    []Metric{MetricVisitors, MetricSessions, MetricViews, MetricBounces},
+		OrderBy:    []Order{{Metric: MetricVisitors, Desc: true}},
+		Limit:      filter.Limit,
+	}
 
 	if filter.IncludeTitle {
-		title = "title,"
-		titleGroupBy = ",title"
-		titleOrderBy = "title ASC,"
-	}
-
-	query := fmt.Sprintf(`SELECT path,
-		%s
-		sum(visitors) visitors,
-		visitors / greatest((
-			SELECT count(DISTINCT fingerprint)
-			FROM %s
-			WHERE %s
-		), 1) relative_visitors,
-		sum(sessions) sessions,
-		sum(views) views,
-		views / greatest((
-			SELECT count(*)
-			FROM %s
-			WHERE %s
-		), 1) relative_views,
-		countIf(bounce = 1) bounces,
-		bounces / IF(visitors = 0, 1, visitors) bounce_rate
-		FROM (
-			SELECT path,
-			%s
-			count(DISTINCT fingerprint) visitors,
-			count(DISTINCT(fingerprint, session)) sessions,
-			count(*) views,
-			length(groupArray(path)) = 1 bounce
-			FROM %s
-			WHERE %s
-			GROUP BY path, %s fingerprint
-		)
-		GROUP BY path %s
-		ORDER BY visitors DESC, %s path ASC
-		%s`, title, table, relativeFilterQuery, table, relativeFilterQuery, title, table, filterQuery, title, titleGroupBy, titleOrderBy, filter.withLimit())
-	args := make([]interface{}, 0, len(filterArgs)*3)
-	args = append(args, relativeFilterArgs...)
-	args = append(args, relativeFilterArgs...)
-	args = append(args, filterArgs...)
+		query.Dimensions = append(query.Dimensions, DimensionTitle)
+		query.OrderBy = append(query.OrderBy, Order{Dimension: DimensionTitle})
+	}
+
+	query.OrderBy = append(query.OrderBy, Order{Dimension: DimensionPath})
 	var stats []PageStats
 
-	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+	if err := analyzer.store.Query(&stats, query); err != nil {
+		return nil, err
+	}
+
+	relativeFilter := *filter
+	relativeFilter.EventName = ""
+	total, err := analyzer.total(&relativeFilter)
+
+	if err != nil {
 		return nil, err
 	}
 
+	for i := range stats {
+		stats[i].RelativeVisitors = relative(stats[i].Visitors, total.Visitors)
+		stats[i].RelativeViews = relative(stats[i].Views, total.Views)
+		stats[i].BounceRate = relative(stats[i].Bounces, stats[i].Visitors)
+	}
+
 	// select average time on page if set and we do not read results from the events table
-	if filter.IncludeAvgTimeOnPage && table == "hit" {
+	if filter.IncludeAvgTimeOnPage && filter.table() == "hit" {
 		timeOnPage, err := analyzer.AvgTimeOnPages(filter)
 
 		if err != nil {
@@ -308,60 +208,16 @@ func (analyzer *Analyzer) Pages(filter *Filter) ([]PageStats, error) {
 // EntryPages returns the visitor count and time on page grouped by path and (optional) page title for the first page visited.
 func (analyzer *Analyzer) EntryPages(filter *Filter) ([]EntryStats, error) {
 	filter = analyzer.getFilter(filter)
-	var path, pathFilter string
-
-	if filter.Path != "" {
-		path = filter.Path
-		pathFilter = "AND path = ?"
-		filter.Path = ""
-	}
-
-	filterArgs, filterQuery := filter.query()
-
-	if path != "" {
-		filterArgs = append(filterArgs, path)
-	}
-
-	title, titleInner, titleOrderBy := "", "", ""
-
-	if filter.IncludeTitle {
-		title = "title,"
-		titleInner = ",title"
-		titleOrderBy = "title ASC,"
-	}
-
-	query := fmt.Sprintf(`SELECT *
-		FROM (
-			SELECT path,
-			%s
-			count(DISTINCT fingerprint) visitors,
-			countIf(prev_fingerprint != fingerprint) entries
-			FROM (
-				SELECT fingerprint,
-				"session",
-				path,
-				%s
-				neighbor("fingerprint", -1) prev_fingerprint
-				FROM (
-					SELECT fingerprint, "session", path %s
-					FROM %s
-					WHERE %s
-					ORDER BY fingerprint, "time"
-				)
-			)
-			GROUP BY path %s
-		)
-		WHERE entries > 0 %s
-		ORDER BY entries DESC, %s path ASC
-		%s`, title, title, titleInner, filter.table(), filterQuery, titleInner, pathFilter, titleOrderBy, filter.withLimit())
 	var stats []EntryStats
 
-	if err := analyzer.store.Select(&stats, query, filterArgs...); err != nil {
+	if err := analyzer.store.Query(&stats, analyzer.entryExitQuery(filter, MetricEntries)); err != nil {
 		return nil, err
 	}
 
 	if filter.IncludeAvgTimeOnPage {
-		timeOnPage, err := analyzer.AvgTimeOnPages(filter)
+		timeOnPageFilter := *filter
+		timeOnPageFilter.Path = ""
+		timeOnPage, err := analyzer.AvgTimeOnPages(&timeOnPageFilter)
 
 		if err != nil {
 			return nil, err
@@ -383,59 +239,16 @@ func (analyzer *Analyzer) EntryPages(filter *Filter) ([]EntryStats, error) {
 // ExitPages returns the visitor count and time on page grouped by path and (optional) page title for the last page visited.
 func (analyzer *Analyzer) ExitPages(filter *Filter) ([]ExitStats, error) {
 	filter = analyzer.getFilter(filter)
-	var path, pathFilter string
-
-	if filter.Path != "" {
-		path = filter.Path
-		pathFilter = "AND path = ?"
-		filter.Path = ""
-	}
-
-	filterArgs, filterQuery := filter.query()
-
-	if path != "" {
-		filterArgs = append(filterArgs, path)
-	}
-
-	title, titleInner, titleOrderBy := "", "", ""
-
-	if filter.IncludeTitle {
-		title = "title,"
-		titleInner = ",title"
-		titleOrderBy = "title ASC,"
-	}
-
-	query := fmt.Sprintf(`SELECT *
-		FROM (
-			SELECT path,
-			%s
-			count(DISTINCT fingerprint) visitors,
-			countIf(next_fingerprint != fingerprint) exits,
-			exits/visitors exit_rate
-			FROM (
-				SELECT fingerprint,
-				"session",
-				path,
-				%s
-				neighbor("fingerprint", 1) next_fingerprint
-				FROM (
-					SELECT fingerprint, "session", path %s
-					FROM %s
-					WHERE %s
-					ORDER BY fingerprint, "time"
-				)
-			)
-			GROUP BY path %s
-		)
-		WHERE exits > 0 %s
-		ORDER BY exits DESC, %s path ASC
-		%s`, title, title, titleInner, filter.table(), filterQuery, titleInner, pathFilter, titleOrderBy, filter.withLimit())
 	var stats []ExitStats
 
-	if err := analyzer.store.Select(&stats, query, filterArgs...); err != nil {
+	if err := analyzer.store.Query(&stats, analyzer.entryExitQuery(filter, MetricExits)); err != nil {
 		return nil, err
 	}
 
+	for i := range stats {
+		stats[i].ExitRate = relative(stats[i].Exits, stats[i].Visitors)
+	}
+
 	return stats, nil
 }
 
@@ -443,72 +256,78 @@ func (analyzer *Analyzer) ExitPages(filter *Filter) ([]ExitStats, error) {
 // This function is supposed to be used with the Filter.PathPattern, to list page conversions.
 func (analyzer *Analyzer) PageConversions(filter *Filter) (*PageConversionsStats, error) {
 	filter = analyzer.getFilter(filter)
-	table := filter.table()
-	filterArgsPath, filterQueryPath := filter.query()
-	filter.PathPattern = ""
-	filter.EventName = ""
-	filterArgs, filterQuery := filter.query()
-	query := fmt.Sprintf(`SELECT sum(visitors) visitors,
-		sum(views) views,
-		visitors / greatest((
-			SELECT count(DISTINCT fingerprint)
-			FROM hit
-			WHERE %s
-		), 1) cr
-		FROM (
-			SELECT count(DISTINCT fingerprint) visitors,
-			count(*) views
-			FROM %s
-			WHERE %s
-		)
-		ORDER BY visitors DESC`, filterQuery, table, filterQueryPath)
-	args := make([]interface{}, 0, len(filterArgs)+len(filterArgsPath))
-	args = append(args, filterArgs...)
-	args = append(args, filterArgsPath...)
-	stats := new(PageConversionsStats)
-
-	if err := analyzer.store.Get(stats, query, args...); err != nil {
+	stats, err := analyzer.total(filter)
+
+	if err != nil {
 		return nil, err
 	}
 
-	return stats, nil
+	totalFilter := *filter
+	totalFilter.PathPattern = ""
+	totalFilter.EventName = ""
+	total, err := analyzer.total(&totalFilter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &PageConversionsStats{
+		Visitors: stats.Visitors,
+		Views:    stats.Views,
+		CR:       relative(stats.Visitors, total.Visitors),
+	}, nil
 }
 
 // Events returns the visitor count, views, and conversion rate for custom events.
 func (analyzer *Analyzer) Events(filter *Filter) ([]EventStats, error) {
 	filter = analyzer.getFilter(filter)
-	filterArgs, filterQuery := filter.query()
-	filter.EventName = ""
-	crFilterArgs, crFilterQuery := filter.query()
-	query := fmt.Sprintf(`SELECT event_name,
-		sum(visitors) visitors,
-		sum(views) views,
-		visitors / greatest((
-			SELECT count(DISTINCT fingerprint)
-			FROM hit
-			WHERE %s
-		), 1) cr,
-		toUInt64(avg(avg_duration)) average_duration_seconds,
-		groupUniqArrayArray(meta_keys) meta_keys
-		FROM (
-			SELECT event_name,
-			groupUniqArrayArray(event_meta_keys) meta_keys,
-			count(DISTINCT fingerprint) visitors,
-			count(*) views,
-			avg(event_duration_seconds) avg_duration
-			FROM event
-			WHERE %s
-			GROUP BY event_name
-		)
-		GROUP BY event_name
-		ORDER BY visitors DESC, event_name
-		%s`, crFilterQuery, filterQuery, filter.withLimit())
-	args := make([]interface{}, 0, len(filterArgs)*2)
-	args = append(args, crFilterArgs...)
-	args = append(args, filterArgs...)
 	var stats []EventStats
 
-	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+	if err := analyzer.store.Query(&stats, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionEventName},
+		Metrics:    []Metric{MetricVisitors, MetricViews, MetricAvgEventDuration, MetricEventMetaKeys},
+		OrderBy:    []Order{{Metric: MetricVisitors, Desc: true}, {Dimension: DimensionEventName}},
+		Limit:      filter.Limit,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := analyzer.setConversionRate(stats, filter); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Bots returns the number of requests recorded for each bot reason and rule (see TrackerConfig.RecordBots).
+// Only the client, time range, path, and limit of the Filter are applied.
+func (analyzer *Analyzer) Bots(filter *Filter) ([]BotStats, error) {
+	filter = analyzer.getFilter(filter)
+	botFilter := &Filter{
+		ClientID:    filter.ClientID,
+		Timezone:    filter.Timezone,
+		From:        filter.From,
+		To:          filter.To,
+		Day:         filter.Day,
+		Start:       filter.Start,
+		Path:        filter.Path,
+		PathPattern: filter.PathPattern,
+		Limit:       filter.Limit,
+	}
+	var stats []BotStats
+
+	if err := analyzer.store.Query(&stats, &Query{
+		Filter:     botFilter,
+		Dimensions: []Dimension{DimensionBotReason, DimensionBotRule},
+		Metrics:    []Metric{MetricViews},
+		OrderBy: []Order{
+			{Metric: MetricViews, Desc: true},
+			{Dimension: DimensionBotReason},
+			{Dimension: DimensionBotRule},
+		},
+		Limit: botFilter.Limit,
+	}); err != nil {
 		return nil, err
 	}
 
@@ -524,41 +343,19 @@ func (analyzer *Analyzer) EventBreakdown(filter *Filter) ([]EventStats, error) {
 		return []EventStats{}, nil
 	}
 
-	filterArgs, filterQuery := filter.query()
-	filter.EventName = ""
-	crFilterArgs, crFilterQuery := filter.query()
-	query := fmt.Sprintf(`SELECT event_name,
-		sum(visitors) visitors,
-		sum(views) views,
-		visitors / greatest((
-			SELECT count(DISTINCT fingerprint)
-			FROM hit
-			WHERE %s
-		), 1) cr,
-		toUInt64(avg(avg_duration)) average_duration_seconds,
-		meta_value
-		FROM (
-			SELECT event_name,
-			count(DISTINCT fingerprint) visitors,
-			count(*) views,
-			avg(event_duration_seconds) avg_duration,
-			event_meta_values[indexOf(event_meta_keys, ?)] meta_value
-			FROM event
-			WHERE %s
-			AND has(event_meta_keys, ?)
-			GROUP BY event_name, meta_value
-		)
-		GROUP BY event_name, meta_value
-		ORDER BY visitors DESC, meta_value
-		%s`, crFilterQuery, filterQuery, filter.withLimit())
-	args := make([]interface{}, 0, len(filterArgs)*2)
-	args = append(args, crFilterArgs...)
-	args = append(args, filter.EventMetaKey)
-	args = append(args, filterArgs...)
-	args = append(args, filter.EventMetaKey)
 	var stats []EventStats
 
-	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+	if err := analyzer.store.Query(&stats, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionEventName, DimensionEventMetaValue},
+		Metrics:    []Metric{MetricVisitors, MetricViews, MetricAvgEventDuration},
+		OrderBy:    []Order{{Metric: MetricVisitors, Desc: true}, {Dimension: DimensionEventMetaValue}},
+		Limit:      filter.Limit,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := analyzer.setConversionRate(stats, filter); err != nil {
 		return nil, err
 	}
 
@@ -568,81 +365,94 @@ func (analyzer *Analyzer) EventBreakdown(filter *Filter) ([]EventStats, error) {
 // Referrer returns the visitor count and bounce rate grouped by referrer.
 func (analyzer *Analyzer) Referrer(filter *Filter) ([]ReferrerStats, error) {
 	filter = analyzer.getFilter(filter)
-	table := filter.table()
-	args, filterQuery := filter.query()
-	filter.EventName = ""
-	relativeFilterArgs, relativeFilterQuery := filter.query()
-	query := fmt.Sprintf(`SELECT referrer,
-		referrer_name,
-		referrer_icon,
-		sum(visitors) visitors,
-		visitors / greatest((
-			SELECT count(DISTINCT fingerprint)
-			FROM hit
-			WHERE %s
-		), 1) relative_visitors,
-		countIf(bounce = 1) bounces,
-		bounces / IF(visitors = 0, 1, visitors) bounce_rate
-		FROM (
-			SELECT count(DISTINCT fingerprint) visitors,
-			referrer,
-			referrer_name,
-			referrer_icon,
-			length(groupArray(path)) = 1 bounce
-			FROM %s
-			WHERE %s
-			GROUP BY fingerprint, referrer, referrer_name, referrer_icon
-		)
-		GROUP BY referrer, referrer_name, referrer_icon
-		ORDER BY visitors DESC
-		%s`, relativeFilterQuery, table, filterQuery, filter.withLimit())
-	relativeFilterArgs = append(relativeFilterArgs, args...)
 	var stats []ReferrerStats
 
-	if err := analyzer.store.Select(&stats, query, relativeFilterArgs...); err != nil {
+	if err := analyzer.store.Query(&stats, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionReferrer, DimensionReferrerName, DimensionReferrerIcon},
+		Metrics:    []Metric{MetricVisitors, MetricBounces},
+		OrderBy:    []Order{{Metric: MetricVisitors, Desc: true}, {Dimension: DimensionReferrer}},
+		Limit:      filter.Limit,
+	}); err != nil {
+		return nil, err
+	}
+
+	relativeFilter := *filter
+	relativeFilter.EventName = ""
+	total, err := analyzer.total(&relativeFilter)
+
+	if err != nil {
 		return nil, err
 	}
 
+	for i := range stats {
+		stats[i].RelativeVisitors = relative(stats[i].Visitors, total.Visitors)
+		stats[i].BounceRate = relative(stats[i].Bounces, stats[i].Visitors)
+	}
+
 	return stats, nil
 }
 
-// Platform returns the visitor count grouped by platform.
+// Platform returns the visitor count grouped by platform and device type.
 func (analyzer *Analyzer) Platform(filter *Filter) (*PlatformStats, error) {
-	filterArgs, filterQuery := analyzer.getFilter(filter).query()
-	table := filter.table()
-	query := fmt.Sprintf(`SELECT (
-			SELECT count(DISTINCT fingerprint)
-			FROM %s
-			WHERE %s
-			AND desktop = 1
-			AND mobile = 0
-		) AS "platform_desktop",
-		(
-			SELECT count(DISTINCT fingerprint)
-			FROM %s
-			WHERE %s
-			AND desktop = 0
-			AND mobile = 1
-		) AS "platform_mobile",
-		(
-			SELECT count(DISTINCT fingerprint)
-			FROM %s
-			WHERE %s
-			AND desktop = 0
-			AND mobile = 0
-		) AS "platform_unknown",
-		"platform_desktop" / IF("platform_desktop" + "platform_mobile" + "platform_unknown" = 0, 1, "platform_desktop" + "platform_mobile" + "platform_unknown") AS relative_platform_desktop,
-		"platform_mobile" / IF("platform_desktop" + "platform_mobile" + "platform_unknown" = 0, 1, "platform_desktop" + "platform_mobile" + "platform_unknown") AS relative_platform_mobile,
-		"platform_unknown" / IF("platform_desktop" + "platform_mobile" + "platform_unknown" = 0, 1, "platform_desktop" + "platform_mobile" + "platform_unknown") AS relative_platform_unknown`,
-		table, filterQuery, table, filterQuery, table, filterQuery)
-	args := make([]interface{}, 0, len(filterArgs)*3)
-	args = append(args, filterArgs...)
-	args = append(args, filterArgs...)
-	args = append(args, filterArgs...)
+	filter = analyzer.getFilter(filter)
+	var platforms []struct {
+		Desktop    bool
+		Mobile     bool
+		DeviceType string `db:"device_type"`
+		Visitors   int
+	}
+
+	if err := analyzer.store.Query(&platforms, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionDesktop, DimensionMobile, DimensionDeviceType},
+		Metrics:    []Metric{MetricVisitors},
+		OrderBy: []Order{
+			{Metric: MetricVisitors, Desc: true},
+			{Dimension: DimensionDeviceType},
+		},
+	}); err != nil {
+		return nil, err
+	}
+
 	stats := new(PlatformStats)
+	deviceTypes := make(map[string]int)
+	stats.DeviceTypes = make([]DeviceTypeStats, 0)
+
+	for _, platform := range platforms {
+		if platform.Desktop && !platform.Mobile {
+			stats.PlatformDesktop += platform.Visitors
+		} else if !platform.Desktop && platform.Mobile {
+			stats.PlatformMobile += platform.Visitors
+		} else if !platform.Desktop && !platform.Mobile {
+			stats.PlatformUnknown += platform.Visitors
+		}
 
-	if err := analyzer.store.Get(stats, query, args...); err != nil {
-		return nil, err
+		if i, found := deviceTypes[platform.DeviceType]; found {
+			stats.DeviceTypes[i].Visitors += platform.Visitors
+		} else {
+			deviceTypes[platform.DeviceType] = len(stats.DeviceTypes)
+			stats.DeviceTypes = append(stats.DeviceTypes, DeviceTypeStats{
+				MetaStats:  MetaStats{Visitors: platform.Visitors},
+				DeviceType: platform.DeviceType,
+			})
+		}
+	}
+
+	total := stats.PlatformDesktop + stats.PlatformMobile + stats.PlatformUnknown
+	stats.RelativePlatformDesktop = relative(stats.PlatformDesktop, total)
+	stats.RelativePlatformMobile = relative(stats.PlatformMobile, total)
+	stats.RelativePlatformUnknown = relative(stats.PlatformUnknown, total)
+	sort.Slice(stats.DeviceTypes, func(i, j int) bool {
+		if stats.DeviceTypes[i].Visitors == stats.DeviceTypes[j].Visitors {
+			return stats.DeviceTypes[i].DeviceType < stats.DeviceTypes[j].DeviceType
+		}
+
+		return stats.DeviceTypes[i].Visitors > stats.DeviceTypes[j].Visitors
+	})
+
+	for i := range stats.DeviceTypes {
+		stats.DeviceTypes[i].RelativeVisitors = relative(stats.DeviceTypes[i].Visitors, total)
 	}
 
 	return stats, nil
@@ -652,7 +462,18 @@ func (analyzer *Analyzer) Platform(filter *Filter) (*PlatformStats, error) {
 func (analyzer *Analyzer) Languages(filter *Filter) ([]LanguageStats, error) {
 	var stats []LanguageStats
 
-	if err := analyzer.selectByAttribute(&stats, filter, "language"); err != nil {
+	if err := analyzer.selectByAttribute(&stats, filter, DimensionLanguage); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Locales returns the visitor count grouped by language and region (like en-GB and en-US).
+func (analyzer *Analyzer) Locales(filter *Filter) ([]LocaleStats, error) {
+	var stats []LocaleStats
+
+	if err := analyzer.selectByVersion(&stats, filter, DimensionLanguage, DimensionLanguageRegion); err != nil {
 		return nil, err
 	}
 
@@ -663,7 +484,42 @@ func (analyzer *Analyzer) Languages(filter *Filter) ([]LanguageStats, error) {
 func (analyzer *Analyzer) Countries(filter *Filter) ([]CountryStats, error) {
 	var stats []CountryStats
 
-	if err := analyzer.selectByAttribute(&stats, filter, "country_code"); err != nil {
+	if err := analyzer.selectByAttribute(&stats, filter, DimensionCountryCode); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Regions returns the visitor count grouped by country and region.
+func (analyzer *Analyzer) Regions(filter *Filter) ([]RegionStats, error) {
+	var stats []RegionStats
+	filter = analyzer.getFilter(filter)
+	filter.EventName = ""
+
+	if err := analyzer.selectMetaStats(&stats, filter, []Dimension{DimensionCountryCode, DimensionRegion}, []Order{
+		{Metric: MetricVisitors, Desc: true},
+		{Dimension: DimensionCountryCode},
+		{Dimension: DimensionRegion},
+	}); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Cities returns the visitor count grouped by country, region, and city.
+func (analyzer *Analyzer) Cities(filter *Filter) ([]CityStats, error) {
+	var stats []CityStats
+	filter = analyzer.getFilter(filter)
+	filter.EventName = ""
+
+	if err := analyzer.selectMetaStats(&stats, filter, []Dimension{DimensionCountryCode, DimensionRegion, DimensionCity}, []Order{
+		{Metric: MetricVisitors, Desc: true},
+		{Dimension: DimensionCountryCode},
+		{Dimension: DimensionRegion},
+		{Dimension: DimensionCity},
+	}); err != nil {
 		return nil, err
 	}
 
@@ -674,7 +530,7 @@ func (analyzer *Analyzer) Countries(filter *Filter) ([]CountryStats, error) {
 func (analyzer *Analyzer) Browser(filter *Filter) ([]BrowserStats, error) {
 	var stats []BrowserStats
 
-	if err := analyzer.selectByAttribute(&stats, filter, "browser"); err != nil {
+	if err := analyzer.selectByAttribute(&stats, filter, DimensionBrowser); err != nil {
 		return nil, err
 	}
 
@@ -685,7 +541,7 @@ func (analyzer *Analyzer) Browser(filter *Filter) ([]BrowserStats, error) {
 func (analyzer *Analyzer) OS(filter *Filter) ([]OSStats, error) {
 	var stats []OSStats
 
-	if err := analyzer.selectByAttribute(&stats, filter, "os"); err != nil {
+	if err := analyzer.selectByAttribute(&stats, filter, DimensionOS); err != nil {
 		return nil, err
 	}
 
@@ -696,7 +552,7 @@ func (analyzer *Analyzer) OS(filter *Filter) ([]OSStats, error) {
 func (analyzer *Analyzer) ScreenClass(filter *Filter) ([]ScreenClassStats, error) {
 	var stats []ScreenClassStats
 
-	if err := analyzer.selectByAttribute(&stats, filter, "screen_class"); err != nil {
+	if err := analyzer.selectByAttribute(&stats, filter, DimensionScreenClass); err != nil {
 		return nil, err
 	}
 
@@ -707,7 +563,7 @@ func (analyzer *Analyzer) ScreenClass(filter *Filter) ([]ScreenClassStats, error
 func (analyzer *Analyzer) UTMSource(filter *Filter) ([]UTMSourceStats, error) {
 	var stats []UTMSourceStats
 
-	if err := analyzer.selectByAttribute(&stats, filter, "utm_source"); err != nil {
+	if err := analyzer.selectByAttribute(&stats, filter, DimensionUTMSource); err != nil {
 		return nil, err
 	}
 
@@ -718,7 +574,7 @@ func (analyzer *Analyzer) UTMSource(filter *Filter) ([]UTMSourceStats, error) {
 func (analyzer *Analyzer) UTMMedium(filter *Filter) ([]UTMMediumStats, error) {
 	var stats []UTMMediumStats
 
-	if err := analyzer.selectByAttribute(&stats, filter, "utm_medium"); err != nil {
+	if err := analyzer.selectByAttribute(&stats, filter, DimensionUTMMedium); err != nil {
 		return nil, err
 	}
 
@@ -729,7 +585,7 @@ func (analyzer *Analyzer) UTMMedium(filter *Filter) ([]UTMMediumStats, error) {
 func (analyzer *Analyzer) UTMCampaign(filter *Filter) ([]UTMCampaignStats, error) {
 	var stats []UTMCampaignStats
 
-	if err := analyzer.selectByAttribute(&stats, filter, "utm_campaign"); err != nil {
+	if err := analyzer.selectByAttribute(&stats, filter, DimensionUTMCampaign); err != nil {
 		return nil, err
 	}
 
@@ -740,7 +596,7 @@ func (analyzer *Analyzer) UTMCampaign(filter *Filter) ([]UTMCampaignStats, error
 func (analyzer *Analyzer) UTMContent(filter *Filter) ([]UTMContentStats, error) {
 	var stats []UTMContentStats
 
-	if err := analyzer.selectByAttribute(&stats, filter, "utm_content"); err != nil {
+	if err := analyzer.selectByAttribute(&stats, filter, DimensionUTMContent); err != nil {
 		return nil, err
 	}
 
@@ -751,7 +607,7 @@ func (analyzer *Analyzer) UTMContent(filter *Filter) ([]UTMContentStats, error)
 func (analyzer *Analyzer) UTMTerm(filter *Filter) ([]UTMTermStats, error) {
 	var stats []UTMTermStats
 
-	if err := analyzer.selectByAttribute(&stats, filter, "utm_term"); err != nil {
+	if err := analyzer.selectByAttribute(&stats, filter, DimensionUTMTerm); err != nil {
 		return nil, err
 	}
 
@@ -760,25 +616,9 @@ func (analyzer *Analyzer) UTMTerm(filter *Filter) ([]UTMTermStats, error) {
 
 // OSVersion returns the visitor count grouped by operating systems and version.
 func (analyzer *Analyzer) OSVersion(filter *Filter) ([]OSVersionStats, error) {
-	filter = analyzer.getFilter(filter)
-	table := filter.table()
-	args, filterQuery := filter.query()
-	filter.EventName = ""
-	relativeFilterArgs, relativeFilterQuery := filter.query()
-	query := fmt.Sprintf(`SELECT os, os_version, count(DISTINCT fingerprint) visitors, visitors / greatest((
-			SELECT count(DISTINCT fingerprint)
-			FROM hit
-			WHERE %s
-		), 1) relative_visitors
-		FROM %s
-		WHERE %s
-		GROUP BY os, os_version
-		ORDER BY visitors DESC, os, os_version
-		%s`, relativeFilterQuery, table, filterQuery, filter.withLimit())
-	relativeFilterArgs = append(relativeFilterArgs, args...)
 	var stats []OSVersionStats
 
-	if err := analyzer.store.Select(&stats, query, relativeFilterArgs...); err != nil {
+	if err := analyzer.selectByVersion(&stats, filter, DimensionOS, DimensionOSVersion); err != nil {
 		return nil, err
 	}
 
@@ -787,25 +627,9 @@ func (analyzer *Analyzer) OSVersion(filter *Filter) ([]OSVersionStats, error) {
 
 // BrowserVersion returns the visitor count grouped by browser and version.
 func (analyzer *Analyzer) BrowserVersion(filter *Filter) ([]BrowserVersionStats, error) {
-	filter = analyzer.getFilter(filter)
-	table := filter.table()
-	args, filterQuery := filter.query()
-	filter.EventName = ""
-	relativeFilterArgs, relativeFilterQuery := filter.query()
-	query := fmt.Sprintf(`SELECT browser, browser_version, count(DISTINCT fingerprint) visitors, visitors / greatest((
-			SELECT count(DISTINCT fingerprint)
-			FROM hit
-			WHERE %s
-		), 1) relative_visitors
-		FROM %s
-		WHERE %s
-		GROUP BY browser, browser_version
-		ORDER BY visitors DESC, browser, browser_version
-		%s`, relativeFilterQuery, table, filterQuery, filter.withLimit())
-	relativeFilterArgs = append(relativeFilterArgs, args...)
 	var stats []BrowserVersionStats
 
-	if err := analyzer.store.Select(&stats, query, relativeFilterArgs...); err != nil {
+	if err := analyzer.selectByVersion(&stats, filter, DimensionBrowser, DimensionBrowserVersion); err != nil {
 		return nil, err
 	}
 
@@ -815,86 +639,43 @@ func (analyzer *Analyzer) BrowserVersion(filter *Filter) ([]BrowserVersionStats,
 // AvgSessionDuration returns the average session duration grouped by day.
 func (analyzer *Analyzer) AvgSessionDuration(filter *Filter) ([]TimeSpentStats, error) {
 	filter = analyzer.getFilter(filter)
-	args, filterQuery := filter.query()
-	withFillArgs, withFillQuery := filter.withFill()
-	args = append(args, withFillArgs...)
-	query := fmt.Sprintf(`SELECT day, toUInt64(avg(duration)) average_time_spent_seconds
-			FROM (
-				SELECT toDate(time, '%s') day, max(time)-min(time) duration
-				FROM hit
-				WHERE %s
-				AND session != 0
-				GROUP BY day, fingerprint, session
-			)
-		WHERE duration != 0
-		GROUP BY day
-		ORDER BY day %s`, filter.Timezone.String(), filterQuery, withFillQuery)
 	var stats []TimeSpentStats
 
-	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+	if err := analyzer.store.Query(&stats, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionDay},
+		Metrics:    []Metric{MetricAvgSessionDuration},
+		OrderBy:    []Order{{Dimension: DimensionDay}},
+	}); err != nil {
 		return nil, err
 	}
 
-	return stats, nil
+	return fillTimeSpentStats(stats, filter), nil
 }
 
 // TotalSessionDuration returns the total session duration in seconds.
 func (analyzer *Analyzer) TotalSessionDuration(filter *Filter) (int, error) {
-	filter = analyzer.getFilter(filter)
-	args, filterQuery := filter.query()
-	query := fmt.Sprintf(`SELECT sum(duration) average_time_spent_seconds
-		FROM (
-			SELECT toDate(time, '%s') day, max(time)-min(time) duration
-			FROM hit
-			WHERE %s
-			AND session != 0
-			GROUP BY day, fingerprint, session
-		)`, filter.Timezone.String(), filterQuery)
-	stats := new(struct {
-		AverageTimeSpentSeconds int `db:"average_time_spent_seconds" json:"average_time_spent_seconds"`
-	})
-
-	if err := analyzer.store.Get(stats, query, args...); err != nil {
-		return 0, err
-	}
-
-	return stats.AverageTimeSpentSeconds, nil
+	return analyzer.totalTimeSpent(analyzer.getFilter(filter), MetricTotalSessionDuration)
 }
 
 // AvgTimeOnPages returns the average time on page grouped by path and (optional) page title.
 func (analyzer *Analyzer) AvgTimeOnPages(filter *Filter) ([]TimeSpentStats, error) {
 	filter = analyzer.getFilter(filter)
-	timeArgs, timeQuery := filter.queryTime()
-	fieldArgs, fieldQuery := filter.queryFields()
-
-	if len(fieldArgs) > 0 {
-		fieldQuery = "AND " + fieldQuery
+	query := &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionPath},
+		Metrics:    []Metric{MetricAvgTimeOnPage},
+		OrderBy:    []Order{{Dimension: DimensionPath}},
 	}
 
-	title := ""
-
 	if filter.IncludeTitle {
-		title = ",title"
-	}
-
-	query := fmt.Sprintf(`SELECT path %s, toUInt64(avg(time_on_page)) average_time_spent_seconds
-		FROM (
-			SELECT path %s, %s time_on_page
-			FROM (
-				SELECT *
-				FROM hit
-				WHERE %s
-				ORDER BY fingerprint, time
-			)
-			WHERE time_on_page > 0
-			%s
-		)
-		GROUP BY path %s
-		ORDER BY path %s`, title, title, analyzer.timeOnPageQuery(filter), timeQuery, fieldQuery, title, title)
-	timeArgs = append(timeArgs, fieldArgs...)
+		query.Dimensions = append(query.Dimensions, DimensionTitle)
+		query.OrderBy = append(query.OrderBy, Order{Dimension: DimensionTitle})
+	}
+
 	var stats []TimeSpentStats
 
-	if err := analyzer.store.Select(&stats, query, timeArgs...); err != nil {
+	if err := analyzer.store.Query(&stats, query); err != nil {
 		return nil, err
 	}
 
@@ -904,70 +685,23 @@ func (analyzer *Analyzer) AvgTimeOnPages(filter *Filter) ([]TimeSpentStats, erro
 // AvgTimeOnPage returns the average time on page grouped by day.
 func (analyzer *Analyzer) AvgTimeOnPage(filter *Filter) ([]TimeSpentStats, error) {
 	filter = analyzer.getFilter(filter)
-	timeArgs, timeQuery := filter.queryTime()
-	fieldArgs, fieldQuery := filter.queryFields()
-
-	if len(fieldArgs) > 0 {
-		fieldQuery = "AND " + fieldQuery
-	}
-
-	withFillArgs, withFillQuery := filter.withFill()
-	query := fmt.Sprintf(`SELECT day, toUInt64(avg(time_on_page)) average_time_spent_seconds
-		FROM (
-			SELECT toDate(time, '%s') day, %s time_on_page
-			FROM (
-				SELECT *
-				FROM hit
-				WHERE %s
-				ORDER BY fingerprint, time
-			)
-			WHERE time_on_page > 0
-			%s
-		)
-		GROUP BY day
-		ORDER BY day %s`, filter.Timezone.String(), analyzer.timeOnPageQuery(filter), timeQuery, fieldQuery, withFillQuery)
-	timeArgs = append(timeArgs, fieldArgs...)
-	timeArgs = append(timeArgs, withFillArgs...)
 	var stats []TimeSpentStats
 
-	if err := analyzer.store.Select(&stats, query, timeArgs...); err != nil {
+	if err := analyzer.store.Query(&stats, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionDay},
+		Metrics:    []Metric{MetricAvgTimeOnPage},
+		OrderBy:    []Order{{Dimension: DimensionDay}},
+	}); err != nil {
 		return nil, err
 	}
 
-	return stats, nil
+	return fillTimeSpentStats(stats, filter), nil
 }
 
 // TotalTimeOnPage returns the total time on page in seconds.
 func (analyzer *Analyzer) TotalTimeOnPage(filter *Filter) (int, error) {
-	filter = analyzer.getFilter(filter)
-	timeArgs, timeQuery := filter.queryTime()
-	fieldArgs, fieldQuery := filter.queryFields()
-
-	if fieldQuery != "" {
-		fieldQuery = "WHERE " + fieldQuery
-	}
-
-	query := fmt.Sprintf(`SELECT sum(time_on_page) average_time_spent_seconds
-		FROM (
-			SELECT %s time_on_page
-			FROM (
-				SELECT *
-				FROM hit
-				WHERE %s
-				ORDER BY fingerprint, time
-			)
-			%s
-		)`, analyzer.timeOnPageQuery(filter), timeQuery, fieldQuery)
-	timeArgs = append(timeArgs, fieldArgs...)
-	stats := new(struct {
-		AverageTimeSpentSeconds int `db:"average_time_spent_seconds" json:"average_time_spent_seconds"`
-	})
-
-	if err := analyzer.store.Get(stats, query, timeArgs...); err != nil {
-		return 0, err
-	}
-
-	return stats.AverageTimeSpentSeconds, nil
+	return analyzer.totalTimeSpent(analyzer.getFilter(filter), MetricTotalTimeOnPage)
 }
 
 func (analyzer *Analyzer) calculateGrowth(current, previous int) float64 {
@@ -982,24 +716,170 @@ func (analyzer *Analyzer) calculateGrowth(current, previous int) float64 {
 	return (c - p) / p
 }
 
-func (analyzer *Analyzer) timeOnPageQuery(filter *Filter) string {
-	timeOnPage := "neighbor(previous_time_on_page_seconds, 1, 0)"
+// growthStats sums up the statistics for each day, as visitors are counted once per day.
+func (analyzer *Analyzer) growthStats(filter *Filter) (*growthStats, error) {
+	query := &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionDay},
+		Metrics:    []Metric{MetricVisitors, MetricViews},
+	}
+	hits := filter.table() == "hit"
+
+	if hits {
+		query.Metrics = append(query.Metrics, MetricSessions, MetricBounces)
+	}
+
+	var days []growthStats
+
+	if err := analyzer.store.Query(&days, query); err != nil {
+		return nil, err
+	}
+
+	stats := new(growthStats)
+
+	for _, day := range days {
+		stats.Visitors += day.Visitors
+		stats.Views += day.Views
+		stats.Sessions += day.Sessions
+		stats.Bounces += day.Bounces
+	}
+
+	if hits {
+		var err error
+
+		if filter.Path == "" {
+			stats.TimeSpent, err = analyzer.TotalSessionDuration(filter)
+		} else {
+			stats.TimeSpent, err = analyzer.TotalTimeOnPage(filter)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
+
+func (analyzer *Analyzer) entryExitQuery(filter *Filter, metric Metric) *Query {
+	query := &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionPath},
+		Metrics:    []Metric{MetricVisitors, metric},
+		OrderBy:    []Order{{Metric: metric, Desc: true}},
+		Limit:      filter.Limit,
+	}
+
+	if filter.IncludeTitle {
+		query.Dimensions = append(query.Dimensions, DimensionTitle)
+		query.OrderBy = append(query.OrderBy, Order{Dimension: DimensionTitle})
+	}
+
+	query.OrderBy = append(query.OrderBy, Order{Dimension: DimensionPath})
+	return query
+}
+
+// total returns the total number of visitors and views for given filter.
+func (analyzer *Analyzer) total(filter *Filter) (*totalStats, error) {
+	var stats []totalStats
+
+	if err := analyzer.store.Query(&stats, &Query{
+		Filter:  filter,
+		Metrics: []Metric{MetricVisitors, MetricViews},
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(stats) == 0 {
+		return new(totalStats), nil
+	}
+
+	return &stats[0], nil
+}
+
+func (analyzer *Analyzer) totalTimeSpent(filter *Filter, metric Metric) (int, error) {
+	var stats []struct {
+		TotalTimeSpentSeconds int `db:"total_time_spent_seconds"`
+	}
+
+	if err := analyzer.store.Query(&stats, &Query{
+		Filter:  filter,
+		Metrics: []Metric{metric},
+	}); err != nil {
+		return 0, err
+	}
+
+	if len(stats) == 0 {
+		return 0, nil
+	}
+
+	return stats[0].TotalTimeSpentSeconds, nil
+}
+
+// setConversionRate sets the conversion rate for events relative to the total number of visitors.
+func (analyzer *Analyzer) setConversionRate(stats []EventStats, filter *Filter) error {
+	totalFilter := *filter
+	totalFilter.EventName = ""
+	total, err := analyzer.total(&totalFilter)
+
+	if err != nil {
+		return err
+	}
 
-	if filter.MaxTimeOnPageSeconds > 0 {
-		timeOnPage = fmt.Sprintf("least(neighbor(previous_time_on_page_seconds, 1, 0), %d)", filter.MaxTimeOnPageSeconds)
+	for i := range stats {
+		stats[i].CR = relative(stats[i].Visitors, total.Visitors)
 	}
 
-	return timeOnPage
+	return nil
 }
 
-func (analyzer *Analyzer) selectByAttribute(results interface{}, filter *Filter, attr string) error {
+// selectByAttribute selects the meta statistics for page views, ignoring the Filter.EventName.
+func (analyzer *Analyzer) selectByAttribute(results interface{}, filter *Filter, attr Dimension) error {
 	filter = analyzer.getFilter(filter)
-	table := filter.table()
 	filter.EventName = ""
-	args, filterQuery := filter.query()
-	query := fmt.Sprintf(byAttributeQuery, attr, filterQuery, table, filterQuery, attr, attr, filter.withLimit())
-	args = append(args, args...)
-	return analyzer.store.Select(results, query, args...)
+	return analyzer.selectMetaStats(results, filter, []Dimension{attr}, []Order{
+		{Metric: MetricVisitors, Desc: true},
+		{Dimension: attr},
+	})
+}
+
+func (analyzer *Analyzer) selectByVersion(results interface{}, filter *Filter, attr, version Dimension) error {
+	filter = analyzer.getFilter(filter)
+	return analyzer.selectMetaStats(results, filter, []Dimension{attr, version}, []Order{
+		{Metric: MetricVisitors, Desc: true},
+		{Dimension: attr},
+		{Dimension: version},
+	})
+}
+
+// selectMetaStats selects the visitors for given dimensions into results, which must be a pointer to a slice of a type embedding MetaStats.
+func (analyzer *Analyzer) selectMetaStats(results interface{}, filter *Filter, dimensions []Dimension, orderBy []Order) error {
+	if err := analyzer.store.Query(results, &Query{
+		Filter:     filter,
+		Dimensions: dimensions,
+		Metrics:    []Metric{MetricVisitors},
+		OrderBy:    orderBy,
+		Limit:      filter.Limit,
+	}); err != nil {
+		return err
+	}
+
+	relativeFilter := *filter
+	relativeFilter.EventName = ""
+	total, err := analyzer.total(&relativeFilter)
+
+	if err != nil {
+		return err
+	}
+
+	stats := reflect.ValueOf(results).Elem()
+
+	for i := 0; i < stats.Len(); i++ {
+		meta := stats.Index(i).FieldByName("MetaStats").Addr().Interface().(*MetaStats)
+		meta.RelativeVisitors = relative(meta.Visitors, total.Visitors)
+	}
+
+	return nil
 }
 
 func (analyzer *Analyzer) getFilter(filter *Filter) *Filter {
@@ -1010,3 +890,75 @@ func (analyzer *Analyzer) getFilter(filter *Filter) *Filter {
 	filter.validate()
 	return filter
 }
+
+// relative returns a divided by b, or a if b is 0.
+func relative(a, b int) float64 {
+	if b < 1 {
+		b = 1
+	}
+
+	return float64(a) / float64(b)
+}
+
+// fillVisitorStats adds the missing days in the filter period to given statistics (sorted by day).
+func fillVisitorStats(stats []VisitorStats, filter *Filter) []VisitorStats {
+	if filter.From.IsZero() || filter.To.IsZero() {
+		return stats
+	}
+
+	result := make([]VisitorStats, 0, len(stats))
+	i := 0
+
+	for day := filter.From; !day.After(filter.To); day = day.Add(time.Hour * 24) {
+		if i < len(stats) && sameDay(stats[i].Day, day) {
+			result = append(result, stats[i])
+			i++
+		} else {
+			result = append(result, VisitorStats{Day: day})
+		}
+	}
+
+	return result
+}
+
+// fillTimeSpentStats adds the missing days in the filter period to given statistics (sorted by day).
+func fillTimeSpentStats(stats []TimeSpentStats, filter *Filter) []TimeSpentStats {
+	if filter.From.IsZero() || filter.To.IsZero() {
+		return stats
+	}
+
+	result := make([]TimeSpentStats, 0, len(stats))
+	i := 0
+
+	for day := filter.From; !day.After(filter.To); day = day.Add(time.Hour * 24) {
+		if i < len(stats) && sameDay(stats[i].Day, day) {
+			result = append(result, stats[i])
+			i++
+		} else {
+			result = append(result, TimeSpentStats{Day: day})
+		}
+	}
+
+	return result
+}
+
+// fillVisitorHourStats adds the missing hours of the day to given statistics (sorted by hour).
+func fillVisitorHourStats(stats []VisitorHourStats) []VisitorHourStats {
+	result := make([]VisitorHourStats, 0, 24)
+	i := 0
+
+	for hour := 0; hour < 24; hour++ {
+		if i < len(stats) && stats[i].Hour == hour {
+			result = append(result, stats[i])
+			i++
+		} else {
+			result = append(result, VisitorHourStats{Hour: hour})
+		}
+	}
+
+	return result
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
@@ -588,6 +588,37 @@ func TestAnalyzer_Events(t *testing.T) {
 	assert.Empty(t, stats)
 }
 
+func TestAnalyzer_Bots(t *testing.T) {
+	cleanupDB()
+	assert.NoError(t, dbClient.SaveBotHits([]BotHit{
+		{Time: time.Now(), UserAgent: "Googlebot", Path: "/", Reason: IgnoreBot, Rule: "Googlebot"},
+		{Time: time.Now(), UserAgent: "Googlebot", Path: "/foo", Reason: IgnoreBot, Rule: "Googlebot"},
+		{Time: time.Now(), UserAgent: "curl/8.4.0", Path: "/", Reason: IgnoreBot, Rule: "curl"},
+		{Time: time.Now(), Path: "/", Reason: IgnoreEmptyUserAgent},
+		{Time: time.Now(), Path: "/", Reason: IgnoreEmptyUserAgent},
+		{Time: time.Now(), Path: "/", Reason: IgnoreEmptyUserAgent},
+	}))
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	bots, err := analyzer.Bots(nil)
+	assert.NoError(t, err)
+	assert.Len(t, bots, 3)
+	assert.Equal(t, IgnoreEmptyUserAgent, bots[0].Reason)
+	assert.Empty(t, bots[0].Rule)
+	assert.Equal(t, 3, bots[0].Views)
+	assert.Equal(t, IgnoreBot, bots[1].Reason)
+	assert.Equal(t, "Googlebot", bots[1].Rule)
+	assert.Equal(t, 2, bots[1].Views)
+	assert.Equal(t, "curl", bots[2].Rule)
+	assert.Equal(t, 1, bots[2].Views)
+	bots, err = analyzer.Bots(&Filter{Path: "/foo"})
+	assert.NoError(t, err)
+	assert.Len(t, bots, 1)
+	assert.Equal(t, "Googlebot", bots[0].Rule)
+	_, err = analyzer.Bots(getMaxFilter())
+	assert.NoError(t, err)
+}
+
 func TestAnalyzer_Referrer(t *testing.T) {
 	cleanupDB()
 	assert.NoError(t, dbClient.SaveHits([]Hit{
@@ -629,13 +660,13 @@ func TestAnalyzer_Referrer(t *testing.T) {
 func TestAnalyzer_Platform(t *testing.T) {
 	cleanupDB()
 	assert.NoError(t, dbClient.SaveHits([]Hit{
-		{Fingerprint: "fp1", Time: time.Now(), Desktop: true},
-		{Fingerprint: "fp1", Time: time.Now(), Desktop: true},
-		{Fingerprint: "fp1", Time: time.Now(), Mobile: true},
-		{Fingerprint: "fp2", Time: time.Now(), Mobile: true},
-		{Fingerprint: "fp2", Time: time.Now()},
-		{Fingerprint: "fp3", Time: time.Now(), Desktop: true},
-		{Fingerprint: "fp4", Time: time.Now(), Desktop: true},
+		{Fingerprint: "fp1", Time: time.Now(), Desktop: true, DeviceType: DeviceTypeDesktop},
+		{Fingerprint: "fp1", Time: time.Now(), Desktop: true, DeviceType: DeviceTypeDesktop},
+		{Fingerprint: "fp1", Time: time.Now(), Mobile: true, DeviceType: DeviceTypeTablet},
+		{Fingerprint: "fp2", Time: time.Now(), Mobile: true, DeviceType: DeviceTypeMobile},
+		{Fingerprint: "fp2", Time: time.Now(), DeviceType: DeviceTypeTV},
+		{Fingerprint: "fp3", Time: time.Now(), Desktop: true, DeviceType: DeviceTypeDesktop},
+		{Fingerprint: "fp4", Time: time.Now(), Desktop: true, DeviceType: DeviceTypeDesktop},
 	}))
 	time.Sleep(time.Millisecond * 20)
 	analyzer := NewAnalyzer(dbClient)
@@ -647,6 +678,24 @@ func TestAnalyzer_Platform(t *testing.T) {
 	assert.InDelta(t, 0.5, platform.RelativePlatformDesktop, 0.01)
 	assert.InDelta(t, 0.3333, platform.RelativePlatformMobile, 0.01)
 	assert.InDelta(t, 0.1666, platform.RelativePlatformUnknown, 0.01)
+	assert.Len(t, platform.DeviceTypes, 4)
+	assert.Equal(t, DeviceTypeDesktop, platform.DeviceTypes[0].DeviceType)
+	assert.Equal(t, DeviceTypeMobile, platform.DeviceTypes[1].DeviceType)
+	assert.Equal(t, DeviceTypeTablet, platform.DeviceTypes[2].DeviceType)
+	assert.Equal(t, DeviceTypeTV, platform.DeviceTypes[3].DeviceType)
+	assert.Equal(t, 3, platform.DeviceTypes[0].Visitors)
+	assert.Equal(t, 1, platform.DeviceTypes[1].Visitors)
+	assert.Equal(t, 1, platform.DeviceTypes[2].Visitors)
+	assert.Equal(t, 1, platform.DeviceTypes[3].Visitors)
+	assert.InDelta(t, 0.5, platform.DeviceTypes[0].RelativeVisitors, 0.01)
+	assert.InDelta(t, 0.1666, platform.DeviceTypes[1].RelativeVisitors, 0.01)
+	assert.InDelta(t, 0.1666, platform.DeviceTypes[2].RelativeVisitors, 0.01)
+	assert.InDelta(t, 0.1666, platform.DeviceTypes[3].RelativeVisitors, 0.01)
+	platform, err = analyzer.Platform(&Filter{From: pastDay(5), To: Today(), DeviceType: DeviceTypeTablet})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, platform.PlatformDesktop)
+	assert.Equal(t, 1, platform.PlatformMobile)
+	assert.Len(t, platform.DeviceTypes, 1)
 	_, err = analyzer.Platform(getMaxFilter())
 	assert.NoError(t, err)
 }
@@ -680,6 +729,39 @@ func TestAnalyzer_Languages(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestAnalyzer_Locales(t *testing.T) {
+	cleanupDB()
+	assert.NoError(t, dbClient.SaveHits([]Hit{
+		{Fingerprint: "fp1", Time: time.Now(), Language: "en", LanguageRegion: "GB"},
+		{Fingerprint: "fp1", Time: time.Now(), Language: "en", LanguageRegion: "US"},
+		{Fingerprint: "fp2", Time: time.Now(), Language: "en", LanguageRegion: "GB"},
+		{Fingerprint: "fp3", Time: time.Now(), Language: "en"},
+		{Fingerprint: "fp4", Time: time.Now(), Language: "de", LanguageRegion: "AT"},
+	}))
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	visitors, err := analyzer.Locales(nil)
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 4)
+	assert.Equal(t, "en", visitors[0].Language)
+	assert.Equal(t, "GB", visitors[0].LanguageRegion)
+	assert.Equal(t, "de", visitors[1].Language)
+	assert.Equal(t, "AT", visitors[1].LanguageRegion)
+	assert.Equal(t, "en", visitors[2].Language)
+	assert.Equal(t, "", visitors[2].LanguageRegion)
+	assert.Equal(t, "en", visitors[3].Language)
+	assert.Equal(t, "US", visitors[3].LanguageRegion)
+	assert.Equal(t, 2, visitors[0].Visitors)
+	assert.Equal(t, 1, visitors[1].Visitors)
+	assert.InDelta(t, 0.5, visitors[0].RelativeVisitors, 0.01)
+	assert.InDelta(t, 0.25, visitors[1].RelativeVisitors, 0.01)
+	visitors, err = analyzer.Locales(&Filter{LanguageRegion: "GB"})
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 1)
+	_, err = analyzer.Locales(getMaxFilter())
+	assert.NoError(t, err)
+}
+
 func TestAnalyzer_Countries(t *testing.T) {
 	cleanupDB()
 	assert.NoError(t, dbClient.SaveHits([]Hit{
@@ -709,6 +791,69 @@ func TestAnalyzer_Countries(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestAnalyzer_Regions(t *testing.T) {
+	cleanupDB()
+	assert.NoError(t, dbClient.SaveHits([]Hit{
+		{Fingerprint: "fp1", Time: time.Now(), CountryCode: "gb", Region: "England"},
+		{Fingerprint: "fp1", Time: time.Now(), CountryCode: "gb", Region: "Scotland"},
+		{Fingerprint: "fp2", Time: time.Now(), CountryCode: "gb", Region: "England"},
+		{Fingerprint: "fp3", Time: time.Now(), CountryCode: "de", Region: "Bavaria"},
+	}))
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	visitors, err := analyzer.Regions(nil)
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 3)
+	assert.Equal(t, "gb", visitors[0].CountryCode)
+	assert.Equal(t, "England", visitors[0].Region)
+	assert.Equal(t, "de", visitors[1].CountryCode)
+	assert.Equal(t, "Bavaria", visitors[1].Region)
+	assert.Equal(t, "gb", visitors[2].CountryCode)
+	assert.Equal(t, "Scotland", visitors[2].Region)
+	assert.Equal(t, 2, visitors[0].Visitors)
+	assert.Equal(t, 1, visitors[1].Visitors)
+	assert.Equal(t, 1, visitors[2].Visitors)
+	assert.InDelta(t, 0.66, visitors[0].RelativeVisitors, 0.01)
+	assert.InDelta(t, 0.33, visitors[1].RelativeVisitors, 0.01)
+	visitors, err = analyzer.Regions(&Filter{Country: "gb"})
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 2)
+	_, err = analyzer.Regions(getMaxFilter())
+	assert.NoError(t, err)
+}
+
+func TestAnalyzer_Cities(t *testing.T) {
+	cleanupDB()
+	assert.NoError(t, dbClient.SaveHits([]Hit{
+		{Fingerprint: "fp1", Time: time.Now(), CountryCode: "gb", Region: "England", City: "London"},
+		{Fingerprint: "fp1", Time: time.Now(), CountryCode: "gb", Region: "England", City: "London"},
+		{Fingerprint: "fp2", Time: time.Now(), CountryCode: "gb", Region: "England", City: "London"},
+		{Fingerprint: "fp2", Time: time.Now(), CountryCode: "gb", Region: "England", City: "Leeds"},
+		{Fingerprint: "fp3", Time: time.Now(), CountryCode: "us", Region: "Kentucky", City: "London"},
+	}))
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	visitors, err := analyzer.Cities(nil)
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 3)
+	assert.Equal(t, "gb", visitors[0].CountryCode)
+	assert.Equal(t, "England", visitors[0].Region)
+	assert.Equal(t, "London", visitors[0].City)
+	assert.Equal(t, "gb", visitors[1].CountryCode)
+	assert.Equal(t, "Leeds", visitors[1].City)
+	assert.Equal(t, "us", visitors[2].CountryCode)
+	assert.Equal(t, "Kentucky", visitors[2].Region)
+	assert.Equal(t, "London", visitors[2].City)
+	assert.Equal(t, 2, visitors[0].Visitors)
+	assert.Equal(t, 1, visitors[1].Visitors)
+	assert.Equal(t, 1, visitors[2].Visitors)
+	visitors, err = analyzer.Cities(&Filter{City: "London"})
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 2)
+	_, err = analyzer.Cities(getMaxFilter())
+	assert.NoError(t, err)
+}
+
 func TestAnalyzer_Browser(t *testing.T) {
 	cleanupDB()
 	assert.NoError(t, dbClient.SaveHits([]Hit{
@@ -1031,13 +1176,17 @@ func getMaxFilter() *Filter {
 		Start:          time.Now().UTC(),
 		Path:           "/path",
 		Language:       "en",
+		LanguageRegion: "GB",
 		Country:        "en",
+		Region:         "England",
+		City:           "London",
 		Referrer:       "ref",
 		OS:             OSWindows,
 		OSVersion:      "10",
 		Browser:        BrowserChrome,
 		BrowserVersion: "90",
 		Platform:       PlatformDesktop,
+		DeviceType:     DeviceTypeDesktop,
 		ScreenClass:    "XL",
 		UTMSource:      "source",
 		UTMMedium:      "medium",
@@ -1109,3 +1258,36 @@ func TestAnalyzer_PathPattern(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, visitors, 1)
 }
+
+func TestFillVisitorStats(t *testing.T) {
+	filter := &Filter{From: pastDay(3), To: pastDay(0)}
+	stats := fillVisitorStats([]VisitorStats{
+		{Day: pastDay(2), Visitors: 2},
+		{Day: pastDay(0), Visitors: 3},
+	}, filter)
+	assert.Len(t, stats, 4)
+	assert.Equal(t, pastDay(3), stats[0].Day)
+	assert.Equal(t, 0, stats[0].Visitors)
+	assert.Equal(t, 2, stats[1].Visitors)
+	assert.Equal(t, 0, stats[2].Visitors)
+	assert.Equal(t, pastDay(0), stats[3].Day)
+	assert.Equal(t, 3, stats[3].Visitors)
+	assert.Len(t, fillVisitorStats([]VisitorStats{{Day: pastDay(1)}}, &Filter{}), 1)
+}
+
+func TestFillTimeSpentStats(t *testing.T) {
+	filter := &Filter{From: pastDay(2), To: pastDay(0)}
+	stats := fillTimeSpentStats([]TimeSpentStats{{Day: pastDay(1), AverageTimeSpentSeconds: 42}}, filter)
+	assert.Len(t, stats, 3)
+	assert.Equal(t, 0, stats[0].AverageTimeSpentSeconds)
+	assert.Equal(t, 42, stats[1].AverageTimeSpentSeconds)
+	assert.Equal(t, 0, stats[2].AverageTimeSpentSeconds)
+}
+
+func TestFillVisitorHourStats(t *testing.T) {
+	stats := fillVisitorHourStats([]VisitorHourStats{{Hour: 3, Visitors: 1}, {Hour: 23, Visitors: 2}})
+	assert.Len(t, stats, 24)
+	assert.Equal(t, 0, stats[0].Hour)
+	assert.Equal(t, 1, stats[3].Visitors)
+	assert.Equal(t, 2, stats[23].Visitors)
+}
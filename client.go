@@ -49,11 +49,15 @@ func (client *Client) SaveHits(hits []Hit) error {
 	}
 
 	query, err := tx.Prepare(`INSERT INTO "hit" (client_id, fingerprint, time, session, previous_time_on_page_seconds,
-		user_agent, path, url, title, language, country_code, referrer, referrer_name, referrer_icon, os, os_version,
-		browser, browser_version, desktop, mobile, screen_width, screen_height, screen_class,
-		utm_source, utm_medium, utm_campaign, utm_content, utm_term) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
+		user_agent, path, url, title, language, language_region, country_code, region, city, asn, as_organization, referrer, referrer_name, referrer_icon, os, os_version,
+		browser, browser_version, desktop, mobile, device_type, screen_width, screen_height, screen_class,
+		utm_source, utm_medium, utm_campaign, utm_content, utm_term) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
 
 	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			client.logger.Printf("error rolling back transaction to save hits: %s", e)
+		}
+
 		return err
 	}
 
@@ -68,7 +72,12 @@ func (client *Client) SaveHits(hits []Hit) error {
 			hit.URL,
 			hit.Title,
 			hit.Language,
+			hit.LanguageRegion,
 			hit.CountryCode,
+			hit.Region,
+			hit.City,
+			hit.ASN,
+			hit.ASOrganization,
 			hit.Referrer,
 			hit.ReferrerName,
 			hit.ReferrerIcon,
@@ -78,6 +87,7 @@ func (client *Client) SaveHits(hits []Hit) error {
 			hit.BrowserVersion,
 			client.boolean(hit.Desktop),
 			client.boolean(hit.Mobile),
+			hit.DeviceType,
 			hit.ScreenWidth,
 			hit.ScreenHeight,
 			hit.ScreenClass,
@@ -89,7 +99,7 @@ func (client *Client) SaveHits(hits []Hit) error {
 
 		if err != nil {
 			if e := tx.Rollback(); e != nil {
-				client.logger.Printf("error rolling back transaction to save hits: %s", err)
+				client.logger.Printf("error rolling back transaction to save hits: %s", e)
 			}
 
 			return err
@@ -112,12 +122,16 @@ func (client *Client) SaveEvents(events []Event) error {
 	}
 
 	query, err := tx.Prepare(`INSERT INTO "event" (client_id, fingerprint, time, session, previous_time_on_page_seconds,
-		user_agent, path, url, title, language, country_code, referrer, referrer_name, referrer_icon, os, os_version,
-		browser, browser_version, desktop, mobile, screen_width, screen_height, screen_class,
+		user_agent, path, url, title, language, language_region, country_code, region, city, asn, as_organization, referrer, referrer_name, referrer_icon, os, os_version,
+		browser, browser_version, desktop, mobile, device_type, screen_width, screen_height, screen_class,
 		utm_source, utm_medium, utm_campaign, utm_content, utm_term,
-		event_name, event_duration_seconds, event_meta_keys, event_meta_values) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
+		event_name, event_duration_seconds, event_meta_keys, event_meta_values) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
 
 	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			client.logger.Printf("error rolling back transaction to save events: %s", e)
+		}
+
 		return err
 	}
 
@@ -132,7 +146,12 @@ func (client *Client) SaveEvents(events []Event) error {
 			event.URL,
 			event.Title,
 			event.Language,
+			event.LanguageRegion,
 			event.CountryCode,
+			event.Region,
+			event.City,
+			event.ASN,
+			event.ASOrganization,
 			event.Referrer,
 			event.ReferrerName,
 			event.ReferrerIcon,
@@ -142,6 +161,7 @@ func (client *Client) SaveEvents(events []Event) error {
 			event.BrowserVersion,
 			client.boolean(event.Desktop),
 			client.boolean(event.Mobile),
+			event.DeviceType,
 			event.ScreenWidth,
 			event.ScreenHeight,
 			event.ScreenClass,
@@ -157,7 +177,50 @@ func (client *Client) SaveEvents(events []Event) error {
 
 		if err != nil {
 			if e := tx.Rollback(); e != nil {
-				client.logger.Printf("error rolling back transaction to save events: %s", err)
+				client.logger.Printf("error rolling back transaction to save events: %s", e)
+			}
+
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveBotHits implements the Store interface.
+func (client *Client) SaveBotHits(hits []BotHit) error {
+	tx, err := client.Beginx()
+
+	if err != nil {
+		return err
+	}
+
+	query, err := tx.Prepare(`INSERT INTO "bot_hit" (client_id, time, user_agent, path, url, bot_reason, bot_rule) VALUES (?,?,?,?,?,?,?)`)
+
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			client.logger.Printf("error rolling back transaction to save bot hits: %s", e)
+		}
+
+		return err
+	}
+
+	for _, hit := range hits {
+		_, err := query.Exec(hit.ClientID,
+			hit.Time,
+			hit.UserAgent,
+			hit.Path,
+			hit.URL,
+			string(hit.Reason),
+			hit.Rule)
+
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				client.logger.Printf("error rolling back transaction to save bot hits: %s", e)
 			}
 
 			return err
@@ -188,31 +251,15 @@ func (client *Client) Session(clientID int64, fingerprint string, maxAge time.Ti
 	return data, nil
 }
 
-// Count implements the Store interface.
-func (client *Client) Count(query string, args ...interface{}) (int, error) {
-	count := 0
+// Query implements the Store interface.
+func (client *Client) Query(results interface{}, query *Query) error {
+	q, args, err := buildSQLQuery(clickHouse, query)
 
-	if err := client.DB.Get(&count, query, args...); err != nil {
-		client.logger.Printf("error counting results: %s", err)
-		return 0, err
-	}
-
-	return count, nil
-}
-
-// Get implements the Store interface.
-func (client *Client) Get(result interface{}, query string, args ...interface{}) error {
-	if err := client.DB.Get(result, query, args...); err != nil {
-		client.logger.Printf("error getting result: %s", err)
+	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-// Select implements the Store interface.
-func (client *Client) Select(results interface{}, query string, args ...interface{}) error {
-	if err := client.DB.Select(results, query, args...); err != nil {
+	if err := client.DB.Select(results, q, args...); err != nil {
 		client.logger.Printf("error selecting results: %s", err)
 		return err
 	}
@@ -0,0 +1,186 @@
+package pirsch
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// HeaderSecCHUA is the User-Agent Client Hints header containing the browser brands and major versions.
+	HeaderSecCHUA = "Sec-CH-UA"
+
+	// HeaderSecCHUAPlatform is the User-Agent Client Hints header containing the operating system.
+	HeaderSecCHUAPlatform = "Sec-CH-UA-Platform"
+
+	// HeaderSecCHUAPlatformVersion is the User-Agent Client Hints header containing the operating system version.
+	HeaderSecCHUAPlatformVersion = "Sec-CH-UA-Platform-Version"
+
+	// HeaderSecCHUAMobile is the User-Agent Client Hints header indicating a mobile device.
+	HeaderSecCHUAMobile = "Sec-CH-UA-Mobile"
+
+	// HeaderSecCHUAModel is the User-Agent Client Hints header containing the device model.
+	HeaderSecCHUAModel = "Sec-CH-UA-Model"
+
+	// the first Windows platform version reported for Windows 11
+	// https://learn.microsoft.com/en-us/microsoft-edge/web-platform/how-to-detect-win11
+	windows11PlatformVersion = 13
+)
+
+// clientHintsBrands maps the Sec-CH-UA brands to browsers.
+// Chromium is listed separately, as it's included by all Chromium based browsers.
+var clientHintsBrands = map[string]string{
+	"Google Chrome":    BrowserChrome,
+	"Microsoft Edge":   BrowserEdge,
+	"Opera":            BrowserOpera,
+	"Brave":            BrowserBrave,
+	"Samsung Internet": BrowserSamsung,
+	"YaBrowser":        BrowserYandex,
+}
+
+// clientHintsPlatforms maps the Sec-CH-UA-Platform values to operating systems.
+var clientHintsPlatforms = map[string]string{
+	"Windows":   OSWindows,
+	"macOS":     OSMac,
+	"Linux":     OSLinux,
+	"Chrome OS": OSChromeOS,
+	"Android":   OSAndroid,
+	"iOS":       OSiOS,
+}
+
+// ClientHints contains information extracted from the User-Agent Client Hints headers.
+// Browsers only send Sec-CH-UA-Platform-Version and Sec-CH-UA-Model after being asked to using the Accept-CH header.
+type ClientHints struct {
+	// Browser is the browser name.
+	Browser string
+
+	// BrowserVersion is the browser major version number.
+	BrowserVersion string
+
+	// Platform is the operating system.
+	Platform string
+
+	// PlatformVersion is the operating system version number.
+	PlatformVersion string
+
+	// Mobile is true if the browser is running on a mobile device.
+	Mobile bool
+
+	// Model is the device model.
+	Model string
+}
+
+// ParseClientHints parses the User-Agent Client Hints headers of given request.
+// Unknown brands and platforms are ignored.
+func ParseClientHints(r *http.Request) ClientHints {
+	hints := ClientHints{
+		Mobile: r.Header.Get(HeaderSecCHUAMobile) == "?1",
+		Model:  unquoteClientHint(r.Header.Get(HeaderSecCHUAModel)),
+	}
+	hints.Browser, hints.BrowserVersion = getClientHintsBrowser(r.Header.Get(HeaderSecCHUA))
+	hints.Platform = clientHintsPlatforms[unquoteClientHint(r.Header.Get(HeaderSecCHUAPlatform))]
+
+	if hints.Platform != "" {
+		hints.PlatformVersion = getClientHintsPlatformVersion(hints.Platform, unquoteClientHint(r.Header.Get(HeaderSecCHUAPlatformVersion)))
+	}
+
+	return hints
+}
+
+// ParseUserAgentWithClientHints parses given User-Agent header and overrides the extracted information with given ClientHints.
+// Client hints are more accurate, as the User-Agent is frozen for Chromium based browsers (reporting Windows 11 as 10, for example).
+func ParseUserAgentWithClientHints(ua string, hints ClientHints) UserAgent {
+	userAgent := ParseUserAgent(ua)
+
+	if hints.Browser != "" && (hints.Browser != BrowserChrome || userAgent.Browser == "") {
+		if userAgent.Browser != hints.Browser || !strings.HasPrefix(userAgent.BrowserVersion, hints.BrowserVersion+".") {
+			userAgent.BrowserVersion = hints.BrowserVersion
+		}
+
+		userAgent.Browser = hints.Browser
+	}
+
+	if hints.Platform != "" {
+		if userAgent.OS != hints.Platform || hints.PlatformVersion != "" {
+			userAgent.OSVersion = hints.PlatformVersion
+		}
+
+		userAgent.OS = hints.Platform
+	}
+
+	return userAgent
+}
+
+// SetAcceptCH sets the Accept-CH header to ask browsers to send all User-Agent Client Hints used by Pirsch on subsequent requests.
+func SetAcceptCH(w http.ResponseWriter) {
+	w.Header().Set("Accept-CH", strings.Join([]string{
+		HeaderSecCHUA,
+		HeaderSecCHUAPlatform,
+		HeaderSecCHUAPlatformVersion,
+		HeaderSecCHUAMobile,
+		HeaderSecCHUAModel,
+	}, ", "))
+}
+
+// returns the browser and major version from the Sec-CH-UA brand list, like: "Chromium";v="118", "Google Chrome";v="118", "Not=A?Brand";v="99"
+func getClientHintsBrowser(header string) (string, string) {
+	browser := ""
+	version := ""
+
+	for _, brand := range strings.Split(header, ",") {
+		name, v := parseClientHintsBrand(brand)
+
+		if b, found := clientHintsBrands[name]; found {
+			return b, v
+		} else if name == "Chromium" {
+			browser = BrowserChrome
+			version = v
+		}
+	}
+
+	return browser, version
+}
+
+func parseClientHintsBrand(brand string) (string, string) {
+	parts := strings.Split(brand, ";")
+	version := ""
+
+	for _, param := range parts[1:] {
+		param = strings.TrimSpace(param)
+
+		if strings.HasPrefix(param, "v=") {
+			version = unquoteClientHint(param[2:])
+		}
+	}
+
+	return unquoteClientHint(parts[0]), version
+}
+
+func getClientHintsPlatformVersion(platform, version string) string {
+	if version == "" {
+		return ""
+	}
+
+	if platform == OSWindows {
+		major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+
+		if err != nil {
+			return ""
+		}
+
+		// 0 is returned for Windows 7, 8, and 8.1, which we can't distinguish from each other
+		if major >= windows11PlatformVersion {
+			return "11"
+		} else if major > 0 {
+			return "10"
+		}
+
+		return ""
+	}
+
+	return getOSVersion(version, 2)
+}
+
+func unquoteClientHint(value string) string {
+	return strings.Trim(strings.TrimSpace(value), `"`)
+}
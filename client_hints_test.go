@@ -0,0 +1,119 @@
+package pirsch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseClientHints(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderSecCHUA, `" Not A;Brand";v="99", "Chromium";v="118", "Google Chrome";v="118"`)
+	req.Header.Set(HeaderSecCHUAPlatform, `"Android"`)
+	req.Header.Set(HeaderSecCHUAPlatformVersion, `"13.0.0"`)
+	req.Header.Set(HeaderSecCHUAMobile, "?1")
+	req.Header.Set(HeaderSecCHUAModel, `"Pixel 7"`)
+	hints := ParseClientHints(req)
+	assert.Equal(t, BrowserChrome, hints.Browser)
+	assert.Equal(t, "118", hints.BrowserVersion)
+	assert.Equal(t, OSAndroid, hints.Platform)
+	assert.Equal(t, "13.0.0", hints.PlatformVersion)
+	assert.True(t, hints.Mobile)
+	assert.Equal(t, "Pixel 7", hints.Model)
+	hints = ParseClientHints(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, hints.Browser)
+	assert.Empty(t, hints.BrowserVersion)
+	assert.Empty(t, hints.Platform)
+	assert.Empty(t, hints.PlatformVersion)
+	assert.False(t, hints.Mobile)
+	assert.Empty(t, hints.Model)
+}
+
+func TestGetClientHintsBrowser(t *testing.T) {
+	input := []string{
+		`"Chromium";v="118", "Google Chrome";v="118", "Not=A?Brand";v="99"`,
+		`"Not_A Brand";v="8", "Chromium";v="120", "Microsoft Edge";v="120"`,
+		`"Opera";v="105", "Chromium";v="119", "Not?A_Brand";v="24"`,
+		`"Chromium";v="117", "Not;A=Brand";v="8"`,
+		`"Not A(Brand";v="99", "Unknown";v="1"`,
+		"",
+	}
+	expected := []struct {
+		browser string
+		version string
+	}{
+		{BrowserChrome, "118"},
+		{BrowserEdge, "120"},
+		{BrowserOpera, "105"},
+		{BrowserChrome, "117"},
+		{"", ""},
+		{"", ""},
+	}
+
+	for i, in := range input {
+		browser, version := getClientHintsBrowser(in)
+		assert.Equal(t, expected[i].browser, browser)
+		assert.Equal(t, expected[i].version, version)
+	}
+}
+
+func TestGetClientHintsPlatformVersion(t *testing.T) {
+	assert.Equal(t, "11", getClientHintsPlatformVersion(OSWindows, "15.0.0"))
+	assert.Equal(t, "11", getClientHintsPlatformVersion(OSWindows, "13.0.0"))
+	assert.Equal(t, "10", getClientHintsPlatformVersion(OSWindows, "10.0.0"))
+	assert.Equal(t, "10", getClientHintsPlatformVersion(OSWindows, "1.0.0"))
+	assert.Empty(t, getClientHintsPlatformVersion(OSWindows, "0.3.0"))
+	assert.Empty(t, getClientHintsPlatformVersion(OSWindows, "invalid"))
+	assert.Equal(t, "14.1.0", getClientHintsPlatformVersion(OSMac, "14.1.0"))
+	assert.Equal(t, "6.5", getClientHintsPlatformVersion(OSLinux, "6.5"))
+	assert.Empty(t, getClientHintsPlatformVersion(OSMac, ""))
+}
+
+func TestParseUserAgentWithClientHints(t *testing.T) {
+	chrome := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36"
+	ua := ParseUserAgentWithClientHints(chrome, ClientHints{
+		Browser:         BrowserChrome,
+		BrowserVersion:  "118",
+		Platform:        OSWindows,
+		PlatformVersion: "11",
+	})
+	assert.Equal(t, BrowserChrome, ua.Browser)
+	assert.Equal(t, "118.0", ua.BrowserVersion)
+	assert.Equal(t, OSWindows, ua.OS)
+	assert.Equal(t, "11", ua.OSVersion)
+
+	// Windows 7 to 8.1 cannot be distinguished using client hints
+	ua = ParseUserAgentWithClientHints("Mozilla/5.0 (Windows NT 6.1; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/109.0.0.0 Safari/537.36", ClientHints{
+		Browser:  BrowserChrome,
+		Platform: OSWindows,
+	})
+	assert.Equal(t, OSWindows, ua.OS)
+	assert.Equal(t, "7", ua.OSVersion)
+
+	// the Chromium brand must not override other browsers
+	opera := "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36 OPR/105.0.0.0"
+	ua = ParseUserAgentWithClientHints(opera, ClientHints{
+		Browser:         BrowserChrome,
+		BrowserVersion:  "119",
+		Platform:        OSMac,
+		PlatformVersion: "14.1.0",
+	})
+	assert.Equal(t, BrowserOpera, ua.Browser)
+	assert.Equal(t, "105.0", ua.BrowserVersion)
+	assert.Equal(t, OSMac, ua.OS)
+	assert.Equal(t, "14.1.0", ua.OSVersion)
+
+	// the User-Agent is used if no client hints were sent
+	ua = ParseUserAgentWithClientHints(chrome, ClientHints{})
+	assert.Equal(t, BrowserChrome, ua.Browser)
+	assert.Equal(t, "118.0", ua.BrowserVersion)
+	assert.Equal(t, OSWindows, ua.OS)
+	assert.Equal(t, "10", ua.OSVersion)
+}
+
+func TestSetAcceptCH(t *testing.T) {
+	w := httptest.NewRecorder()
+	SetAcceptCH(w)
+	assert.Equal(t, "Sec-CH-UA, Sec-CH-UA-Platform, Sec-CH-UA-Platform-Version, Sec-CH-UA-Mobile, Sec-CH-UA-Model", w.Header().Get("Accept-CH"))
+}
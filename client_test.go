@@ -7,6 +7,7 @@ import (
 )
 
 func TestNewClient(t *testing.T) {
+	requireClickHouse(t)
 	client, err := NewClient("tcp://127.0.0.1:9000", nil)
 	assert.NoError(t, err)
 	assert.NotNil(t, client)
@@ -26,6 +27,7 @@ func TestClient_SaveHit(t *testing.T) {
 			Path:                      "/path",
 			Title:                     "title",
 			Language:                  "en",
+			LanguageRegion:            "GB",
 			Referrer:                  "ref",
 			ReferrerName:              "ref_name",
 			ReferrerIcon:              "ref_icon",
@@ -34,8 +36,13 @@ func TestClient_SaveHit(t *testing.T) {
 			Browser:                   "browser",
 			BrowserVersion:            "89",
 			CountryCode:               "en",
+			Region:                    "England",
+			City:                      "London",
+			ASN:                       20712,
+			ASOrganization:            "Andrews & Arnold Ltd",
 			Desktop:                   true,
 			Mobile:                    false,
+			DeviceType:                DeviceTypeDesktop,
 			ScreenWidth:               1920,
 			ScreenHeight:              1080,
 			ScreenClass:               "XL",
@@ -63,6 +70,7 @@ func TestClient_SaveEvent(t *testing.T) {
 				Path:                      "/path",
 				Title:                     "title",
 				Language:                  "en",
+				LanguageRegion:            "GB",
 				Referrer:                  "ref",
 				ReferrerName:              "ref_name",
 				ReferrerIcon:              "ref_icon",
@@ -71,8 +79,13 @@ func TestClient_SaveEvent(t *testing.T) {
 				Browser:                   "browser",
 				BrowserVersion:            "89",
 				CountryCode:               "en",
+				Region:                    "England",
+				City:                      "London",
+				ASN:                       20712,
+				ASOrganization:            "Andrews & Arnold Ltd",
 				Desktop:                   true,
 				Mobile:                    false,
+				DeviceType:                DeviceTypeDesktop,
 				ScreenWidth:               1920,
 				ScreenHeight:              1080,
 				ScreenClass:               "XL",
@@ -94,6 +107,26 @@ func TestClient_SaveEvent(t *testing.T) {
 	}))
 }
 
+func TestClient_SaveBotHits(t *testing.T) {
+	cleanupDB()
+	assert.NoError(t, dbClient.SaveBotHits([]BotHit{
+		{
+			ClientID:  1,
+			Time:      time.Now(),
+			UserAgent: "Googlebot",
+			Path:      "/path",
+			URL:       "https://example.com/path",
+			Reason:    IgnoreBot,
+			Rule:      "Googlebot",
+		},
+		{
+			Time:   time.Now().UTC(),
+			Path:   "/path",
+			Reason: IgnoreEmptyUserAgent,
+		},
+	}))
+}
+
 func TestClient_Session(t *testing.T) {
 	cleanupDB()
 	fp := "session_fp"
@@ -23,16 +23,13 @@ func main() {
 	// This will buffer and store hits and generate sessions by default.
 	tracker := pirsch.NewTracker(store, "salt", nil)
 
-	// Create a handler to serve traffic.
-	http.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// You can make sure only page calls get counted by checking the path: if r.URL.Path == "/" {...
-		if r.URL.Path != "/favicon.ico" {
-			go tracker.Hit(r, nil)
-		}
-
+	// Create a handler to serve traffic and wrap it in the tracking middleware.
+	// The middleware only tracks successful page calls, static files like /favicon.ico are ignored.
+	trackingMiddleware := pirsch.Middleware(tracker, nil)
+	http.Handle("/", trackingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Send response.
 		w.Write([]byte("<h1>Hello World!</h1>"))
-	}))
+	})))
 
 	// And finally, start the server.
 	// We don't flush hits on shutdown but you should add that in a real application by calling Tracker.Flush().
@@ -23,12 +23,14 @@ func main() {
 	tracker := pirsch.NewTracker(store, "salt", nil)
 
 	// Create an endpoint to handle client tracking requests.
-	// HitOptionsFromRequest is a utility function to process the required parameters.
-	// You might want to additional checks, like for the client ID.
+	// The Handler reads the parameters send by pirsch.js and checks the client ID before passing the hit on to the tracker.
+	handler := pirsch.NewHandler(tracker, &pirsch.HandlerConfig{
+		ValidClientID: func(clientID int64) bool {
+			return clientID == 42
+		},
+	})
 	http.Handle("/count", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// We don't need to call Hit in a new goroutine, as this is the only call in the handler
-		// (running in its own goroutine already).
-		tracker.Hit(r, pirsch.HitOptionsFromRequest(r))
+		handler.Hit(w, r)
 		log.Println("Counted one hit")
 	}))
 
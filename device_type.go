@@ -0,0 +1,72 @@
+package pirsch
+
+import (
+	"strings"
+)
+
+const (
+	// DeviceTypeDesktop is a desktop or laptop computer.
+	DeviceTypeDesktop = "desktop"
+
+	// DeviceTypeMobile is a smartphone.
+	DeviceTypeMobile = "mobile"
+
+	// DeviceTypeTablet is a tablet, like an iPad.
+	DeviceTypeTablet = "tablet"
+
+	// DeviceTypeTV is a smart TV or streaming device.
+	DeviceTypeTV = "tv"
+
+	// DeviceTypeConsole is a game console.
+	DeviceTypeConsole = "console"
+
+	// DeviceTypeWearable is a wearable device, like a smartwatch.
+	DeviceTypeWearable = "wearable"
+
+	// DeviceTypeBot is a bot or crawler.
+	DeviceTypeBot = "bot"
+)
+
+// GetDeviceType returns the device type for given User-Agent header, parsed UserAgent, and ClientHints.
+// The device model from the client hints is checked before the User-Agent, as Chromium based browsers report a generic model in it.
+// An empty string is returned if the device type is unknown.
+func GetDeviceType(ua string, userAgent UserAgent, hints ClientHints) string {
+	if strings.TrimSpace(ua) == "" {
+		return ""
+	}
+
+	if matchBotUserAgent(strings.ToLower(ua)) != "" {
+		return DeviceTypeBot
+	}
+
+	if hints.Model != "" {
+		if deviceType, _, found := matchUserAgentRules(hints.Model, 0, getUserAgentRules().Device, defaultUserAgentRules.Device); found {
+			return deviceType
+		}
+	}
+
+	if deviceType, _, found := matchUserAgentRules(ua, 0, getUserAgentRules().Device, defaultUserAgentRules.Device); found {
+		return deviceType
+	}
+
+	if userAgent.OS == OSAndroid {
+		// Android tablets don't send the Mobile token
+		if hints.Platform != "" {
+			if hints.Mobile {
+				return DeviceTypeMobile
+			}
+
+			return DeviceTypeTablet
+		} else if !strings.Contains(ua, "Mobile") {
+			return DeviceTypeTablet
+		}
+	}
+
+	if userAgent.IsDesktop() {
+		return DeviceTypeDesktop
+	} else if userAgent.IsMobile() || hints.Mobile {
+		return DeviceTypeMobile
+	}
+
+	return ""
+}
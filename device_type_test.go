@@ -0,0 +1,59 @@
+package pirsch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetDeviceType(t *testing.T) {
+	input := []struct {
+		ua         string
+		hints      ClientHints
+		deviceType string
+	}{
+		{"", ClientHints{}, ""},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36", ClientHints{}, DeviceTypeDesktop},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:79.0) Gecko/20100101 Firefox/79.0", ClientHints{}, DeviceTypeDesktop},
+		{"Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36", ClientHints{}, DeviceTypeDesktop},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", ClientHints{}, DeviceTypeMobile},
+		{"Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Mobile Safari/537.36", ClientHints{}, DeviceTypeMobile},
+		{"Mozilla/5.0 (iPad; CPU OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", ClientHints{}, DeviceTypeTablet},
+		{"Mozilla/5.0 (Linux; Android 10; SAMSUNG SM-T510) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/14.2 Chrome/87.0.4280.141 Safari/537.36", ClientHints{}, DeviceTypeTablet},
+		{"Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36", ClientHints{Platform: OSAndroid, Mobile: true}, DeviceTypeMobile},
+		{"Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Mobile Safari/537.36", ClientHints{Platform: OSAndroid}, DeviceTypeTablet},
+		{"Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Mobile Safari/537.36", ClientHints{Platform: OSAndroid, Mobile: true, Model: "Pixel 7"}, DeviceTypeMobile},
+		{"Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Mobile Safari/537.36", ClientHints{Platform: OSAndroid, Mobile: true, Model: "Pixel Tablet"}, DeviceTypeTablet},
+		{"Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36", ClientHints{Platform: OSAndroid, Model: "BRAVIA 4K VH21"}, DeviceTypeTV},
+		{"Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Mobile Safari/537.36", ClientHints{Platform: OSAndroid, Mobile: true, Model: "Galaxy Watch4"}, DeviceTypeWearable},
+		{"Mozilla/5.0 (Linux; Android 9; KFTRWI) AppleWebKit/537.36 (KHTML, like Gecko) Silk/118.4.1 like Chrome/118.0.5993.117 Safari/537.36", ClientHints{}, DeviceTypeTablet},
+		{"Mozilla/5.0 (SMART-TV; LINUX; Tizen 6.0) AppleWebKit/537.36 (KHTML, like Gecko) 76.0.3809.146/6.0 TV Safari/537.36", ClientHints{}, DeviceTypeTV},
+		{"Mozilla/5.0 (Web0S; Linux/SmartTV) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36", ClientHints{}, DeviceTypeTV},
+		{"Mozilla/5.0 (Linux; Android 12; BRAVIA 4K VH21 Build/STT2.230123.003) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.5359.128 Safari/537.36", ClientHints{}, DeviceTypeTV},
+		{"Mozilla/5.0 (PlayStation; PlayStation 5/2.26) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.0 Safari/605.1.15", ClientHints{}, DeviceTypeConsole},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64; Xbox; Xbox One) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/84.0.4147.135 Safari/537.36 Edge/44.18363.8131", ClientHints{}, DeviceTypeConsole},
+		{"Mozilla/5.0 (Nintendo Switch; WifiWebAuthApplet) AppleWebKit/606.4 (KHTML, like Gecko) NF/6.0.1.15.4 NintendoBrowser/5.1.0.20393", ClientHints{}, DeviceTypeConsole},
+		{"Mozilla/5.0 (Linux; Android 11; Galaxy Watch4 Build/RWD4.220909.001) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/111.0.5563.116 Mobile Safari/537.36", ClientHints{}, DeviceTypeWearable},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", ClientHints{}, DeviceTypeBot},
+		{"Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.5993.117 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", ClientHints{}, DeviceTypeBot},
+		{"curl/8.4.0", ClientHints{}, DeviceTypeBot},
+		{"Unknown", ClientHints{}, ""},
+		{"Unknown", ClientHints{Mobile: true}, DeviceTypeMobile},
+	}
+
+	for _, in := range input {
+		userAgent := ParseUserAgentWithClientHints(in.ua, in.hints)
+		assert.Equal(t, in.deviceType, GetDeviceType(in.ua, userAgent, in.hints), in.ua)
+	}
+}
+
+func TestGetDeviceTypeRules(t *testing.T) {
+	defer func() {
+		assert.NoError(t, SetUserAgentRules(nil))
+	}()
+	ua := "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36 OculusBrowser/29.0"
+	assert.Equal(t, DeviceTypeDesktop, GetDeviceType(ua, ParseUserAgent(ua), ClientHints{}))
+	assert.NoError(t, SetUserAgentRules(&UserAgentRules{
+		Device: []UserAgentRule{{Name: DeviceTypeWearable, Contains: []string{"OculusBrowser/"}}},
+	}))
+	assert.Equal(t, DeviceTypeWearable, GetDeviceType(ua, ParseUserAgent(ua), ClientHints{}))
+}
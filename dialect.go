@@ -0,0 +1,316 @@
+package pirsch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dialect abstracts the differences in SQL between the databases the Analyzer can query.
+// The queries built for a Query are written in a common subset of SQL and use the dialect for everything else.
+type dialect interface {
+	// timezone returns the parameter passed for the timezone placeholder in date and hour.
+	timezone(tz *time.Location) interface{}
+
+	// date returns the expression to convert given column to a date.
+	// The timezone is passed as a parameter.
+	date(column string) string
+
+	// dateParam returns the expression to convert a date parameter to a date.
+	dateParam() string
+
+	// hour returns the expression to extract the hour of the day from given column.
+	// The timezone is passed as a parameter.
+	hour(column string) string
+
+	// timeDiff returns the expression for the difference between two points in time in seconds.
+	timeDiff(end, start string) string
+
+	// zeroTime returns the value used for dates and times that are not set.
+	zeroTime() string
+
+	// boolean returns the literal for given boolean.
+	boolean(b bool) string
+
+	// match returns the expression to check if given column matches the regular expression passed as a parameter.
+	match(column string, matches bool) string
+
+	// countIf returns the expression to count all rows for which the condition is true.
+	countIf(cond string) string
+
+	// countDistinct returns the expression to count the distinct combinations of given columns.
+	countDistinct(columns ...string) string
+
+	// least returns the expression for the lesser value of a and b.
+	least(a, b string) string
+
+	// toInt returns the expression to convert a number to an integer (truncating it).
+	toInt(expr string) string
+
+	// neighbor returns the expression to access the value of given column in the row offset by n.
+	// The rows are ordered by fingerprint and time. The default value is returned if the row does not exist.
+	neighbor(column string, offset int, defaultValue string) string
+
+	// groupUniqArray returns the expression to aggregate all unique values of the array column into an array.
+	groupUniqArray(column string) string
+
+	// arrayValue returns the expression to look up the element in the values column
+	// at the position of the parameter in the keys column.
+	arrayValue(values, keys string) string
+
+	// arrayHas returns the expression to check if the array column contains the parameter.
+	arrayHas(column string) string
+}
+
+var clickHouse = clickHouseDialect{}
+
+type clickHouseDialect struct{}
+
+func (clickHouseDialect) timezone(tz *time.Location) interface{} {
+	return tz.String()
+}
+
+func (clickHouseDialect) date(column string) string {
+	return fmt.Sprintf("toDate(%s, ?)", column)
+}
+
+func (clickHouseDialect) dateParam() string {
+	return "toDate(?)"
+}
+
+func (clickHouseDialect) hour(column string) string {
+	return fmt.Sprintf("toHour(%s, ?)", column)
+}
+
+func (clickHouseDialect) timeDiff(end, start string) string {
+	return fmt.Sprintf("%s-%s", end, start)
+}
+
+func (clickHouseDialect) zeroTime() string {
+	return "0"
+}
+
+func (clickHouseDialect) boolean(b bool) string {
+	if b {
+		return "1"
+	}
+
+	return "0"
+}
+
+func (clickHouseDialect) match(column string, matches bool) string {
+	if matches {
+		return fmt.Sprintf("match(%s, ?) = 1", column)
+	}
+
+	return fmt.Sprintf("match(%s, ?) = 0", column)
+}
+
+func (clickHouseDialect) countIf(cond string) string {
+	return fmt.Sprintf("countIf(%s)", cond)
+}
+
+func (clickHouseDialect) countDistinct(columns ...string) string {
+	if len(columns) == 1 {
+		return fmt.Sprintf("count(DISTINCT %s)", columns[0])
+	}
+
+	return fmt.Sprintf("count(DISTINCT(%s))", strings.Join(columns, ", "))
+}
+
+func (clickHouseDialect) least(a, b string) string {
+	return fmt.Sprintf("least(%s, %s)", a, b)
+}
+
+func (clickHouseDialect) toInt(expr string) string {
+	return fmt.Sprintf("toUInt64(%s)", expr)
+}
+
+func (clickHouseDialect) neighbor(column string, offset int, defaultValue string) string {
+	return fmt.Sprintf("neighbor(%s, %d, %s)", column, offset, defaultValue)
+}
+
+func (clickHouseDialect) groupUniqArray(column string) string {
+	return fmt.Sprintf("groupUniqArrayArray(%s)", column)
+}
+
+func (clickHouseDialect) arrayValue(values, keys string) string {
+	return fmt.Sprintf("%s[indexOf(%s, ?)]", values, keys)
+}
+
+func (clickHouseDialect) arrayHas(column string) string {
+	return fmt.Sprintf("has(%s, ?)", column)
+}
+
+var postgres = postgresDialect{}
+
+type postgresDialect struct{}
+
+func (postgresDialect) timezone(tz *time.Location) interface{} {
+	return tz.String()
+}
+
+func (postgresDialect) date(column string) string {
+	return fmt.Sprintf("CAST(%s AT TIME ZONE CAST(? AS text) AS date)", column)
+}
+
+func (postgresDialect) dateParam() string {
+	return "CAST(? AS date)"
+}
+
+func (postgresDialect) hour(column string) string {
+	return fmt.Sprintf("CAST(EXTRACT(HOUR FROM %s AT TIME ZONE CAST(? AS text)) AS integer)", column)
+}
+
+func (postgresDialect) timeDiff(end, start string) string {
+	return fmt.Sprintf("CAST(EXTRACT(EPOCH FROM %s-%s) AS bigint)", end, start)
+}
+
+func (postgresDialect) zeroTime() string {
+	return "'0001-01-01 00:00:00+00'"
+}
+
+func (postgresDialect) boolean(b bool) string {
+	if b {
+		return "TRUE"
+	}
+
+	return "FALSE"
+}
+
+func (postgresDialect) match(column string, matches bool) string {
+	if matches {
+		return fmt.Sprintf("%s ~ ?", column)
+	}
+
+	return fmt.Sprintf("%s !~ ?", column)
+}
+
+func (postgresDialect) countIf(cond string) string {
+	return fmt.Sprintf("count(*) FILTER (WHERE %s)", cond)
+}
+
+func (postgresDialect) countDistinct(columns ...string) string {
+	if len(columns) == 1 {
+		return fmt.Sprintf("count(DISTINCT %s)", columns[0])
+	}
+
+	return fmt.Sprintf("count(DISTINCT (%s))", strings.Join(columns, ", "))
+}
+
+func (postgresDialect) least(a, b string) string {
+	return fmt.Sprintf("least(%s, %s)", a, b)
+}
+
+func (postgresDialect) toInt(expr string) string {
+	return fmt.Sprintf("CAST(trunc(%s) AS bigint)", expr)
+}
+
+func (postgresDialect) neighbor(column string, offset int, defaultValue string) string {
+	if offset < 0 {
+		return fmt.Sprintf(`lag(%s, %d, %s) OVER (ORDER BY fingerprint, "time")`, column, -offset, defaultValue)
+	}
+
+	return fmt.Sprintf(`lead(%s, %d, %s) OVER (ORDER BY fingerprint, "time")`, column, offset, defaultValue)
+}
+
+func (postgresDialect) groupUniqArray(column string) string {
+	// array_cat_agg and array_distinct are created by the Postgres schema migration
+	return fmt.Sprintf("array_to_json(array_distinct(array_cat_agg(%s)))", column)
+}
+
+func (postgresDialect) arrayValue(values, keys string) string {
+	return fmt.Sprintf("%s[array_position(%s, CAST(? AS text))]", values, keys)
+}
+
+func (postgresDialect) arrayHas(column string) string {
+	return fmt.Sprintf("CAST(? AS text) = ANY(%s)", column)
+}
+
+var sqlite = sqliteDialect{}
+
+type sqliteDialect struct{}
+
+// timezone returns the name of given timezone, which is used to convert the times per row by pirsch_timezone.
+// Timezones that cannot be loaded by name (like a time.FixedZone) are passed as their offset in seconds.
+func (sqliteDialect) timezone(tz *time.Location) interface{} {
+	if _, err := loadSQLiteLocation(tz.String()); err != nil {
+		_, offset := time.Now().In(tz).Zone()
+		return strconv.Itoa(offset)
+	}
+
+	return tz.String()
+}
+
+func (sqliteDialect) date(column string) string {
+	// pirsch_timezone is registered as a function with the SQLite driver
+	return fmt.Sprintf("date(pirsch_timezone(%s, ?))", column)
+}
+
+func (sqliteDialect) dateParam() string {
+	return "date(?)"
+}
+
+func (sqliteDialect) hour(column string) string {
+	return fmt.Sprintf("CAST(strftime('%%H', pirsch_timezone(%s, ?)) AS INTEGER)", column)
+}
+
+func (sqliteDialect) timeDiff(end, start string) string {
+	return fmt.Sprintf("(strftime('%%s', %s)-strftime('%%s', %s))", end, start)
+}
+
+func (sqliteDialect) zeroTime() string {
+	return "'0001-01-01 00:00:00'"
+}
+
+func (sqliteDialect) boolean(b bool) string {
+	if b {
+		return "1"
+	}
+
+	return "0"
+}
+
+func (sqliteDialect) match(column string, matches bool) string {
+	// pirsch_match is registered as a function with the SQLite driver
+	if matches {
+		return fmt.Sprintf("pirsch_match(%s, ?) = 1", column)
+	}
+
+	return fmt.Sprintf("pirsch_match(%s, ?) = 0", column)
+}
+
+func (sqliteDialect) countIf(cond string) string {
+	return fmt.Sprintf("count(*) FILTER (WHERE %s)", cond)
+}
+
+func (sqliteDialect) countDistinct(columns ...string) string {
+	return fmt.Sprintf("count(DISTINCT %s)", strings.Join(columns, " || ' ' || "))
+}
+
+func (sqliteDialect) least(a, b string) string {
+	return fmt.Sprintf("min(%s, %s)", a, b)
+}
+
+func (sqliteDialect) toInt(expr string) string {
+	return fmt.Sprintf("CAST(%s AS INTEGER)", expr)
+}
+
+func (sqliteDialect) neighbor(column string, offset int, defaultValue string) string {
+	return postgres.neighbor(column, offset, defaultValue)
+}
+
+func (sqliteDialect) groupUniqArray(column string) string {
+	// the arrays are stored as JSON, so they can be concatenated into an array of arrays and flattened by json_each
+	return fmt.Sprintf(`(SELECT json_group_array(DISTINCT v.value)
+		FROM json_each('[' || group_concat(%s) || ']') a, json_each(a.value) v)`, column)
+}
+
+func (sqliteDialect) arrayValue(values, keys string) string {
+	return fmt.Sprintf("(SELECT v.value FROM json_each(%s) k, json_each(%s) v WHERE k.value = ? AND k.key = v.key)", keys, values)
+}
+
+func (sqliteDialect) arrayHas(column string) string {
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s) WHERE value = ?)", column)
+}
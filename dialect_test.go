@@ -0,0 +1,54 @@
+package pirsch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestClickHouseDialect(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	assert.Equal(t, "Europe/Berlin", clickHouse.timezone(tz))
+	assert.Equal(t, "toDate(time, ?)", clickHouse.date("time"))
+	assert.Equal(t, "toDate(?)", clickHouse.dateParam())
+	assert.Equal(t, "toHour(time, ?)", clickHouse.hour("time"))
+	assert.Equal(t, "match(path, ?) = 0", clickHouse.match("path", false))
+	assert.Equal(t, "countIf(bounce)", clickHouse.countIf("bounce"))
+	assert.Equal(t, "count(DISTINCT(fingerprint, session))", clickHouse.countDistinct("fingerprint", "session"))
+	assert.Equal(t, "neighbor(path, -1, '')", clickHouse.neighbor("path", -1, "''"))
+	assert.Equal(t, "groupUniqArrayArray(event_meta_keys)", clickHouse.groupUniqArray("event_meta_keys"))
+	assert.Equal(t, "event_meta_values[indexOf(event_meta_keys, ?)]", clickHouse.arrayValue("event_meta_values", "event_meta_keys"))
+}
+
+func TestPostgresDialect(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	assert.Equal(t, "Europe/Berlin", postgres.timezone(tz))
+	assert.Equal(t, `CAST("time" AT TIME ZONE CAST(? AS text) AS date)`, postgres.date(`"time"`))
+	assert.Equal(t, "CAST(? AS date)", postgres.dateParam())
+	assert.Equal(t, `CAST(EXTRACT(HOUR FROM "time" AT TIME ZONE CAST(? AS text)) AS integer)`, postgres.hour(`"time"`))
+	assert.Equal(t, `CAST(EXTRACT(EPOCH FROM max("time")-min("time")) AS bigint)`, postgres.timeDiff(`max("time")`, `min("time")`))
+	assert.Equal(t, "TRUE", postgres.boolean(true))
+	assert.Equal(t, "path ~ ?", postgres.match("path", true))
+	assert.Equal(t, "path !~ ?", postgres.match("path", false))
+	assert.Equal(t, "count(*) FILTER (WHERE bounce)", postgres.countIf("bounce"))
+	assert.Equal(t, "count(DISTINCT (fingerprint, path))", postgres.countDistinct("fingerprint", "path"))
+	assert.Equal(t, `lag(path, 1, '') OVER (ORDER BY fingerprint, "time")`, postgres.neighbor("path", -1, "''"))
+	assert.Equal(t, `lead(path, 1, '') OVER (ORDER BY fingerprint, "time")`, postgres.neighbor("path", 1, "''"))
+	assert.Equal(t, "array_to_json(array_distinct(array_cat_agg(event_meta_keys)))", postgres.groupUniqArray("event_meta_keys"))
+	assert.Equal(t, "CAST(? AS text) = ANY(event_meta_keys)", postgres.arrayHas("event_meta_keys"))
+}
+
+func TestSQLiteDialect(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	assert.Equal(t, "Europe/Berlin", sqlite.timezone(tz))
+	assert.Equal(t, "UTC", sqlite.timezone(time.UTC))
+	assert.Equal(t, "7200", sqlite.timezone(time.FixedZone("test", 2*60*60)))
+	assert.Equal(t, "-5400", sqlite.timezone(time.FixedZone("test", -90*60)))
+	assert.Equal(t, "date(pirsch_timezone(time, ?))", sqlite.date("time"))
+	assert.Equal(t, "date(?)", sqlite.dateParam())
+	assert.Equal(t, "CAST(strftime('%H', pirsch_timezone(time, ?)) AS INTEGER)", sqlite.hour("time"))
+	assert.Equal(t, "pirsch_match(path, ?) = 0", sqlite.match("path", false))
+	assert.Equal(t, "count(DISTINCT fingerprint || ' ' || session)", sqlite.countDistinct("fingerprint", "session"))
+	assert.Equal(t, "min(time_on_page, 60)", sqlite.least("time_on_page", "60"))
+	assert.Equal(t, "EXISTS (SELECT 1 FROM json_each(event_meta_keys) WHERE value = ?)", sqlite.arrayHas("event_meta_keys"))
+}
@@ -1,5 +1,22 @@
 package pirsch
 
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	maxEventRequestBodySize = 1 << 16
+)
+
+var (
+	// ErrNoEventName is returned in case an event is send without a name.
+	ErrNoEventName = errors.New("no event name specified")
+)
+
 // EventOptions are the options to save a new event.
 // The name is required. All other fields are optional.
 type EventOptions struct {
@@ -13,6 +30,71 @@ type EventOptions struct {
 	Meta map[string]string
 }
 
+// EventRequest is the JSON body send by pirsch-events.js to track a custom event.
+type EventRequest struct {
+	// ClientID is the client ID as set in the data-client-id attribute.
+	// pirsch-events.js sends it as a string, but numbers are accepted as well.
+	ClientID json.Number `json:"client_id"`
+
+	// URL is the URL of the page the event was triggered on.
+	URL string `json:"url"`
+
+	// Title is the title of the page the event was triggered on.
+	Title string `json:"title"`
+
+	// Referrer is the referrer of the page the event was triggered on.
+	Referrer string `json:"referrer"`
+
+	// ScreenWidth is the screen width in pixels.
+	ScreenWidth int `json:"screen_width"`
+
+	// ScreenHeight is the screen height in pixels.
+	ScreenHeight int `json:"screen_height"`
+
+	// EventName is the name of the event (required).
+	EventName string `json:"event_name"`
+
+	// EventDuration is the optional duration of the event in seconds.
+	EventDuration int `json:"event_duration"`
+
+	// EventMeta are the optional metadata fields for the event.
+	EventMeta map[string]string `json:"event_meta"`
+}
+
+// EventOptionsFromRequest returns the EventOptions and HitOptions for given client request.
+// This function can be used to accept events from pirsch-events.js. Invalid parameters are ignored and left empty,
+// but an error is returned if the body cannot be decoded or the event name is missing.
+// You might want to add additional checks before calling Tracker.Event afterwards (like for the HitOptions.ClientID).
+func EventOptionsFromRequest(r *http.Request) (EventOptions, *HitOptions, error) {
+	var req EventRequest
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxEventRequestBodySize))
+
+	if err := decoder.Decode(&req); err != nil {
+		return EventOptions{}, nil, err
+	}
+
+	name := strings.TrimSpace(req.EventName)
+
+	if name == "" {
+		return EventOptions{}, nil, ErrNoEventName
+	}
+
+	eventOptions := EventOptions{
+		Name:     name,
+		Duration: req.EventDuration,
+		Meta:     req.EventMeta,
+	}
+	options := &HitOptions{
+		ClientID:     getInt64QueryParam(req.ClientID.String()),
+		URL:          getURLQueryParam(req.URL),
+		Title:        strings.TrimSpace(req.Title),
+		Referrer:     getURLQueryParam(req.Referrer),
+		ScreenWidth:  req.ScreenWidth,
+		ScreenHeight: req.ScreenHeight,
+	}
+	return eventOptions, options, nil
+}
+
 func (options *EventOptions) getMetaData() ([]string, []string) {
 	keys, values := make([]string, 0, len(options.Meta)), make([]string, 0, len(options.Meta))
 
@@ -2,6 +2,9 @@ package pirsch
 
 import (
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -20,3 +23,39 @@ func TestEventOptions_getMetaData(t *testing.T) {
 	assert.Contains(t, v, "value")
 	assert.Contains(t, v, "world")
 }
+
+func TestEventOptionsFromRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/pirsch-event", strings.NewReader(`{
+		"client_id": "42",
+		"url": "http://foo.bar/test?utm_source=test",
+		"title": " Title ",
+		"referrer": "http://ref/",
+		"screen_width": 1920,
+		"screen_height": 1080,
+		"event_name": " event ",
+		"event_duration": 21,
+		"event_meta": {"key": "value"}
+	}`))
+	eventOptions, options, err := EventOptionsFromRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "event", eventOptions.Name)
+	assert.Equal(t, 21, eventOptions.Duration)
+	assert.Equal(t, "value", eventOptions.Meta["key"])
+	assert.Equal(t, int64(42), options.ClientID)
+	assert.Equal(t, "http://foo.bar/test?utm_source=test", options.URL)
+	assert.Equal(t, "Title", options.Title)
+	assert.Equal(t, "http://ref/", options.Referrer)
+	assert.Equal(t, 1920, options.ScreenWidth)
+	assert.Equal(t, 1080, options.ScreenHeight)
+	req = httptest.NewRequest(http.MethodPost, "/pirsch-event", strings.NewReader(`{"client_id": 42, "url": "invalid", "event_name": "event"}`))
+	_, options, err = EventOptionsFromRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), options.ClientID)
+	assert.Empty(t, options.URL)
+	req = httptest.NewRequest(http.MethodPost, "/pirsch-event", strings.NewReader(`{"client_id": null, "event_name": " "}`))
+	_, _, err = EventOptionsFromRequest(req)
+	assert.Equal(t, ErrNoEventName, err)
+	req = httptest.NewRequest(http.MethodPost, "/pirsch-event", strings.NewReader(`not json`))
+	_, _, err = EventOptionsFromRequest(req)
+	assert.Error(t, err)
+}
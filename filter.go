@@ -58,9 +58,18 @@ type Filter struct {
 	// Language filters for the ISO language code.
 	Language string
 
+	// LanguageRegion filters for the region of the language (like GB for en-GB).
+	LanguageRegion string
+
 	// Country filters for the ISO country code.
 	Country string
 
+	// Region filters for the region (like a state or province).
+	Region string
+
+	// City filters for the city.
+	City string
+
 	// Referrer filters for the referrer.
 	Referrer string
 
@@ -79,6 +88,9 @@ type Filter struct {
 	// Platform filters for the platform (desktop, mobile, unknown).
 	Platform string
 
+	// DeviceType filters for the device type (desktop, mobile, tablet, tv, console, wearable, bot).
+	DeviceType string
+
 	// ScreenClass filters for the screen class.
 	ScreenClass string
 
@@ -181,47 +193,51 @@ func (filter *Filter) table() string {
 	return "hit"
 }
 
-func (filter *Filter) queryTime() ([]interface{}, string) {
-	args := make([]interface{}, 0, 5)
+func (filter *Filter) queryTime(d dialect) ([]interface{}, string) {
+	args := make([]interface{}, 0, 8)
 	args = append(args, filter.ClientID)
-	timezone := filter.Timezone.String()
 	var sqlQuery strings.Builder
 	sqlQuery.WriteString("client_id = ? ")
+	tz := d.timezone(filter.Timezone)
 
 	if !filter.From.IsZero() {
-		args = append(args, filter.From)
-		sqlQuery.WriteString(fmt.Sprintf("AND toDate(time, '%s') >= toDate(?, '%s') ", timezone, timezone))
+		args = append(args, tz, filter.From)
+		sqlQuery.WriteString("AND " + d.date("time") + " >= " + d.dateParam() + " ")
 	}
 
 	if !filter.To.IsZero() {
-		args = append(args, filter.To)
-		sqlQuery.WriteString(fmt.Sprintf("AND toDate(time, '%s') <= toDate(?, '%s') ", timezone, timezone))
+		args = append(args, tz, filter.To)
+		sqlQuery.WriteString("AND " + d.date("time") + " <= " + d.dateParam() + " ")
 	}
 
 	if !filter.Day.IsZero() {
-		args = append(args, filter.Day)
-		sqlQuery.WriteString(fmt.Sprintf("AND toDate(time, '%s') = toDate(?, '%s') ", timezone, timezone))
+		args = append(args, tz, filter.Day)
+		sqlQuery.WriteString("AND " + d.date("time") + " = " + d.dateParam() + " ")
 	}
 
 	if !filter.Start.IsZero() {
 		args = append(args, filter.Start)
-		sqlQuery.WriteString(fmt.Sprintf("AND toDateTime(time, '%s') >= toDateTime(?, '%s') ", timezone, timezone))
+		sqlQuery.WriteString("AND time >= ? ")
 	}
 
 	return args, sqlQuery.String()
 }
 
-func (filter *Filter) queryFields() ([]interface{}, string) {
+func (filter *Filter) queryFields(d dialect) ([]interface{}, string) {
 	args := make([]interface{}, 0, 16)
 	fields := make([]string, 0, 16)
 	filter.appendQuery(&fields, &args, "path", filter.Path)
 	filter.appendQuery(&fields, &args, "language", filter.Language)
+	filter.appendQuery(&fields, &args, "language_region", filter.LanguageRegion)
 	filter.appendQuery(&fields, &args, "country_code", filter.Country)
+	filter.appendQuery(&fields, &args, "region", filter.Region)
+	filter.appendQuery(&fields, &args, "city", filter.City)
 	filter.appendQuery(&fields, &args, "referrer", filter.Referrer)
 	filter.appendQuery(&fields, &args, "os", filter.OS)
 	filter.appendQuery(&fields, &args, "os_version", filter.OSVersion)
 	filter.appendQuery(&fields, &args, "browser", filter.Browser)
 	filter.appendQuery(&fields, &args, "browser_version", filter.BrowserVersion)
+	filter.appendQuery(&fields, &args, "device_type", filter.DeviceType)
 	filter.appendQuery(&fields, &args, "screen_class", filter.ScreenClass)
 	filter.appendQuery(&fields, &args, "utm_source", filter.UTMSource)
 	filter.appendQuery(&fields, &args, "utm_medium", filter.UTMMedium)
@@ -235,19 +251,19 @@ func (filter *Filter) queryFields() ([]interface{}, string) {
 			platform := filter.Platform[1:]
 
 			if platform == PlatformDesktop {
-				fields = append(fields, "desktop != 1 ")
+				fields = append(fields, fmt.Sprintf("desktop != %s ", d.boolean(true)))
 			} else if platform == PlatformMobile {
-				fields = append(fields, "mobile != 1 ")
+				fields = append(fields, fmt.Sprintf("mobile != %s ", d.boolean(true)))
 			} else {
-				fields = append(fields, "(desktop = 1 OR mobile = 1) ")
+				fields = append(fields, fmt.Sprintf("(desktop = %s OR mobile = %s) ", d.boolean(true), d.boolean(true)))
 			}
 		} else {
 			if filter.Platform == PlatformDesktop {
-				fields = append(fields, "desktop = 1 ")
+				fields = append(fields, fmt.Sprintf("desktop = %s ", d.boolean(true)))
 			} else if filter.Platform == PlatformMobile {
-				fields = append(fields, "mobile = 1 ")
+				fields = append(fields, fmt.Sprintf("mobile = %s ", d.boolean(true)))
 			} else {
-				fields = append(fields, "desktop = 0 AND mobile = 0 ")
+				fields = append(fields, fmt.Sprintf("desktop = %s AND mobile = %s ", d.boolean(false), d.boolean(false)))
 			}
 		}
 	}
@@ -255,36 +271,19 @@ func (filter *Filter) queryFields() ([]interface{}, string) {
 	if filter.PathPattern != "" {
 		if strings.HasPrefix(filter.PathPattern, "!") {
 			args = append(args, filter.PathPattern[1:])
-			fields = append(fields, `match("path", ?) = 0`)
+			fields = append(fields, d.match(`"path"`, false))
 		} else {
 			args = append(args, filter.PathPattern)
-			fields = append(fields, `match("path", ?) = 1`)
+			fields = append(fields, d.match(`"path"`, true))
 		}
 	}
 
 	return args, strings.Join(fields, "AND ")
 }
 
-func (filter *Filter) withFill() ([]interface{}, string) {
-	if !filter.From.IsZero() && !filter.To.IsZero() {
-		timezone := filter.Timezone.String()
-		return []interface{}{filter.From, filter.To}, fmt.Sprintf("WITH FILL FROM toDate(?, '%s') TO toDate(?, '%s')+1 ", timezone, timezone)
-	}
-
-	return nil, ""
-}
-
-func (filter *Filter) withLimit() string {
-	if filter.Limit > 0 {
-		return fmt.Sprintf("LIMIT %d ", filter.Limit)
-	}
-
-	return ""
-}
-
-func (filter *Filter) query() ([]interface{}, string) {
-	args, query := filter.queryTime()
-	fieldArgs, queryFields := filter.queryFields()
+func (filter *Filter) query(d dialect) ([]interface{}, string) {
+	args, query := filter.queryTime(d)
+	fieldArgs, queryFields := filter.queryFields(d)
 	args = append(args, fieldArgs...)
 
 	if queryFields != "" {
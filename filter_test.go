@@ -51,14 +51,17 @@ func TestFilter_QueryTime(t *testing.T) {
 	filter.To = pastDay(2)
 	filter.Day = pastDay(1)
 	filter.Start = time.Now().UTC()
-	args, query := filter.queryTime()
-	assert.Len(t, args, 5)
+	args, query := filter.queryTime(clickHouse)
+	assert.Len(t, args, 8)
 	assert.Equal(t, NullClient, args[0])
-	assert.Equal(t, filter.From, args[1])
-	assert.Equal(t, filter.To, args[2])
-	assert.Equal(t, filter.Day, args[3])
-	assert.Equal(t, filter.Start, args[4])
-	assert.Equal(t, "client_id = ? AND toDate(time, 'UTC') >= toDate(?, 'UTC') AND toDate(time, 'UTC') <= toDate(?, 'UTC') AND toDate(time, 'UTC') = toDate(?, 'UTC') AND toDateTime(time, 'UTC') >= toDateTime(?, 'UTC') ", query)
+	assert.Equal(t, "UTC", args[1])
+	assert.Equal(t, filter.From, args[2])
+	assert.Equal(t, "UTC", args[3])
+	assert.Equal(t, filter.To, args[4])
+	assert.Equal(t, "UTC", args[5])
+	assert.Equal(t, filter.Day, args[6])
+	assert.Equal(t, filter.Start, args[7])
+	assert.Equal(t, "client_id = ? AND toDate(time, ?) >= toDate(?) AND toDate(time, ?) <= toDate(?) AND toDate(time, ?) = toDate(?) AND time >= ? ", query)
 }
 
 func TestFilter_QueryFields(t *testing.T) {
@@ -66,12 +69,16 @@ func TestFilter_QueryFields(t *testing.T) {
 	filter.Path = "/"
 	filter.PathPattern = "pattern"
 	filter.Language = "en"
+	filter.LanguageRegion = "GB"
 	filter.Country = "jp"
+	filter.Region = "england"
+	filter.City = "london"
 	filter.Referrer = "ref"
 	filter.OS = OSWindows
 	filter.OSVersion = "10"
 	filter.Browser = BrowserEdge
 	filter.BrowserVersion = "89"
+	filter.DeviceType = DeviceTypeTablet
 	filter.Platform = PlatformUnknown
 	filter.ScreenClass = "XXL"
 	filter.UTMSource = "source"
@@ -81,24 +88,28 @@ func TestFilter_QueryFields(t *testing.T) {
 	filter.UTMTerm = "term"
 	filter.EventName = "event"
 	filter.validate()
-	args, query := filter.queryFields()
-	assert.Len(t, args, 15)
+	args, query := filter.queryFields(clickHouse)
+	assert.Len(t, args, 19)
 	assert.Equal(t, "/", args[0])
 	assert.Equal(t, "en", args[1])
-	assert.Equal(t, "jp", args[2])
-	assert.Equal(t, "ref", args[3])
-	assert.Equal(t, OSWindows, args[4])
-	assert.Equal(t, "10", args[5])
-	assert.Equal(t, BrowserEdge, args[6])
-	assert.Equal(t, "89", args[7])
-	assert.Equal(t, "XXL", args[8])
-	assert.Equal(t, "source", args[9])
-	assert.Equal(t, "medium", args[10])
-	assert.Equal(t, "campaign", args[11])
-	assert.Equal(t, "content", args[12])
-	assert.Equal(t, "term", args[13])
-	assert.Equal(t, "event", args[14])
-	assert.Equal(t, "path = ? AND language = ? AND country_code = ? AND referrer = ? AND os = ? AND os_version = ? AND browser = ? AND browser_version = ? AND screen_class = ? AND utm_source = ? AND utm_medium = ? AND utm_campaign = ? AND utm_content = ? AND utm_term = ? AND event_name = ? AND desktop = 0 AND mobile = 0 ", query)
+	assert.Equal(t, "GB", args[2])
+	assert.Equal(t, "jp", args[3])
+	assert.Equal(t, "england", args[4])
+	assert.Equal(t, "london", args[5])
+	assert.Equal(t, "ref", args[6])
+	assert.Equal(t, OSWindows, args[7])
+	assert.Equal(t, "10", args[8])
+	assert.Equal(t, BrowserEdge, args[9])
+	assert.Equal(t, "89", args[10])
+	assert.Equal(t, DeviceTypeTablet, args[11])
+	assert.Equal(t, "XXL", args[12])
+	assert.Equal(t, "source", args[13])
+	assert.Equal(t, "medium", args[14])
+	assert.Equal(t, "campaign", args[15])
+	assert.Equal(t, "content", args[16])
+	assert.Equal(t, "term", args[17])
+	assert.Equal(t, "event", args[18])
+	assert.Equal(t, "path = ? AND language = ? AND language_region = ? AND country_code = ? AND region = ? AND city = ? AND referrer = ? AND os = ? AND os_version = ? AND browser = ? AND browser_version = ? AND device_type = ? AND screen_class = ? AND utm_source = ? AND utm_medium = ? AND utm_campaign = ? AND utm_content = ? AND utm_term = ? AND event_name = ? AND desktop = 0 AND mobile = 0 ", query)
 }
 
 func TestFilter_QueryFieldsInvert(t *testing.T) {
@@ -106,12 +117,16 @@ func TestFilter_QueryFieldsInvert(t *testing.T) {
 	filter.Path = "!/"
 	filter.PathPattern = "!pattern"
 	filter.Language = "!en"
+	filter.LanguageRegion = "!GB"
 	filter.Country = "!jp"
+	filter.Region = "!england"
+	filter.City = "!london"
 	filter.Referrer = "!ref"
 	filter.OS = "!" + OSWindows
 	filter.OSVersion = "!10"
 	filter.Browser = "!" + BrowserEdge
 	filter.BrowserVersion = "!89"
+	filter.DeviceType = "!" + DeviceTypeTablet
 	filter.Platform = "!" + PlatformUnknown
 	filter.ScreenClass = "!XXL"
 	filter.UTMSource = "!source"
@@ -121,70 +136,74 @@ func TestFilter_QueryFieldsInvert(t *testing.T) {
 	filter.UTMTerm = "!term"
 	filter.EventName = "!event"
 	filter.validate()
-	args, query := filter.queryFields()
-	assert.Len(t, args, 15)
+	args, query := filter.queryFields(clickHouse)
+	assert.Len(t, args, 19)
 	assert.Equal(t, "/", args[0])
 	assert.Equal(t, "en", args[1])
-	assert.Equal(t, "jp", args[2])
-	assert.Equal(t, "ref", args[3])
-	assert.Equal(t, OSWindows, args[4])
-	assert.Equal(t, "10", args[5])
-	assert.Equal(t, BrowserEdge, args[6])
-	assert.Equal(t, "89", args[7])
-	assert.Equal(t, "XXL", args[8])
-	assert.Equal(t, "source", args[9])
-	assert.Equal(t, "medium", args[10])
-	assert.Equal(t, "campaign", args[11])
-	assert.Equal(t, "content", args[12])
-	assert.Equal(t, "term", args[13])
-	assert.Equal(t, "event", args[14])
-	assert.Equal(t, "path != ? AND language != ? AND country_code != ? AND referrer != ? AND os != ? AND os_version != ? AND browser != ? AND browser_version != ? AND screen_class != ? AND utm_source != ? AND utm_medium != ? AND utm_campaign != ? AND utm_content != ? AND utm_term != ? AND event_name != ? AND (desktop = 1 OR mobile = 1) ", query)
+	assert.Equal(t, "GB", args[2])
+	assert.Equal(t, "jp", args[3])
+	assert.Equal(t, "england", args[4])
+	assert.Equal(t, "london", args[5])
+	assert.Equal(t, "ref", args[6])
+	assert.Equal(t, OSWindows, args[7])
+	assert.Equal(t, "10", args[8])
+	assert.Equal(t, BrowserEdge, args[9])
+	assert.Equal(t, "89", args[10])
+	assert.Equal(t, DeviceTypeTablet, args[11])
+	assert.Equal(t, "XXL", args[12])
+	assert.Equal(t, "source", args[13])
+	assert.Equal(t, "medium", args[14])
+	assert.Equal(t, "campaign", args[15])
+	assert.Equal(t, "content", args[16])
+	assert.Equal(t, "term", args[17])
+	assert.Equal(t, "event", args[18])
+	assert.Equal(t, "path != ? AND language != ? AND language_region != ? AND country_code != ? AND region != ? AND city != ? AND referrer != ? AND os != ? AND os_version != ? AND browser != ? AND browser_version != ? AND device_type != ? AND screen_class != ? AND utm_source != ? AND utm_medium != ? AND utm_campaign != ? AND utm_content != ? AND utm_term != ? AND event_name != ? AND (desktop = 1 OR mobile = 1) ", query)
 }
 
 func TestFilter_QueryFieldsPlatform(t *testing.T) {
 	filter := NewFilter(NullClient)
 	filter.Platform = PlatformDesktop
-	args, query := filter.queryFields()
+	args, query := filter.queryFields(clickHouse)
 	assert.Len(t, args, 0)
 	assert.Equal(t, "desktop = 1 ", query)
 	filter = NewFilter(NullClient)
 	filter.Platform = PlatformMobile
-	args, query = filter.queryFields()
+	args, query = filter.queryFields(clickHouse)
 	assert.Len(t, args, 0)
 	assert.Equal(t, "mobile = 1 ", query)
 	filter = NewFilter(NullClient)
 	filter.Platform = PlatformUnknown
-	args, query = filter.queryFields()
+	args, query = filter.queryFields(clickHouse)
 	assert.Len(t, args, 0)
 	assert.Equal(t, "desktop = 0 AND mobile = 0 ", query)
-	_, query = filter.query()
+	_, query = filter.query(clickHouse)
 	assert.Contains(t, query, "desktop = 0 AND mobile = 0")
 }
 
 func TestFilter_QueryFieldsPlatformInvert(t *testing.T) {
 	filter := NewFilter(NullClient)
 	filter.Platform = "!" + PlatformDesktop
-	args, query := filter.queryFields()
+	args, query := filter.queryFields(clickHouse)
 	assert.Len(t, args, 0)
 	assert.Equal(t, "desktop != 1 ", query)
 	filter = NewFilter(NullClient)
 	filter.Platform = "!" + PlatformMobile
-	args, query = filter.queryFields()
+	args, query = filter.queryFields(clickHouse)
 	assert.Len(t, args, 0)
 	assert.Equal(t, "mobile != 1 ", query)
 	filter = NewFilter(NullClient)
 	filter.Platform = "!" + PlatformUnknown
-	args, query = filter.queryFields()
+	args, query = filter.queryFields(clickHouse)
 	assert.Len(t, args, 0)
 	assert.Equal(t, "(desktop = 1 OR mobile = 1) ", query)
-	_, query = filter.query()
+	_, query = filter.query(clickHouse)
 	assert.Contains(t, query, "(desktop = 1 OR mobile = 1)")
 }
 
 func TestFilter_QueryFieldsPathPattern(t *testing.T) {
 	filter := NewFilter(NullClient)
 	filter.PathPattern = "/some/pattern"
-	args, query := filter.queryFields()
+	args, query := filter.queryFields(clickHouse)
 	assert.Len(t, args, 1)
 	assert.Equal(t, "/some/pattern", args[0])
 	assert.Equal(t, `match("path", ?) = 1`, query)
@@ -193,33 +212,12 @@ func TestFilter_QueryFieldsPathPattern(t *testing.T) {
 func TestFilter_QueryFieldsPathPatternInvert(t *testing.T) {
 	filter := NewFilter(NullClient)
 	filter.PathPattern = "!/some/pattern"
-	args, query := filter.queryFields()
+	args, query := filter.queryFields(clickHouse)
 	assert.Len(t, args, 1)
 	assert.Equal(t, "/some/pattern", args[0])
 	assert.Equal(t, `match("path", ?) = 0`, query)
 }
 
-func TestFilter_WithFill(t *testing.T) {
-	filter := NewFilter(NullClient)
-	args, query := filter.withFill()
-	assert.Len(t, args, 0)
-	assert.Empty(t, query)
-	filter.From = pastDay(10)
-	filter.To = pastDay(5)
-	args, query = filter.withFill()
-	assert.Len(t, args, 2)
-	assert.Equal(t, filter.From, args[0])
-	assert.Equal(t, filter.To, args[1])
-	assert.Equal(t, "WITH FILL FROM toDate(?, 'UTC') TO toDate(?, 'UTC')+1 ", query)
-}
-
-func TestFilter_WithLimit(t *testing.T) {
-	filter := NewFilter(NullClient)
-	assert.Empty(t, filter.withLimit())
-	filter.Limit = 42
-	assert.Equal(t, "LIMIT 42 ", filter.withLimit())
-}
-
 func pastDay(n int) time.Time {
 	now := time.Now().UTC()
 	return time.Date(now.Year(), now.Month(), now.Day()-n, 0, 0, 0, 0, time.UTC)
@@ -1,24 +1,129 @@
 package pirsch
 
 import (
+	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"io"
+	"math/bits"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
+// FingerprintInput is a part of the request used to generate the fingerprint.
+type FingerprintInput int
+
+const (
+	// FingerprintUserAgent is the User-Agent header.
+	FingerprintUserAgent = FingerprintInput(iota)
+
+	// FingerprintIP is the IP address of the visitor (see IPExtractor).
+	FingerprintIP
+
+	// FingerprintClientID is the HitOptions.ClientID.
+	FingerprintClientID
+
+	// FingerprintHost is the host the request was sent to.
+	FingerprintHost
+
+	// FingerprintAcceptLanguage is the Accept-Language header.
+	FingerprintAcceptLanguage
+)
+
+// FingerprintHash is the hash function used to generate the fingerprint.
+// All hash functions return 16 bytes (32 hex characters), to fit into the fingerprint column.
+type FingerprintHash int
+
+const (
+	// FingerprintSHA256MD5 hashes the inputs and salt using SHA256 and the result using MD5.
+	FingerprintSHA256MD5 = FingerprintHash(iota)
+
+	// FingerprintHMAC hashes the inputs using HMAC-SHA256 keyed with the salt, truncated to 16 bytes.
+	FingerprintHMAC
+
+	// FingerprintSipHash hashes the inputs using SipHash-2-4 (128 bit) keyed with a key derived from the salt.
+	FingerprintSipHash
+)
+
+// Fingerprinter generates the fingerprint for a visitor.
+type Fingerprinter interface {
+	// Fingerprint returns a hash for given request, client IP, client ID, and salt.
+	// The hash must be a hex string of 32 characters.
+	Fingerprint(r *http.Request, ip string, clientID int64, salt string) string
+}
+
+// RequestFingerprinter is a Fingerprinter for configurable inputs and hash functions.
+// Leaving it empty generates the same fingerprint as Fingerprint.
+type RequestFingerprinter struct {
+	// Inputs are the parts of the request used to generate the fingerprint, in order.
+	// If you leave it empty, the User-Agent and IP are used. Leave out the IP for a strict privacy mode.
+	Inputs []FingerprintInput
+
+	// Hash is the hash function.
+	Hash FingerprintHash
+}
+
+// Fingerprint implements the Fingerprinter interface.
+func (fingerprinter *RequestFingerprinter) Fingerprint(r *http.Request, ip string, clientID int64, salt string) string {
+	inputs := fingerprinter.Inputs
+
+	if len(inputs) == 0 {
+		inputs = []FingerprintInput{FingerprintUserAgent, FingerprintIP}
+	}
+
+	var sb strings.Builder
+
+	for _, input := range inputs {
+		switch input {
+		case FingerprintUserAgent:
+			sb.WriteString(r.Header.Get("User-Agent"))
+		case FingerprintIP:
+			sb.WriteString(ip)
+		case FingerprintClientID:
+			sb.WriteString(strconv.FormatInt(clientID, 10))
+		case FingerprintHost:
+			sb.WriteString(r.Host)
+		case FingerprintAcceptLanguage:
+			sb.WriteString(r.Header.Get("Accept-Language"))
+		}
+	}
+
+	switch fingerprinter.Hash {
+	case FingerprintHMAC:
+		mac := hmac.New(sha256.New, []byte(salt))
+		mac.Write([]byte(sb.String()))
+		return hex.EncodeToString(mac.Sum(nil)[:md5.Size])
+	case FingerprintSipHash:
+		key := sha256.Sum256([]byte(salt))
+		hash := sipHash128(key[:16], []byte(sb.String()))
+		return hex.EncodeToString(hash[:])
+	}
+
+	sb.WriteString(salt)
+	return sha256MD5(sb.String())
+}
+
 // Fingerprint returns a hash for given request and salt.
 // The hash is unique for the visitor.
 func Fingerprint(r *http.Request, salt string) string {
+	return fingerprint(r.Header.Get("User-Agent"), getIP(r), salt)
+}
+
+func fingerprint(userAgent, ip, salt string) string {
 	var sb strings.Builder
-	sb.WriteString(r.Header.Get("User-Agent"))
-	sb.WriteString(getIP(r))
+	sb.WriteString(userAgent)
+	sb.WriteString(ip)
 	sb.WriteString(salt)
+	return sha256MD5(sb.String())
+}
+
+func sha256MD5(data string) string {
 	sha256Hash := sha256.New()
 
-	if _, err := io.WriteString(sha256Hash, sb.String()); err != nil {
+	if _, err := io.WriteString(sha256Hash, data); err != nil {
 		return "" // this should not fail...
 	}
 
@@ -31,3 +136,64 @@ func Fingerprint(r *http.Request, salt string) string {
 
 	return hex.EncodeToString(md5Hash.Sum(nil))
 }
+
+// sipHash128 returns the SipHash-2-4 with 128 bit output for given 16 byte key and data.
+func sipHash128(key, data []byte) [16]byte {
+	k0 := binary.LittleEndian.Uint64(key[:8])
+	k1 := binary.LittleEndian.Uint64(key[8:16])
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d ^ 0xee
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+	n := len(data)
+
+	for len(data) >= 8 {
+		m := binary.LittleEndian.Uint64(data[:8])
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+		data = data[8:]
+	}
+
+	var last [8]byte
+	copy(last[:], data)
+	last[7] = byte(n)
+	m := binary.LittleEndian.Uint64(last[:])
+	v3 ^= m
+	round()
+	round()
+	v0 ^= m
+	v2 ^= 0xee
+
+	for i := 0; i < 4; i++ {
+		round()
+	}
+
+	var hash [16]byte
+	binary.LittleEndian.PutUint64(hash[:8], v0^v1^v2^v3)
+	v1 ^= 0xdd
+
+	for i := 0; i < 4; i++ {
+		round()
+	}
+
+	binary.LittleEndian.PutUint64(hash[8:], v0^v1^v2^v3)
+	return hash
+}
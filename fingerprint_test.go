@@ -1,6 +1,7 @@
 package pirsch
 
 import (
+	"encoding/hex"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
@@ -13,3 +14,50 @@ func TestFingerprint(t *testing.T) {
 	req.RemoteAddr = "127.0.0.1:80"
 	assert.Equal(t, "6d1f15a605abe4edbe53f18a57ce7654", Fingerprint(req, "salt"))
 }
+
+func TestRequestFingerprinter(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "test")
+	req.Header.Set("Accept-Language", "de")
+	req.RemoteAddr = "127.0.0.1:80"
+	fingerprinter := &RequestFingerprinter{}
+	assert.Equal(t, Fingerprint(req, "salt"), fingerprinter.Fingerprint(req, getIP(req), 42, "salt"))
+	fingerprinter.Inputs = []FingerprintInput{FingerprintUserAgent, FingerprintAcceptLanguage}
+	noIP := fingerprinter.Fingerprint(req, getIP(req), 42, "salt")
+	assert.Len(t, noIP, 32)
+	req.RemoteAddr = "127.0.0.2:80"
+	assert.Equal(t, noIP, fingerprinter.Fingerprint(req, getIP(req), 42, "salt"), "the IP must not be used")
+	fingerprinter.Inputs = []FingerprintInput{FingerprintUserAgent, FingerprintClientID, FingerprintHost}
+	assert.NotEqual(t, fingerprinter.Fingerprint(req, getIP(req), 1, "salt"), fingerprinter.Fingerprint(req, getIP(req), 2, "salt"))
+	fingerprinter.Inputs = nil
+
+	for _, hash := range []FingerprintHash{FingerprintHMAC, FingerprintSipHash} {
+		fingerprinter.Hash = hash
+		fingerprint := fingerprinter.Fingerprint(req, getIP(req), 0, "salt")
+		assert.Len(t, fingerprint, 32)
+		assert.NotEqual(t, Fingerprint(req, "salt"), fingerprint)
+		assert.NotEqual(t, fingerprinter.Fingerprint(req, getIP(req), 0, "other"), fingerprint)
+		assert.Equal(t, fingerprinter.Fingerprint(req, getIP(req), 0, "salt"), fingerprint)
+	}
+}
+
+func TestSipHash128(t *testing.T) {
+	// test vectors from the SipHash reference implementation
+	key := make([]byte, 16)
+	data := make([]byte, 15)
+
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	hash := sipHash128(key, nil)
+	assert.Equal(t, "a3817f04ba25a8e66df67214c7550293", hex.EncodeToString(hash[:]))
+	hash = sipHash128(key, data[:1])
+	assert.Equal(t, "da87c1d86b99af44347659119b22fc45", hex.EncodeToString(hash[:]))
+	hash = sipHash128(key, data)
+	assert.Equal(t, "5493e99933b0a8117e08ec0f97cfc3d9", hex.EncodeToString(hash[:]))
+}
@@ -0,0 +1,265 @@
+package pirsch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidGeoCSV is returned by the CSVGeoResolver if a line cannot be parsed.
+var ErrInvalidGeoCSV = errors.New("invalid IP range")
+
+// GeoCSVFormat is the format of the CSV file loaded by the CSVGeoResolver.
+type GeoCSVFormat int
+
+const (
+	// GeoCSVDBIPCountry is the DB-IP IP to Country Lite format (ip_start, ip_end, country).
+	GeoCSVDBIPCountry = GeoCSVFormat(iota)
+
+	// GeoCSVDBIPCity is the DB-IP IP to City Lite format (ip_start, ip_end, continent, country, region, city, latitude, longitude).
+	GeoCSVDBIPCity
+
+	// GeoCSVIP2LocationCountry is the IP2Location LITE DB1 format (ip_from, ip_to, country_code, country_name).
+	GeoCSVIP2LocationCountry
+
+	// GeoCSVIP2LocationCity is the IP2Location LITE DB3 format (ip_from, ip_to, country_code, country_name, region, city).
+	GeoCSVIP2LocationCity
+)
+
+// columns returns the column index of the country code, region, and city, or -1 if the column does not exist.
+func (format GeoCSVFormat) columns() (int, int, int) {
+	switch format {
+	case GeoCSVDBIPCity:
+		return 3, 4, 5
+	case GeoCSVIP2LocationCountry:
+		return 2, -1, -1
+	case GeoCSVIP2LocationCity:
+		return 2, 4, 5
+	}
+
+	return 2, -1, -1
+}
+
+// CSVGeoResolverConfig is the configuration for the CSVGeoResolver.
+type CSVGeoResolverConfig struct {
+	// File is the path (including the filename) to the CSV file.
+	// Files ending with .gz are decompressed.
+	File string
+
+	// Format is the format of the CSV file.
+	Format GeoCSVFormat
+}
+
+// CSVGeoResolver is a GeoResolver looking up the geo location from a CSV file of IP ranges, like the DB-IP or IP2Location lite databases.
+// IPs can be written in their textual form or as decimal numbers. The ranges are kept in memory, sorted by their start IP.
+type CSVGeoResolver struct {
+	ranges    []geoIPRange
+	locations []GeoLocation
+}
+
+type geoIPRange struct {
+	start    [net.IPv6len]byte
+	end      [net.IPv6len]byte
+	maxEnd   [net.IPv6len]byte // the maximum end of all ranges up to this one, to find overlapping ranges
+	location int
+}
+
+// NewCSVGeoResolver creates a new CSVGeoResolver for given configuration.
+// The file is loaded into memory, and the first line is skipped if it is a header.
+func NewCSVGeoResolver(config CSVGeoResolverConfig) (*CSVGeoResolver, error) {
+	file, err := os.Open(config.File)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			logger.Printf("error closing CSV file")
+		}
+	}()
+	var r io.Reader = file
+
+	if strings.HasSuffix(config.File, ".gz") {
+		gzipFile, err := gzip.NewReader(file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer func() {
+			if err := gzipFile.Close(); err != nil {
+				logger.Printf("error closing CSV zip file")
+			}
+		}()
+		r = gzipFile
+	}
+
+	return newCSVGeoResolver(r, config.Format)
+}
+
+func newCSVGeoResolver(r io.Reader, format GeoCSVFormat) (*CSVGeoResolver, error) {
+	countryColumn, regionColumn, cityColumn := format.columns()
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	resolver := &CSVGeoResolver{
+		ranges:    make([]geoIPRange, 0, 1024),
+		locations: make([]GeoLocation, 0, 256),
+	}
+	locations := make(map[GeoLocation]int)
+
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		start, end, ok := parseGeoCSVRange(record)
+
+		if !ok {
+			// skip the header
+			if line == 1 {
+				continue
+			}
+
+			return nil, fmt.Errorf("%w in line %d", ErrInvalidGeoCSV, line)
+		}
+
+		if len(record) <= countryColumn || len(record) <= cityColumn {
+			return nil, fmt.Errorf("%w in line %d", ErrInvalidGeoCSV, line)
+		}
+
+		location := GeoLocation{CountryCode: geoCSVValue(record[countryColumn])}
+
+		// ZZ is used for unknown countries by DB-IP
+		if location.CountryCode == "ZZ" {
+			location.CountryCode = ""
+		}
+
+		location.CountryCode = strings.ToLower(location.CountryCode)
+
+		if regionColumn > -1 {
+			location.Region = shortenString(geoCSVValue(record[regionColumn]), 200)
+		}
+
+		if cityColumn > -1 {
+			location.City = shortenString(geoCSVValue(record[cityColumn]), 200)
+		}
+
+		index, found := locations[location]
+
+		if !found {
+			index = len(resolver.locations)
+			locations[location] = index
+			resolver.locations = append(resolver.locations, location)
+		}
+
+		resolver.ranges = append(resolver.ranges, geoIPRange{
+			start:    start,
+			end:      end,
+			location: index,
+		})
+	}
+
+	sort.Slice(resolver.ranges, func(i, j int) bool {
+		return bytes.Compare(resolver.ranges[i].start[:], resolver.ranges[j].start[:]) < 0
+	})
+
+	for i := range resolver.ranges {
+		resolver.ranges[i].maxEnd = resolver.ranges[i].end
+
+		if i > 0 && bytes.Compare(resolver.ranges[i-1].maxEnd[:], resolver.ranges[i].end[:]) > 0 {
+			resolver.ranges[i].maxEnd = resolver.ranges[i-1].maxEnd
+		}
+	}
+
+	return resolver, nil
+}
+
+// Resolve implements the GeoResolver interface.
+// If the IP is within multiple ranges, the range starting closest to the IP is used.
+func (resolver *CSVGeoResolver) Resolve(r *http.Request, ip string) (GeoLocation, error) {
+	parsedIP := net.ParseIP(ip)
+
+	if parsedIP == nil {
+		return GeoLocation{}, ErrInvalidIP
+	}
+
+	var key [net.IPv6len]byte
+	copy(key[:], parsedIP.To16())
+	i := sort.Search(len(resolver.ranges), func(i int) bool {
+		return bytes.Compare(resolver.ranges[i].start[:], key[:]) > 0
+	}) - 1
+
+	for ; i >= 0 && bytes.Compare(resolver.ranges[i].maxEnd[:], key[:]) >= 0; i-- {
+		if bytes.Compare(resolver.ranges[i].end[:], key[:]) >= 0 {
+			return resolver.locations[resolver.ranges[i].location], nil
+		}
+	}
+
+	return GeoLocation{}, nil
+}
+
+// parseGeoCSVRange parses the start and end IP from the first two columns.
+func parseGeoCSVRange(record []string) ([net.IPv6len]byte, [net.IPv6len]byte, bool) {
+	var start, end [net.IPv6len]byte
+
+	if len(record) < 2 {
+		return start, end, false
+	}
+
+	start, startOK := parseGeoCSVIP(record[0])
+	end, endOK := parseGeoCSVIP(record[1])
+	return start, end, startOK && endOK && bytes.Compare(start[:], end[:]) <= 0
+}
+
+// parseGeoCSVIP parses an IP in its textual form or as a decimal number.
+// Numbers up to 2^32-1 are treated as IPv4 addresses.
+func parseGeoCSVIP(ip string) ([net.IPv6len]byte, bool) {
+	var out [net.IPv6len]byte
+	parsedIP := net.ParseIP(ip)
+
+	if parsedIP != nil {
+		copy(out[:], parsedIP.To16())
+		return out, true
+	}
+
+	n, ok := new(big.Int).SetString(ip, 10)
+
+	if !ok || n.Sign() < 0 || n.BitLen() > 128 {
+		return out, false
+	}
+
+	if n.BitLen() <= 32 {
+		v := n.Uint64()
+		copy(out[:], net.IPv4(byte(v>>24), byte(v>>16), byte(v>>8), byte(v)).To16())
+		return out, true
+	}
+
+	n.FillBytes(out[:])
+	return out, true
+}
+
+// geoCSVValue returns the trimmed value, or an empty string if it is unknown (-).
+func geoCSVValue(value string) string {
+	value = strings.TrimSpace(value)
+
+	if value == "-" {
+		return ""
+	}
+
+	return value
+}
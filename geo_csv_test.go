@@ -0,0 +1,136 @@
+package pirsch
+
+import (
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCSVGeoResolverDBIP(t *testing.T) {
+	resolver, err := NewCSVGeoResolver(CSVGeoResolverConfig{
+		File: writeTestGeoCSV(t, "dbip-country-lite.csv", `1.0.0.0,1.0.0.255,AU
+1.0.1.0,1.0.3.255,CN
+81.2.69.0,81.2.69.255,GB
+2001:db8::,2001:db8:ffff:ffff:ffff:ffff:ffff:ffff,DE
+10.0.0.0,10.255.255.255,ZZ
+`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, GeoLocation{CountryCode: "au"}, resolveTestIP(t, resolver, "1.0.0.0"))
+	assert.Equal(t, GeoLocation{CountryCode: "au"}, resolveTestIP(t, resolver, "1.0.0.255"))
+	assert.Equal(t, GeoLocation{CountryCode: "cn"}, resolveTestIP(t, resolver, "1.0.2.1"))
+	assert.Equal(t, GeoLocation{CountryCode: "gb"}, resolveTestIP(t, resolver, "81.2.69.142"))
+	assert.Equal(t, GeoLocation{CountryCode: "de"}, resolveTestIP(t, resolver, "2001:db8::1"))
+	assert.Empty(t, resolveTestIP(t, resolver, "10.1.2.3"))
+	assert.Empty(t, resolveTestIP(t, resolver, "1.0.4.0"))
+	assert.Empty(t, resolveTestIP(t, resolver, "0.0.0.1"))
+	assert.Empty(t, resolveTestIP(t, resolver, "2001:db9::1"))
+	_, err = resolver.Resolve(nil, "invalid")
+	assert.Equal(t, ErrInvalidIP, err)
+	resolver, err = NewCSVGeoResolver(CSVGeoResolverConfig{
+		File: writeTestGeoCSV(t, "dbip-city-lite.csv.gz", `ip_start,ip_end,continent,country,stateprov,city,latitude,longitude
+81.2.69.0,81.2.69.255,EU,GB,England,London,51.5085,-0.12574
+81.2.70.0,81.2.70.255,EU,GB,"Scotland","Glasgow, City of",55.8652,-4.25763
+`),
+		Format: GeoCSVDBIPCity,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, GeoLocation{CountryCode: "gb", Region: "England", City: "London"}, resolveTestIP(t, resolver, "81.2.69.142"))
+	assert.Equal(t, GeoLocation{CountryCode: "gb", Region: "Scotland", City: "Glasgow, City of"}, resolveTestIP(t, resolver, "81.2.70.1"))
+	assert.Len(t, resolver.ranges, 2)
+}
+
+func TestCSVGeoResolverIP2Location(t *testing.T) {
+	resolver, err := NewCSVGeoResolver(CSVGeoResolverConfig{
+		File: writeTestGeoCSV(t, "IP2LOCATION-LITE-DB1.CSV", `"0","16777215","-","-"
+"16777216","16777471","US","United States of America"
+"1359103232","1359103487","GB","United Kingdom of Great Britain and Northern Ireland"
+"42540766411282592856903984951653826560","42540766490510755371168322545197776895","DE","Germany"
+`),
+		Format: GeoCSVIP2LocationCountry,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, resolveTestIP(t, resolver, "0.0.0.1"))
+	assert.Equal(t, GeoLocation{CountryCode: "us"}, resolveTestIP(t, resolver, "1.0.0.1"))
+	assert.Equal(t, GeoLocation{CountryCode: "gb"}, resolveTestIP(t, resolver, "81.2.69.142"))
+	assert.Equal(t, GeoLocation{CountryCode: "de"}, resolveTestIP(t, resolver, "2001:db8::1"))
+	resolver, err = NewCSVGeoResolver(CSVGeoResolverConfig{
+		File: writeTestGeoCSV(t, "IP2LOCATION-LITE-DB3.IPV6.CSV", `"281470698520576","281470698520831","US","United States of America","California","Los Angeles"
+"281472040846592","281472040846847","GB","United Kingdom of Great Britain and Northern Ireland","England","London"
+"281472040846848","281472040847103","-","-","-","-"
+`),
+		Format: GeoCSVIP2LocationCity,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, GeoLocation{CountryCode: "us", Region: "California", City: "Los Angeles"}, resolveTestIP(t, resolver, "1.0.0.1"))
+	assert.Equal(t, GeoLocation{CountryCode: "gb", Region: "England", City: "London"}, resolveTestIP(t, resolver, "81.2.69.142"))
+	assert.Empty(t, resolveTestIP(t, resolver, "81.2.70.1"))
+	assert.Len(t, resolver.locations, 3, "locations must be deduplicated")
+}
+
+func TestCSVGeoResolverOverlapping(t *testing.T) {
+	resolver, err := NewCSVGeoResolver(CSVGeoResolverConfig{
+		File: writeTestGeoCSV(t, "overlapping.csv", `10.0.0.0,10.255.255.255,US
+10.1.0.0,10.1.255.255,DE
+10.1.1.0,10.1.1.255,FR
+10.2.0.0,10.2.0.255,JP
+`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "us", resolveTestIP(t, resolver, "10.0.0.1").CountryCode)
+	assert.Equal(t, "de", resolveTestIP(t, resolver, "10.1.0.1").CountryCode)
+	assert.Equal(t, "fr", resolveTestIP(t, resolver, "10.1.1.1").CountryCode)
+	assert.Equal(t, "de", resolveTestIP(t, resolver, "10.1.2.1").CountryCode)
+	assert.Equal(t, "de", resolveTestIP(t, resolver, "10.1.255.255").CountryCode)
+	assert.Equal(t, "jp", resolveTestIP(t, resolver, "10.2.0.1").CountryCode)
+	assert.Equal(t, "us", resolveTestIP(t, resolver, "10.3.0.1").CountryCode)
+	assert.Empty(t, resolveTestIP(t, resolver, "11.0.0.1").CountryCode)
+}
+
+func TestCSVGeoResolverInvalid(t *testing.T) {
+	_, err := NewCSVGeoResolver(CSVGeoResolverConfig{File: filepath.Join(t.TempDir(), "missing.csv")})
+	assert.Error(t, err)
+	_, err = NewCSVGeoResolver(CSVGeoResolverConfig{
+		File: writeTestGeoCSV(t, "invalid.csv", "1.0.0.0,1.0.0.255,AU\ninvalid,1.0.1.255,CN\n"),
+	})
+	assert.ErrorIs(t, err, ErrInvalidGeoCSV)
+	assert.Contains(t, err.Error(), "line 2")
+	_, err = NewCSVGeoResolver(CSVGeoResolverConfig{
+		File: writeTestGeoCSV(t, "reversed.csv", "1.0.0.0,1.0.0.255,AU\n1.0.1.255,1.0.1.0,CN\n"),
+	})
+	assert.ErrorIs(t, err, ErrInvalidGeoCSV)
+	_, err = NewCSVGeoResolver(CSVGeoResolverConfig{
+		File:   writeTestGeoCSV(t, "columns.csv", "1.0.0.0,1.0.0.255,EU,AU\n"),
+		Format: GeoCSVDBIPCity,
+	})
+	assert.ErrorIs(t, err, ErrInvalidGeoCSV)
+}
+
+func resolveTestIP(t *testing.T, resolver GeoResolver, ip string) GeoLocation {
+	location, err := resolver.Resolve(nil, ip)
+	assert.NoError(t, err)
+	return location
+}
+
+// writeTestGeoCSV writes the CSV to a temporary file, which is compressed if the filename ends with .gz.
+func writeTestGeoCSV(t *testing.T, filename, data string) string {
+	file := filepath.Join(t.TempDir(), filename)
+	f, err := os.Create(file)
+	assert.NoError(t, err)
+
+	if strings.HasSuffix(filename, ".gz") {
+		w := gzip.NewWriter(f)
+		_, err = w.Write([]byte(data))
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+	} else {
+		_, err = f.WriteString(data)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, f.Close())
+	return file
+}
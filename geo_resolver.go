@@ -0,0 +1,67 @@
+package pirsch
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	// HeaderCFIPCountry is the header Cloudflare sets to the country code of the visitor.
+	HeaderCFIPCountry = "CF-IPCountry"
+
+	// HeaderCloudFrontViewerCountry is the header AWS CloudFront sets to the country code of the visitor.
+	HeaderCloudFrontViewerCountry = "CloudFront-Viewer-Country"
+)
+
+// GeoResolver looks up the geo location for a request.
+// Implementations are the GeoDB (MaxMind), CSVGeoResolver (IP ranges), CDNGeoResolver (country header), and GeoResolverFunc (callback).
+type GeoResolver interface {
+	// Resolve returns the geo location for given request and client IP.
+	// An unknown IP returns an empty GeoLocation, while an error is returned if the lookup failed (like for an invalid IP).
+	Resolve(r *http.Request, ip string) (GeoLocation, error)
+}
+
+// GeoResolverFunc is a function implementing the GeoResolver interface.
+// It can be used to look up the geo location using a custom database or service.
+type GeoResolverFunc func(r *http.Request, ip string) (GeoLocation, error)
+
+// Resolve implements the GeoResolver interface.
+func (f GeoResolverFunc) Resolve(r *http.Request, ip string) (GeoLocation, error) {
+	return f(r, ip)
+}
+
+// CDNGeoResolver reads the country code from a header set by a CDN, like HeaderCFIPCountry for Cloudflare.
+// The header can be spoofed by the client, so it must only be used if all requests pass through the CDN.
+type CDNGeoResolver struct {
+	// Header is the header containing the ISO country code.
+	Header string
+
+	// Resolver is used to look up the geo location, if set.
+	// The country code from the header takes precedence. The region and city are dropped if the country code differs.
+	Resolver GeoResolver
+}
+
+// Resolve implements the GeoResolver interface.
+func (resolver *CDNGeoResolver) Resolve(r *http.Request, ip string) (GeoLocation, error) {
+	var location GeoLocation
+	var err error
+
+	if resolver.Resolver != nil {
+		location, err = resolver.Resolver.Resolve(r, ip)
+	}
+
+	countryCode := strings.ToLower(strings.TrimSpace(r.Header.Get(resolver.Header)))
+
+	// XX is used for unknown countries, and T1 for Tor by Cloudflare
+	if len(countryCode) != 2 || countryCode == "xx" || countryCode == "t1" {
+		return location, err
+	}
+
+	if location.CountryCode != countryCode {
+		location.CountryCode = countryCode
+		location.Region = ""
+		location.City = ""
+	}
+
+	return location, nil
+}
@@ -0,0 +1,96 @@
+package pirsch
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestGeoResolverFunc(t *testing.T) {
+	resolver := GeoResolverFunc(func(r *http.Request, ip string) (GeoLocation, error) {
+		if ip == "81.2.69.142" {
+			return GeoLocation{CountryCode: "gb", City: "London"}, nil
+		}
+
+		return GeoLocation{}, errors.New("not found")
+	})
+	location, err := resolver.Resolve(nil, "81.2.69.142")
+	assert.NoError(t, err)
+	assert.Equal(t, GeoLocation{CountryCode: "gb", City: "London"}, location)
+	_, err = resolver.Resolve(nil, "127.0.0.1")
+	assert.Error(t, err)
+}
+
+func TestCDNGeoResolver(t *testing.T) {
+	geoDB, err := NewGeoDB(GeoDBConfig{
+		File: filepath.Join("geodb/GeoIP2-Country-Test.mmdb"),
+	})
+	assert.NoError(t, err)
+	resolver := &CDNGeoResolver{Header: HeaderCFIPCountry}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, resolveTestRequest(t, resolver, req, "81.2.69.142"))
+	req.Header.Set(HeaderCFIPCountry, "DE")
+	assert.Equal(t, GeoLocation{CountryCode: "de"}, resolveTestRequest(t, resolver, req, "81.2.69.142"))
+
+	for _, countryCode := range []string{"XX", "T1", "invalid", ""} {
+		req.Header.Set(HeaderCFIPCountry, countryCode)
+		assert.Empty(t, resolveTestRequest(t, resolver, req, "81.2.69.142"))
+	}
+
+	resolver.Resolver = geoDB
+	req.Header.Set(HeaderCFIPCountry, "XX")
+	assert.Equal(t, GeoLocation{CountryCode: "gb"}, resolveTestRequest(t, resolver, req, "81.2.69.142"))
+	req.Header.Set(HeaderCFIPCountry, "US")
+	assert.Equal(t, GeoLocation{CountryCode: "us"}, resolveTestRequest(t, resolver, req, "invalid"))
+	req.Header.Del(HeaderCFIPCountry)
+	_, err = resolver.Resolve(req, "invalid")
+	assert.Equal(t, ErrInvalidIP, err)
+	resolver.Resolver = GeoResolverFunc(func(r *http.Request, ip string) (GeoLocation, error) {
+		return GeoLocation{CountryCode: "gb", Region: "England", City: "London", ASN: 20712}, nil
+	})
+	req.Header.Set(HeaderCFIPCountry, "GB")
+	assert.Equal(t, GeoLocation{CountryCode: "gb", Region: "England", City: "London", ASN: 20712}, resolveTestRequest(t, resolver, req, "81.2.69.142"))
+	req.Header.Set(HeaderCFIPCountry, "DE")
+	assert.Equal(t, GeoLocation{CountryCode: "de", ASN: 20712}, resolveTestRequest(t, resolver, req, "81.2.69.142"), "the region and city must be dropped")
+}
+
+func TestGeoDBResolveNil(t *testing.T) {
+	var geoDB *GeoDB
+	location, err := geoDB.Resolve(nil, "81.2.69.142")
+	assert.NoError(t, err)
+	assert.Empty(t, location)
+}
+
+func TestTrackerGeoResolver(t *testing.T) {
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker: 1,
+		GeoDB:  &CDNGeoResolver{Header: HeaderCloudFrontViewerCountry},
+	})
+	req := overflowRequest("/")
+	req.Header.Set(HeaderCloudFrontViewerCountry, "JP")
+	tracker.Hit(req, nil)
+	tracker.Hit(req, &HitOptions{
+		GeoResolver: GeoResolverFunc(func(r *http.Request, ip string) (GeoLocation, error) {
+			return GeoLocation{CountryCode: "gb", Region: "England", City: "London"}, nil
+		}),
+	})
+	tracker.SetGeoDB(nil)
+	tracker.Hit(req, nil)
+	tracker.Stop()
+	assert.Len(t, client.Hits, 3)
+	assert.Equal(t, "jp", client.Hits[0].CountryCode)
+	assert.Equal(t, "gb", client.Hits[1].CountryCode)
+	assert.Equal(t, "England", client.Hits[1].Region)
+	assert.Equal(t, "London", client.Hits[1].City)
+	assert.Empty(t, client.Hits[2].CountryCode)
+}
+
+func resolveTestRequest(t *testing.T, resolver GeoResolver, r *http.Request, ip string) GeoLocation {
+	location, err := resolver.Resolve(r, ip)
+	assert.NoError(t, err)
+	return location
+}
@@ -2,41 +2,105 @@ package pirsch
 
 import (
 	"archive/tar"
+	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/oschwald/maxminddb-golang"
 	"io"
 	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
 const (
-	geoLite2Permalink     = "https://download.maxmind.com/app/geoip_download?edition_id=GeoLite2-Country&license_key=LICENSE_KEY&suffix=tar.gz"
-	geoLite2LicenseKey    = "LICENSE_KEY"
-	geoLite2TarGzFilename = "GeoLite2-Country.tar.gz"
+	geoLite2BaseURL = "https://download.maxmind.com/app/geoip_download"
 
 	// GeoLite2Filename is the default filename of the GeoLite2 database.
 	GeoLite2Filename = "GeoLite2-Country.mmdb"
+
+	// GeoLite2CityFilename is the default filename of the GeoLite2 city database.
+	GeoLite2CityFilename = "GeoLite2-City.mmdb"
+
+	// GeoLite2ASNFilename is the default filename of the GeoLite2 ASN database.
+	GeoLite2ASNFilename = "GeoLite2-ASN.mmdb"
 )
 
+var (
+	// ErrInvalidIP is returned by the GeoDB if an IP address cannot be parsed.
+	ErrInvalidIP = errors.New("invalid IP address")
+
+	// ErrGeoLite2Checksum is returned if the checksum of a downloaded GeoLite2 database doesn't match.
+	ErrGeoLite2Checksum = errors.New("GeoLite2 checksum mismatch")
+
+	// ErrGeoLite2NotFound is returned if a downloaded GeoLite2 tarball doesn't contain the database file.
+	ErrGeoLite2NotFound = errors.New("GeoLite2 database file not found in tarball")
+)
+
+// GeoLite2Edition is the edition ID of a GeoLite2 database.
+type GeoLite2Edition string
+
+const (
+	// GeoLite2Country is the country database.
+	GeoLite2Country = GeoLite2Edition("GeoLite2-Country")
+
+	// GeoLite2City is the city database, including the country.
+	GeoLite2City = GeoLite2Edition("GeoLite2-City")
+
+	// GeoLite2ASN is the autonomous system database.
+	GeoLite2ASN = GeoLite2Edition("GeoLite2-ASN")
+)
+
+// filename returns the name of the database file for the edition.
+func (edition GeoLite2Edition) filename() string {
+	return string(edition) + ".mmdb"
+}
+
 // GeoDBConfig is the configuration for the GeoDB.
 type GeoDBConfig struct {
-	// File is the path (including the filename) to the GeoLite2 country database file.
-	// See GeoLite2Filename for the required filename.
+	// File is the path (including the filename) to the GeoLite2 country or city database file.
+	// See GeoLite2Filename and GeoLite2CityFilename for the required filename.
+	// The region and city are only looked up when using the city database.
 	File string
 
+	// ASNFile is the optional path (including the filename) to the GeoLite2 ASN database file.
+	// See GeoLite2ASNFilename for the required filename.
+	ASNFile string
+
 	// Logger is the log.Logger used for logging.
-	// Note that this will log the IP address and should therefore only be used for debugging.
+	// The IP address is never logged.
 	// Set it to nil to disable logging for GeoDB.
 	Logger *log.Logger
 }
 
+// GeoLocation is the geo location for an IP address looked up by the GeoDB.
+type GeoLocation struct {
+	// CountryCode is the ISO country code in lowercase.
+	CountryCode string
+
+	// Region is the English name of the region (like a state or province).
+	Region string
+
+	// City is the English name of the city.
+	City string
+
+	// ASN is the autonomous system number.
+	ASN int
+
+	// ASOrganization is the organization the autonomous system is registered for.
+	ASOrganization string
+}
+
 // GeoDB maps IPs to their geo location based on MaxMinds GeoLite2 or GeoIP2 database.
 type GeoDB struct {
 	db     *maxminddb.Reader
+	asnDB  *maxminddb.Reader
 	logger *log.Logger
 }
 
@@ -44,116 +108,221 @@ type GeoDB struct {
 // The file is loaded into memory, therefore it's not necessary to close the reader (see oschwald/maxminddb-golang documentatio).
 // The database should be updated on a regular basis.
 func NewGeoDB(config GeoDBConfig) (*GeoDB, error) {
-	data, err := os.ReadFile(config.File)
+	db, err := loadGeoDB(config.File)
 
 	if err != nil {
 		return nil, err
 	}
 
-	db, err := maxminddb.FromBytes(data)
+	var asnDB *maxminddb.Reader
 
-	if err != nil {
-		return nil, err
+	if config.ASNFile != "" {
+		asnDB, err = loadGeoDB(config.ASNFile)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &GeoDB{
 		db:     db,
+		asnDB:  asnDB,
 		logger: config.Logger,
 	}, nil
 }
 
+func loadGeoDB(file string) (*maxminddb.Reader, error) {
+	data, err := os.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return maxminddb.FromBytes(data)
+}
+
 // CountryCode looks up the country code for given IP.
 // If the IP is invalid it will return an empty string.
 // The country code is returned in lowercase.
 func (db *GeoDB) CountryCode(ip string) string {
+	location, _ := db.lookup(ip)
+	return location.CountryCode
+}
+
+// Lookup looks up the geo location for given IP.
+// If the IP is invalid it will return an empty GeoLocation.
+// The region and city are only set for a city database, and the ASN and AS organization only if an ASN database has been configured.
+func (db *GeoDB) Lookup(ip string) GeoLocation {
+	location, _ := db.lookup(ip)
+	return location
+}
+
+// Resolve implements the GeoResolver interface.
+func (db *GeoDB) Resolve(r *http.Request, ip string) (GeoLocation, error) {
+	if db == nil {
+		return GeoLocation{}, nil
+	}
+
+	return db.lookup(ip)
+}
+
+func (db *GeoDB) lookup(ip string) (GeoLocation, error) {
 	parsedIP := net.ParseIP(ip)
 
 	if parsedIP == nil {
 		if db.logger != nil {
-			db.logger.Printf("error parsing IP address %s to look up country code", ip)
+			db.logger.Printf("error parsing IP address to look up geo location")
 		}
 
-		return ""
+		return GeoLocation{}, ErrInvalidIP
 	}
 
 	record := struct {
 		Country struct {
 			ISOCode string `maxminddb:"iso_code"`
 		} `maxminddb:"country"`
+		Subdivisions []struct {
+			Names struct {
+				EN string `maxminddb:"en"`
+			} `maxminddb:"names"`
+		} `maxminddb:"subdivisions"`
+		City struct {
+			Names struct {
+				EN string `maxminddb:"en"`
+			} `maxminddb:"names"`
+		} `maxminddb:"city"`
 	}{}
 
 	if err := db.db.Lookup(parsedIP, &record); err != nil {
 		if db.logger != nil {
-			db.logger.Printf("error looking up country code for IP address %s", parsedIP)
+			db.logger.Printf("error looking up geo location: %s", err)
 		}
 
-		return ""
+		return GeoLocation{}, err
+	}
+
+	location := GeoLocation{
+		CountryCode: strings.ToLower(record.Country.ISOCode),
+		City:        record.City.Names.EN,
+	}
+
+	if len(record.Subdivisions) > 0 {
+		location.Region = record.Subdivisions[0].Names.EN
 	}
 
-	return strings.ToLower(record.Country.ISOCode)
+	if db.asnDB != nil {
+		asnRecord := struct {
+			ASN            int    `maxminddb:"autonomous_system_number"`
+			ASOrganization string `maxminddb:"autonomous_system_organization"`
+		}{}
+
+		if err := db.asnDB.Lookup(parsedIP, &asnRecord); err != nil {
+			if db.logger != nil {
+				db.logger.Printf("error looking up ASN: %s", err)
+			}
+
+			return location, err
+		}
+
+		location.ASN = asnRecord.ASN
+		location.ASOrganization = asnRecord.ASOrganization
+	}
+
+	return location, nil
 }
 
 // GetGeoLite2 downloads and unpacks the MaxMind GeoLite2 database.
-// The tarball is downloaded and unpacked at the provided path. The directories will created if required.
+// The tarball is downloaded, verified using its SHA256 checksum, and unpacked at the provided path. The directories will created if required.
 // The license key is used for the download and must be provided for a registered account.
 // Please refer to MaxMinds website on how to do that: https://dev.maxmind.com/geoip/geoip2/geolite2/
-// The database should be updated on a regular basis.
+// The database should be updated on a regular basis (see GeoDBUpdater).
 func GetGeoLite2(path, licenseKey string) error {
-	if err := downloadGeoLite2(path, licenseKey); err != nil {
-		return err
-	}
+	return getGeoLite2(http.DefaultClient, geoLite2BaseURL, GeoLite2Country, path, licenseKey)
+}
 
-	if err := unpackGeoLite2(path); err != nil {
+// getGeoLite2 downloads given edition and saves the database file to the path.
+// The previous file is only replaced if the download has been verified.
+func getGeoLite2(client *http.Client, baseURL string, edition GeoLite2Edition, path, licenseKey string) error {
+	if err := os.MkdirAll(path, 0755); err != nil {
 		return err
 	}
 
-	if err := cleanupGeoLite2Download(path); err != nil {
+	tarGz, err := downloadGeoLite2(client, baseURL, edition, licenseKey, "tar.gz")
+
+	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	checksum, err := downloadGeoLite2(client, baseURL, edition, licenseKey, "tar.gz.sha256")
 
-func downloadGeoLite2(path, licenseKey string) error {
-	if err := os.MkdirAll(path, 0755); err != nil {
+	if err != nil {
 		return err
 	}
 
-	resp, err := http.Get(strings.Replace(geoLite2Permalink, geoLite2LicenseKey, licenseKey, 1))
-
-	if err != nil {
+	if err := verifyGeoLite2(tarGz, checksum); err != nil {
 		return err
 	}
 
-	tarGz, err := io.ReadAll(resp.Body)
+	data, err := unpackGeoLite2(tarGz, edition.filename())
 
 	if err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(filepath.Join(path, geoLite2TarGzFilename), tarGz, 0755); err != nil {
+	// make sure the database can be read before replacing the previous one
+	if _, err := maxminddb.FromBytes(data); err != nil {
 		return err
 	}
 
-	return nil
+	return writeGeoLite2(filepath.Join(path, edition.filename()), data)
 }
 
-func unpackGeoLite2(path string) error {
-	file, err := os.Open(filepath.Join(path, geoLite2TarGzFilename))
+func downloadGeoLite2(client *http.Client, baseURL string, edition GeoLite2Edition, licenseKey, suffix string) ([]byte, error) {
+	query := url.Values{}
+	query.Set("edition_id", string(edition))
+	query.Set("license_key", licenseKey)
+	query.Set("suffix", suffix)
+	resp, err := client.Get(baseURL + "?" + query.Encode())
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer func() {
-		if err := file.Close(); err != nil {
-			logger.Printf("error closing GeoDB file")
+		if err := resp.Body.Close(); err != nil {
+			logger.Printf("error closing GeoLite2 response body")
 		}
 	}()
-	gzipFile, err := gzip.NewReader(file)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading GeoLite2 %s: %s", suffix, resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// verifyGeoLite2 verifies the tarball using the sha256 file, which contains the hex encoded checksum and the filename.
+func verifyGeoLite2(tarGz, checksum []byte) error {
+	fields := strings.Fields(string(checksum))
+
+	if len(fields) == 0 {
+		return ErrGeoLite2Checksum
+	}
+
+	hash := sha256.Sum256(tarGz)
+
+	if !strings.EqualFold(hex.EncodeToString(hash[:]), fields[0]) {
+		return ErrGeoLite2Checksum
+	}
+
+	return nil
+}
+
+func unpackGeoLite2(tarGz []byte, filename string) ([]byte, error) {
+	gzipFile, err := gzip.NewReader(bytes.NewReader(tarGz))
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer func() {
@@ -169,37 +338,30 @@ func unpackGeoLite2(path string) error {
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			return err
+			return nil, err
 		}
 
-		if filepath.Base(header.Name) == GeoLite2Filename {
-			out, err := os.Create(filepath.Join(path, GeoLite2Filename))
-
-			if err != nil {
-				return err
-			}
-
-			if _, err := io.Copy(out, r); err != nil {
-				if err := out.Close(); err != nil {
-					logger.Printf("error closing GeoLite2 database file")
-				}
+		if filepath.Base(header.Name) == filename {
+			return io.ReadAll(r)
+		}
+	}
 
-				return err
-			}
+	return nil, ErrGeoLite2NotFound
+}
 
-			if err := out.Close(); err != nil {
-				logger.Printf("error closing GeoLite2 database file")
-			}
+// writeGeoLite2 writes the database to a temporary file first and renames it afterwards, so that the file is replaced atomically.
+func writeGeoLite2(file string, data []byte) error {
+	tmp := file + ".tmp"
 
-			break
-		}
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
 	}
 
-	return nil
-}
+	if err := os.Rename(tmp, file); err != nil {
+		if err := os.Remove(tmp); err != nil {
+			logger.Printf("error removing temporary GeoLite2 database file")
+		}
 
-func cleanupGeoLite2Download(path string) error {
-	if err := os.Remove(filepath.Join(path, geoLite2TarGzFilename)); err != nil {
 		return err
 	}
 
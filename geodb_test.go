@@ -1,9 +1,14 @@
 package pirsch
 
 import (
+	"bytes"
+	"encoding/binary"
 	"github.com/stretchr/testify/assert"
+	"log"
+	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"testing"
 )
 
@@ -18,7 +23,7 @@ func TestGetGeoLite2(t *testing.T) {
 	assert.NoError(t, GetGeoLite2("geodb", licenseKey))
 	_, err := os.Stat(filepath.Join("geodb", GeoLite2Filename))
 	assert.NoError(t, err)
-	_, err = os.Stat(filepath.Join("geodb", geoLite2TarGzFilename))
+	_, err = os.Stat(filepath.Join("geodb", GeoLite2Filename+".tmp"))
 	assert.True(t, os.IsNotExist(err))
 }
 
@@ -28,4 +33,214 @@ func TestGeoDB_CountryCode(t *testing.T) {
 	})
 	assert.NoError(t, err)
 	assert.Equal(t, "gb", db.CountryCode("81.2.69.142"))
+	location, err := db.lookup("invalid")
+	assert.Empty(t, location)
+	assert.Equal(t, ErrInvalidIP, err)
+}
+
+func TestGeoDB_CountryCodeLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	db, err := NewGeoDB(GeoDBConfig{
+		File:   filepath.Join("geodb/GeoIP2-Country-Test.mmdb"),
+		Logger: log.New(&buffer, "", 0),
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, db.CountryCode("81.2.69.142.invalid"))
+	assert.NotEmpty(t, buffer.String())
+	assert.NotContains(t, buffer.String(), "81.2.69.142", "the IP must never be logged")
+}
+
+func TestGeoDB_Lookup(t *testing.T) {
+	dir := t.TempDir()
+	cityFile := filepath.Join(dir, GeoLite2CityFilename)
+	asnFile := filepath.Join(dir, GeoLite2ASNFilename)
+	writeTestGeoDB(t, cityFile, map[string]interface{}{
+		"81.2.69.0/24": map[string]interface{}{
+			"country":      map[string]interface{}{"iso_code": "GB"},
+			"subdivisions": []interface{}{map[string]interface{}{"names": map[string]interface{}{"en": "England", "de": "England"}}},
+			"city":         map[string]interface{}{"names": map[string]interface{}{"en": "London", "de": "London"}},
+		},
+		"2001:db8::/32": map[string]interface{}{
+			"country": map[string]interface{}{"iso_code": "DE"},
+		},
+	})
+	writeTestGeoDB(t, asnFile, map[string]interface{}{
+		"81.2.69.128/25": map[string]interface{}{
+			"autonomous_system_number":       uint32(20712),
+			"autonomous_system_organization": "Andrews & Arnold Ltd",
+		},
+	})
+	db, err := NewGeoDB(GeoDBConfig{File: cityFile})
+	assert.NoError(t, err)
+	assert.Equal(t, GeoLocation{CountryCode: "gb", Region: "England", City: "London"}, db.Lookup("81.2.69.142"))
+	assert.Equal(t, "gb", db.CountryCode("81.2.69.142"))
+	assert.Equal(t, GeoLocation{CountryCode: "de"}, db.Lookup("2001:db8::1"))
+	assert.Empty(t, db.Lookup("81.2.70.1"))
+	db, err = NewGeoDB(GeoDBConfig{File: cityFile, ASNFile: asnFile})
+	assert.NoError(t, err)
+	assert.Equal(t, GeoLocation{
+		CountryCode:    "gb",
+		Region:         "England",
+		City:           "London",
+		ASN:            20712,
+		ASOrganization: "Andrews & Arnold Ltd",
+	}, db.Lookup("81.2.69.142"))
+	assert.Equal(t, GeoLocation{CountryCode: "gb", Region: "England", City: "London"}, db.Lookup("81.2.69.1"))
+	db, err = NewGeoDB(GeoDBConfig{File: filepath.Join("geodb/GeoIP2-Country-Test.mmdb")})
+	assert.NoError(t, err)
+	assert.Equal(t, GeoLocation{CountryCode: "gb"}, db.Lookup("81.2.69.142"))
+	_, err = NewGeoDB(GeoDBConfig{File: cityFile, ASNFile: filepath.Join(dir, "missing.mmdb")})
+	assert.Error(t, err)
+}
+
+// writeTestGeoDB writes a MaxMind DB file (IPv6, 24 bit records) mapping given networks to the records.
+// IPv4 networks are stored in the IPv4 mapped subtree (::/96), where the reader looks them up.
+func writeTestGeoDB(t *testing.T, file string, networks map[string]interface{}) {
+	type node struct {
+		children [2]*node
+		data     [2]int
+	}
+	root := &node{}
+	var data []byte
+	cidrs := make([]string, 0, len(networks))
+
+	for cidr := range networks {
+		cidrs = append(cidrs, cidr)
+	}
+
+	sort.Strings(cidrs)
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		assert.NoError(t, err)
+		ones, bits := network.Mask.Size()
+		ip := network.IP.To16()
+
+		if bits == 32 {
+			ip = make(net.IP, net.IPv6len)
+			copy(ip[12:], network.IP.To4())
+			ones += 96
+		}
+
+		offset := len(data)
+		data = append(data, encodeTestGeoDBValue(networks[cidr])...)
+		n := root
+
+		for i := 0; i < ones; i++ {
+			bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+
+			if i == ones-1 {
+				n.data[bit] = offset + 1
+			} else {
+				if n.children[bit] == nil {
+					n.children[bit] = &node{}
+				}
+
+				n = n.children[bit]
+			}
+		}
+	}
+
+	var nodes []*node
+	index := make(map[*node]int)
+	var walk func(*node)
+	walk = func(n *node) {
+		index[n] = len(nodes)
+		nodes = append(nodes, n)
+
+		for _, child := range n.children {
+			if child != nil {
+				walk(child)
+			}
+		}
+	}
+	walk(root)
+	var out []byte
+
+	for _, n := range nodes {
+		for i := 0; i < 2; i++ {
+			record := len(nodes)
+
+			if n.children[i] != nil {
+				record = index[n.children[i]]
+			} else if n.data[i] > 0 {
+				record = len(nodes) + 16 + n.data[i] - 1
+			}
+
+			out = append(out, byte(record>>16), byte(record>>8), byte(record))
+		}
+	}
+
+	out = append(out, make([]byte, 16)...)
+	out = append(out, data...)
+	out = append(out, "\xab\xcd\xefMaxMind.com"...)
+	out = append(out, encodeTestGeoDBValue(map[string]interface{}{
+		"node_count":                  uint32(len(nodes)),
+		"record_size":                 uint32(24),
+		"ip_version":                  uint32(6),
+		"database_type":               "Test",
+		"languages":                   []interface{}{"en"},
+		"binary_format_major_version": uint32(2),
+		"binary_format_minor_version": uint32(0),
+		"build_epoch":                 uint32(0),
+		"description":                 map[string]interface{}{"en": "Test"},
+	})...)
+	assert.NoError(t, os.WriteFile(file, out, 0644))
+}
+
+// encodeTestGeoDBValue encodes a string, uint32, map, or array for the MaxMind DB data section.
+func encodeTestGeoDBValue(value interface{}) []byte {
+	control := func(t, size int) []byte {
+		var out []byte
+
+		if t > 7 {
+			out = []byte{0, byte(t - 7)}
+		} else {
+			out = []byte{byte(t << 5)}
+		}
+
+		if size < 29 {
+			out[0] |= byte(size)
+		} else {
+			out[0] |= 29
+			out = append(out, byte(size-29))
+		}
+
+		return out
+	}
+
+	switch v := value.(type) {
+	case string:
+		return append(control(2, len(v)), v...)
+	case uint32:
+		var buf [4]byte
+		binary.BigEndian.PutUint32(buf[:], v)
+		return append(control(6, 4), buf[:]...)
+	case []interface{}:
+		out := control(11, len(v))
+
+		for _, item := range v {
+			out = append(out, encodeTestGeoDBValue(item)...)
+		}
+
+		return out
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+
+		for key := range v {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+		out := control(7, len(v))
+
+		for _, key := range keys {
+			out = append(out, encodeTestGeoDBValue(key)...)
+			out = append(out, encodeTestGeoDBValue(v[key])...)
+		}
+
+		return out
+	}
+
+	panic("unsupported type")
 }
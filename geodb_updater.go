@@ -0,0 +1,247 @@
+package pirsch
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	defaultGeoDBUpdateInterval = time.Hour * 24
+	defaultGeoDBUpdateTimeout  = time.Minute * 5
+)
+
+// ErrGeoDBUpdaterConfig is returned by NewGeoDBUpdater if the path or license key is missing.
+var ErrGeoDBUpdaterConfig = errors.New("the path and license key must be set")
+
+// GeoDBUpdaterConfig is the configuration for the GeoDBUpdater.
+type GeoDBUpdaterConfig struct {
+	// Path is the directory the databases are downloaded to (required).
+	Path string
+
+	// LicenseKey is the MaxMind license key used for the download (required).
+	LicenseKey string
+
+	// Edition is the GeoLite2 database used to look up the geo location.
+	// If you leave it empty, GeoLite2Country is used. Set it to GeoLite2City to look up regions and cities.
+	Edition GeoLite2Edition
+
+	// ASN enables downloading the GeoLite2ASN database to look up the autonomous system.
+	ASN bool
+
+	// BaseURL is the URL the databases are downloaded from.
+	// The edition ID, license key, and suffix are added as query parameters.
+	// If you leave it empty, MaxMinds download URL is used.
+	BaseURL string
+
+	// Interval is the time between two updates.
+	// If you leave it 0, the databases are updated once a day.
+	Interval time.Duration
+
+	// Timeout is the timeout for downloading a database.
+	// If you leave it 0, the default of five minutes is used.
+	Timeout time.Duration
+
+	// Tracker is updated with the new GeoDB (see Tracker.SetGeoDB), if set.
+	Tracker *Tracker
+
+	// OnUpdate is called with the new GeoDB after it has been updated, if set.
+	OnUpdate func(*GeoDB)
+
+	// Logger is the log.Logger used for logging.
+	// The default log will be used printing to os.Stdout with "pirsch" in its prefix in case it is not set.
+	Logger *log.Logger
+}
+
+func (config *GeoDBUpdaterConfig) validate() error {
+	if config.Path == "" || config.LicenseKey == "" {
+		return ErrGeoDBUpdaterConfig
+	}
+
+	if config.Edition == "" {
+		config.Edition = GeoLite2Country
+	}
+
+	if config.BaseURL == "" {
+		config.BaseURL = geoLite2BaseURL
+	}
+
+	if config.Interval <= 0 {
+		config.Interval = defaultGeoDBUpdateInterval
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultGeoDBUpdateTimeout
+	}
+
+	if config.Logger == nil {
+		config.Logger = logger
+	}
+
+	return nil
+}
+
+// GeoDBUpdater downloads the GeoLite2 databases on a schedule and swaps the GeoDB in the Tracker.
+// Each download is verified using its SHA256 checksum. If the download or verification fails, the previous database file is kept.
+type GeoDBUpdater struct {
+	config GeoDBUpdaterConfig
+	client *http.Client
+	geoDB  *GeoDB
+	m      sync.RWMutex
+	cancel context.CancelFunc
+	done   chan bool
+}
+
+// NewGeoDBUpdater creates a new GeoDBUpdater for given configuration.
+// The databases already downloaded to the path are loaded right away, so that the GeoDB is available before the first update.
+func NewGeoDBUpdater(config GeoDBUpdaterConfig) (*GeoDBUpdater, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	updater := &GeoDBUpdater{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+
+	if updater.isDownloaded() {
+		if err := updater.load(); err != nil {
+			updater.config.Logger.Printf("error loading GeoDB, it will be downloaded again: %s", err)
+		}
+	}
+
+	return updater, nil
+}
+
+// Start updates the databases in the background.
+// The first update is run right away if the databases have not been downloaded yet, or are older than the interval.
+func (updater *GeoDBUpdater) Start() {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	updater.cancel = cancelFunc
+	updater.done = make(chan bool)
+
+	go func() {
+		timer := time.NewTimer(updater.nextUpdate())
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-timer.C:
+				if err := updater.Update(); err != nil {
+					updater.config.Logger.Printf("error updating GeoDB: %s", err)
+				}
+
+				timer.Reset(updater.config.Interval)
+			case <-ctx.Done():
+				updater.done <- true
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops updating the databases in the background.
+func (updater *GeoDBUpdater) Stop() {
+	if updater.cancel != nil {
+		updater.cancel()
+		<-updater.done
+		updater.cancel = nil
+	}
+}
+
+// Update downloads the databases and swaps the GeoDB.
+// The previous database files and GeoDB are kept in case of an error.
+func (updater *GeoDBUpdater) Update() error {
+	for _, edition := range updater.editions() {
+		if err := getGeoLite2(updater.client, updater.config.BaseURL, edition, updater.config.Path, updater.config.LicenseKey); err != nil {
+			return err
+		}
+	}
+
+	return updater.load()
+}
+
+// GeoDB returns the current GeoDB, or nil if no database has been loaded yet.
+func (updater *GeoDBUpdater) GeoDB() *GeoDB {
+	updater.m.RLock()
+	defer updater.m.RUnlock()
+	return updater.geoDB
+}
+
+func (updater *GeoDBUpdater) load() error {
+	config := GeoDBConfig{File: updater.file(updater.config.Edition)}
+
+	if updater.config.ASN {
+		config.ASNFile = updater.file(GeoLite2ASN)
+	}
+
+	geoDB, err := NewGeoDB(config)
+
+	if err != nil {
+		return err
+	}
+
+	updater.m.Lock()
+	updater.geoDB = geoDB
+	updater.m.Unlock()
+
+	if updater.config.Tracker != nil {
+		updater.config.Tracker.SetGeoDB(geoDB)
+	}
+
+	if updater.config.OnUpdate != nil {
+		updater.config.OnUpdate(geoDB)
+	}
+
+	return nil
+}
+
+// nextUpdate returns the time until the oldest database must be updated.
+func (updater *GeoDBUpdater) nextUpdate() time.Duration {
+	next := updater.config.Interval
+
+	for _, edition := range updater.editions() {
+		info, err := os.Stat(updater.file(edition))
+
+		if err != nil {
+			return 0
+		}
+
+		if d := updater.config.Interval - time.Since(info.ModTime()); d < next {
+			next = d
+		}
+	}
+
+	if next < 0 {
+		return 0
+	}
+
+	return next
+}
+
+func (updater *GeoDBUpdater) isDownloaded() bool {
+	for _, edition := range updater.editions() {
+		if _, err := os.Stat(updater.file(edition)); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (updater *GeoDBUpdater) editions() []GeoLite2Edition {
+	if updater.config.ASN {
+		return []GeoLite2Edition{updater.config.Edition, GeoLite2ASN}
+	}
+
+	return []GeoLite2Edition{updater.config.Edition}
+}
+
+func (updater *GeoDBUpdater) file(edition GeoLite2Edition) string {
+	return filepath.Join(updater.config.Path, edition.filename())
+}
@@ -0,0 +1,254 @@
+package pirsch
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGeoDBUpdater(t *testing.T) {
+	server := newTestGeoLite2Server(t)
+	server.set(t, GeoLite2City, "gb")
+	server.set(t, GeoLite2ASN, "")
+	dir := t.TempDir()
+	tracker := NewTracker(NewMockClient(), "salt", nil)
+	defer tracker.Stop()
+	var updated *GeoDB
+	updater, err := NewGeoDBUpdater(GeoDBUpdaterConfig{
+		Path:       dir,
+		LicenseKey: "key",
+		Edition:    GeoLite2City,
+		ASN:        true,
+		BaseURL:    server.URL,
+		Tracker:    tracker,
+		OnUpdate: func(geoDB *GeoDB) {
+			updated = geoDB
+		},
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, updater.GeoDB())
+	assert.NoError(t, updater.Update())
+	assert.NotNil(t, updater.GeoDB())
+	assert.Equal(t, updater.GeoDB(), updated)
+	assert.Equal(t, updater.GeoDB(), tracker.geoDB)
+	assert.Equal(t, "gb", updater.GeoDB().CountryCode("81.2.69.142"))
+	assert.Equal(t, 20712, updater.GeoDB().Lookup("81.2.69.142").ASN)
+	server.update(func() {
+		assert.Equal(t, "key", server.licenseKey)
+	})
+	_, err = os.Stat(filepath.Join(dir, GeoLite2CityFilename))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, GeoLite2ASNFilename))
+	assert.NoError(t, err)
+	server.set(t, GeoLite2City, "de")
+	assert.NoError(t, updater.Update())
+	assert.Equal(t, "de", tracker.geoDB.(*GeoDB).CountryCode("81.2.69.142"))
+
+	// the database is loaded from disk
+	updater, err = NewGeoDBUpdater(GeoDBUpdaterConfig{
+		Path:       dir,
+		LicenseKey: "key",
+		Edition:    GeoLite2City,
+		ASN:        true,
+		BaseURL:    server.URL,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "de", updater.GeoDB().CountryCode("81.2.69.142"))
+	assert.True(t, updater.nextUpdate() > time.Hour*23)
+}
+
+func TestGeoDBUpdaterFailure(t *testing.T) {
+	server := newTestGeoLite2Server(t)
+	server.set(t, GeoLite2Country, "gb")
+	dir := t.TempDir()
+	updater, err := NewGeoDBUpdater(GeoDBUpdaterConfig{
+		Path:       dir,
+		LicenseKey: "key",
+		BaseURL:    server.URL,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, updater.Update())
+	geoDB := updater.GeoDB()
+	file := filepath.Join(dir, GeoLite2Filename)
+	data, err := os.ReadFile(file)
+	assert.NoError(t, err)
+
+	server.set(t, GeoLite2Country, "de")
+	server.update(func() { server.checksum = "invalid" })
+	assert.Equal(t, ErrGeoLite2Checksum, updater.Update())
+	server.update(func() {
+		server.checksum = ""
+		server.status = http.StatusUnauthorized
+	})
+	assert.Error(t, updater.Update())
+	server.update(func() {
+		server.status = 0
+		server.tarGz[GeoLite2Country] = testGeoLite2TarGz(t, "other.mmdb", []byte("data"))
+	})
+	assert.Equal(t, ErrGeoLite2NotFound, updater.Update())
+	server.update(func() {
+		server.tarGz[GeoLite2Country] = testGeoLite2TarGz(t, GeoLite2Filename, []byte("invalid database"))
+	})
+	assert.Error(t, updater.Update())
+
+	current, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, data, current, "the previous file must be kept")
+	assert.Equal(t, geoDB, updater.GeoDB(), "the previous GeoDB must be kept")
+	assert.Equal(t, "gb", updater.GeoDB().CountryCode("81.2.69.142"))
+	_, err = os.Stat(file + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGeoDBUpdaterStart(t *testing.T) {
+	server := newTestGeoLite2Server(t)
+	server.set(t, GeoLite2Country, "gb")
+	tracker := NewTracker(NewMockClient(), "salt", nil)
+	defer tracker.Stop()
+	updater, err := NewGeoDBUpdater(GeoDBUpdaterConfig{
+		Path:       t.TempDir(),
+		LicenseKey: "key",
+		BaseURL:    server.URL,
+		Tracker:    tracker,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), updater.nextUpdate())
+	updater.Start()
+
+	for i := 0; i < 100 && updater.GeoDB() == nil; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	updater.Stop()
+	assert.NotNil(t, updater.GeoDB())
+	tracker.geoDBMutex.RLock()
+	assert.Equal(t, updater.GeoDB(), tracker.geoDB)
+	tracker.geoDBMutex.RUnlock()
+}
+
+func TestGeoDBUpdaterConfig(t *testing.T) {
+	_, err := NewGeoDBUpdater(GeoDBUpdaterConfig{Path: t.TempDir()})
+	assert.Equal(t, ErrGeoDBUpdaterConfig, err)
+	_, err = NewGeoDBUpdater(GeoDBUpdaterConfig{LicenseKey: "key"})
+	assert.Equal(t, ErrGeoDBUpdaterConfig, err)
+	updater, err := NewGeoDBUpdater(GeoDBUpdaterConfig{Path: t.TempDir(), LicenseKey: "key"})
+	assert.NoError(t, err)
+	assert.Equal(t, GeoLite2Country, updater.config.Edition)
+	assert.Equal(t, geoLite2BaseURL, updater.config.BaseURL)
+	assert.Equal(t, defaultGeoDBUpdateInterval, updater.config.Interval)
+	assert.Equal(t, defaultGeoDBUpdateTimeout, updater.config.Timeout)
+	assert.NotNil(t, updater.config.Logger)
+}
+
+// testGeoLite2Server serves GeoLite2 tarballs and their checksums like MaxMinds download URL.
+type testGeoLite2Server struct {
+	*httptest.Server
+	tarGz      map[GeoLite2Edition][]byte
+	checksum   string
+	status     int
+	licenseKey string
+	m          sync.Mutex
+}
+
+func newTestGeoLite2Server(t *testing.T) *testGeoLite2Server {
+	server := &testGeoLite2Server{
+		tarGz: make(map[GeoLite2Edition][]byte),
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.m.Lock()
+		defer server.m.Unlock()
+		server.licenseKey = r.URL.Query().Get("license_key")
+
+		if server.status != 0 {
+			w.WriteHeader(server.status)
+			return
+		}
+
+		tarGz, found := server.tarGz[GeoLite2Edition(r.URL.Query().Get("edition_id"))]
+
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch r.URL.Query().Get("suffix") {
+		case "tar.gz":
+			w.Write(tarGz)
+		case "tar.gz.sha256":
+			checksum := server.checksum
+
+			if checksum == "" {
+				hash := sha256.Sum256(tarGz)
+				checksum = hex.EncodeToString(hash[:])
+			}
+
+			w.Write([]byte(checksum + "  GeoLite2_20210914.tar.gz\n"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// set serves a database for given edition, mapping 81.2.69.0/24 to the country code, or to an autonomous system for the ASN edition.
+func (server *testGeoLite2Server) set(t *testing.T, edition GeoLite2Edition, countryCode string) {
+	file := filepath.Join(t.TempDir(), edition.filename())
+	record := map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": countryCode},
+	}
+
+	if edition == GeoLite2ASN {
+		record = map[string]interface{}{
+			"autonomous_system_number":       uint32(20712),
+			"autonomous_system_organization": "Andrews & Arnold Ltd",
+		}
+	}
+
+	writeTestGeoDB(t, file, map[string]interface{}{"81.2.69.0/24": record})
+	data, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	server.update(func() {
+		server.tarGz[edition] = testGeoLite2TarGz(t, edition.filename(), data)
+	})
+}
+
+// update calls f to change the server while holding the lock.
+func (server *testGeoLite2Server) update(f func()) {
+	server.m.Lock()
+	defer server.m.Unlock()
+	f()
+}
+
+func testGeoLite2Tar(t *testing.T, filename string, data []byte) []byte {
+	var buffer bytes.Buffer
+	w := tar.NewWriter(&buffer)
+	assert.NoError(t, w.WriteHeader(&tar.Header{
+		Name: "GeoLite2_20210914/" + filename,
+		Mode: 0644,
+		Size: int64(len(data)),
+	}))
+	_, err := w.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buffer.Bytes()
+}
+
+func testGeoLite2TarGz(t *testing.T, filename string, data []byte) []byte {
+	var buffer bytes.Buffer
+	w := gzip.NewWriter(&buffer)
+	_, err := w.Write(testGeoLite2Tar(t, filename, data))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buffer.Bytes()
+}
@@ -0,0 +1,76 @@
+package pirsch
+
+import (
+	"net/http"
+)
+
+// HandlerConfig is the optional configuration for the Handler.
+type HandlerConfig struct {
+	// ValidClientID is called with the client ID of each request.
+	// Return false to reject the request with 403 Forbidden.
+	// All client IDs are accepted if it is not set.
+	ValidClientID func(int64) bool
+}
+
+// Handler provides HTTP handlers for the endpoints called by pirsch.js and pirsch-events.js.
+// The hits and events are passed on to the Tracker.
+type Handler struct {
+	tracker       *Tracker
+	validClientID func(int64) bool
+}
+
+// NewHandler creates a new Handler for given Tracker and config.
+// Pass nil for the config to use the defaults.
+func NewHandler(tracker *Tracker, config *HandlerConfig) *Handler {
+	if config == nil {
+		config = &HandlerConfig{}
+	}
+
+	return &Handler{
+		tracker:       tracker,
+		validClientID: config.ValidClientID,
+	}
+}
+
+// Hit handles the GET requests send by pirsch.js (/pirsch by default).
+func (handler *Handler) Hit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	options := HitOptionsFromRequest(r)
+
+	if !handler.isValidClientID(options.ClientID) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	handler.tracker.Hit(r, handler.tracker.hitOptions(options))
+}
+
+// Event handles the POST requests send by pirsch-events.js (/pirsch-event by default).
+func (handler *Handler) Event(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	eventOptions, options, err := EventOptionsFromRequest(r)
+
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !handler.isValidClientID(options.ClientID) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	handler.tracker.Event(r, eventOptions, handler.tracker.hitOptions(options))
+}
+
+func (handler *Handler) isValidClientID(clientID int64) bool {
+	return handler.validClientID == nil || handler.validClientID(clientID)
+}
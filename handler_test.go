@@ -0,0 +1,76 @@
+package pirsch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler_Hit(t *testing.T) {
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		ReferrerDomainBlacklist: []string{"pirsch.io"},
+	})
+	handler := NewHandler(tracker, &HandlerConfig{
+		ValidClientID: func(clientID int64) bool {
+			return clientID == 42
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/pirsch?client_id=42&url=http%3A%2F%2Fpirsch.io%2Fpage&t=Title&ref=http%3A%2F%2Fpirsch.io%2F&w=1920&h=1080", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	w := httptest.NewRecorder()
+	handler.Hit(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	req = httptest.NewRequest(http.MethodGet, "/pirsch?client_id=43&url=http%3A%2F%2Fpirsch.io%2Fpage", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	w = httptest.NewRecorder()
+	handler.Hit(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	req = httptest.NewRequest(http.MethodPost, "/pirsch?client_id=42", nil)
+	w = httptest.NewRecorder()
+	handler.Hit(w, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	tracker.Stop()
+	assert.Len(t, client.Hits, 1)
+	assert.Equal(t, int64(42), client.Hits[0].ClientID)
+	assert.Equal(t, "/page", client.Hits[0].Path)
+	assert.Equal(t, "Title", client.Hits[0].Title)
+	assert.Empty(t, client.Hits[0].Referrer)
+	assert.Equal(t, 1920, client.Hits[0].ScreenWidth)
+}
+
+func TestHandler_Event(t *testing.T) {
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", nil)
+	handler := NewHandler(tracker, nil)
+	req := httptest.NewRequest(http.MethodPost, "/pirsch-event", strings.NewReader(`{
+		"client_id": "42",
+		"url": "http://foo.bar/page",
+		"title": "Title",
+		"event_name": "event",
+		"event_duration": 21,
+		"event_meta": {"key": "value"}
+	}`))
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	w := httptest.NewRecorder()
+	handler.Event(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	req = httptest.NewRequest(http.MethodPost, "/pirsch-event", strings.NewReader(`{"event_name": ""}`))
+	w = httptest.NewRecorder()
+	handler.Event(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	req = httptest.NewRequest(http.MethodGet, "/pirsch-event", nil)
+	w = httptest.NewRecorder()
+	handler.Event(w, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	tracker.Stop()
+	assert.Len(t, client.Events, 1)
+	assert.Equal(t, int64(42), client.Events[0].ClientID)
+	assert.Equal(t, "/page", client.Events[0].Path)
+	assert.Equal(t, "event", client.Events[0].Name)
+	assert.Equal(t, 21, client.Events[0].DurationSeconds)
+	assert.Equal(t, []string{"key"}, client.Events[0].MetaKeys)
+	assert.Equal(t, []string{"value"}, client.Events[0].MetaValues)
+}
@@ -25,8 +25,8 @@ type HitOptions struct {
 	// Client is the database client required to look up sessions.
 	//Client Store
 
-	// SessionCache is the cache to look up sessions.
-	SessionCache *SessionCache
+	// SessionCache is the SessionStore to look up sessions.
+	SessionCache SessionStore
 
 	// ClientID is optionally saved with a hit to split the data between multiple clients.
 	ClientID int64
@@ -67,7 +67,24 @@ type HitOptions struct {
 	// ScreenHeight sets the screen height to be stored with the hit.
 	ScreenHeight int
 
-	geoDB *GeoDB
+	// Fingerprinter generates the fingerprint for the visitor.
+	// If you leave it nil, Fingerprint is used.
+	Fingerprinter Fingerprinter
+
+	// IPExtractor looks up the client IP from headers set by trusted proxies.
+	// If you leave it nil, the IP is read from a list of headers in a fixed order, which can be spoofed by the client.
+	IPExtractor *IPExtractor
+
+	// AnonymizeIP truncates the IP (see AnonymizeIP) before it is used to generate the fingerprint and look up the geo location.
+	AnonymizeIP bool
+
+	// GeoResolver looks up the geo location for the IP.
+	// The Tracker sets it to the TrackerConfig.GeoDB, unless it has been set.
+	GeoResolver GeoResolver
+
+	metrics      MetricsCollector
+	previousSalt string
+	saltRotated  time.Time
 }
 
 // HitFromRequest returns a new Hit for given request, salt and HitOptions.
@@ -87,28 +104,40 @@ func HitFromRequest(r *http.Request, salt string, options *HitOptions) Hit {
 
 	// shorten strings if required and parse User-Agent to extract more data (OS, Browser)
 	getRequestURI(r, options)
-	fingerprint := Fingerprint(r, salt)
+	ip := options.getIP(r)
+	fingerprint := options.fingerprint(r, ip, salt)
 	userAgent := r.UserAgent()
 	path := shortenString(options.Path, 2000)
 	requestURL := shortenString(options.URL, 2000)
 	title := shortenString(options.Title, 512)
-	uaInfo := ParseUserAgent(userAgent)
+	hints := ParseClientHints(r)
+	uaInfo := ParseUserAgentWithClientHints(userAgent, hints)
+	deviceType := GetDeviceType(userAgent, uaInfo, hints)
 	uaInfo.OS = shortenString(uaInfo.OS, 20)
 	uaInfo.OSVersion = shortenString(uaInfo.OSVersion, 20)
 	uaInfo.Browser = shortenString(uaInfo.Browser, 20)
 	uaInfo.BrowserVersion = shortenString(uaInfo.BrowserVersion, 20)
 	userAgent = shortenString(userAgent, 200)
-	lang := shortenString(getLanguage(r), 10)
+	lang, langRegion := getLanguage(r)
 	referrer, referrerName, referrerIcon := getReferrer(r, options.Referrer, options.ReferrerDomainBlacklist, options.ReferrerDomainBlacklistIncludesSubdomains)
 	referrer = shortenString(referrer, 200)
 	referrerName = shortenString(referrerName, 200)
 	referrerIcon = shortenString(referrerIcon, 2000)
 	screen := GetScreenClass(options.ScreenWidth)
 	utm := getUTMParams(r)
-	countryCode := ""
+	var location GeoLocation
+
+	if options.GeoResolver != nil {
+		var err error
+		location, err = options.GeoResolver.Resolve(r, ip)
 
-	if options.geoDB != nil {
-		countryCode = options.geoDB.CountryCode(getIP(r))
+		if err != nil && options.metrics != nil {
+			options.metrics.GeoDBLookupFailed()
+		}
+
+		location.Region = shortenString(location.Region, 200)
+		location.City = shortenString(location.City, 200)
+		location.ASOrganization = shortenString(location.ASOrganization, 200)
 	}
 
 	lastHitSeconds := 0
@@ -116,7 +145,12 @@ func HitFromRequest(r *http.Request, salt string, options *HitOptions) Hit {
 
 	if options.SessionCache != nil {
 		// hits and sessions use UTC
-		s := options.SessionCache.get(options.ClientID, fingerprint, time.Now().UTC().Add(-options.SessionMaxAge))
+		s := options.SessionCache.Get(options.ClientID, fingerprint, time.Now().UTC().Add(-options.SessionMaxAge))
+
+		// continue sessions started before the salt has been rotated
+		if s.Session.IsZero() && options.previousSalt != "" && now.Sub(options.saltRotated) < options.SessionMaxAge {
+			s = options.SessionCache.Get(options.ClientID, options.fingerprint(r, ip, options.previousSalt), time.Now().UTC().Add(-options.SessionMaxAge))
+		}
 
 		if !s.Time.IsZero() && s.Path != path {
 			lastHitSeconds = int(now.Sub(s.Time).Seconds())
@@ -126,7 +160,11 @@ func HitFromRequest(r *http.Request, salt string, options *HitOptions) Hit {
 			session = s.Session
 		}
 
-		options.SessionCache.put(options.ClientID, fingerprint, path, now, session)
+		options.SessionCache.Put(options.ClientID, fingerprint, Session{
+			Path:    path,
+			Time:    now,
+			Session: session,
+		}, options.SessionMaxAge)
 	}
 
 	if options.ScreenWidth <= 0 || options.ScreenHeight <= 0 {
@@ -149,7 +187,12 @@ func HitFromRequest(r *http.Request, salt string, options *HitOptions) Hit {
 		URL:                       requestURL,
 		Title:                     title,
 		Language:                  lang,
-		CountryCode:               countryCode,
+		LanguageRegion:            langRegion,
+		CountryCode:               location.CountryCode,
+		Region:                    location.Region,
+		City:                      location.City,
+		ASN:                       location.ASN,
+		ASOrganization:            location.ASOrganization,
 		Referrer:                  referrer,
 		ReferrerName:              referrerName,
 		ReferrerIcon:              referrerIcon,
@@ -159,6 +202,7 @@ func HitFromRequest(r *http.Request, salt string, options *HitOptions) Hit {
 		BrowserVersion:            uaInfo.BrowserVersion,
 		Desktop:                   uaInfo.IsDesktop(),
 		Mobile:                    uaInfo.IsMobile(),
+		DeviceType:                deviceType,
 		ScreenWidth:               options.ScreenWidth,
 		ScreenHeight:              options.ScreenHeight,
 		ScreenClass:               screen,
@@ -170,52 +214,118 @@ func HitFromRequest(r *http.Request, salt string, options *HitOptions) Hit {
 	}
 }
 
+func (options *HitOptions) getIP(r *http.Request) string {
+	var ip string
+
+	if options.IPExtractor != nil {
+		ip = options.IPExtractor.ExtractIP(r)
+	} else {
+		ip = getIP(r)
+	}
+
+	if options.AnonymizeIP {
+		return AnonymizeIP(ip)
+	}
+
+	return ip
+}
+
+func (options *HitOptions) fingerprint(r *http.Request, ip, salt string) string {
+	if options.Fingerprinter != nil {
+		return options.Fingerprinter.Fingerprint(r, ip, options.ClientID, salt)
+	}
+
+	return fingerprint(r.Header.Get("User-Agent"), ip, salt)
+}
+
 // IgnoreHit returns true, if a hit should be ignored for given request, or false otherwise.
 // The easiest way to track visitors is to use the Tracker.
 func IgnoreHit(r *http.Request) bool {
+	return ClassifyRequest(r).Reason != ""
+}
+
+// RequestClassification is the result of ClassifyRequest.
+type RequestClassification struct {
+	// Reason is the reason the request should be ignored, or an empty string if it should be tracked.
+	Reason IgnoreReason
+
+	// Rule is the rule that matched the request, depending on the reason.
+	// This is the header for IgnoreDNT and IgnorePrefetch, the blacklisted domain for IgnoreReferrerSpam,
+	// the browser and version for IgnoreOldBrowser, and the name of well-known bots (like "Googlebot") or else the keyword
+	// on the User-Agent blacklist for IgnoreBot.
+	// It's empty for IgnoreEmptyUserAgent.
+	Rule string
+}
+
+// IsBot returns true if the request has been classified as a bot (IgnoreBot or IgnoreEmptyUserAgent).
+func (classification RequestClassification) IsBot() bool {
+	return classification.Reason == IgnoreBot || classification.Reason == IgnoreEmptyUserAgent
+}
+
+// ClassifyRequest returns why a hit should be ignored for given request and which rule matched.
+// The Reason is empty if the request should be tracked.
+func ClassifyRequest(r *http.Request) RequestClassification {
 	// respect do not track header
 	if r.Header.Get("DNT") == "1" {
-		return true
+		return RequestClassification{IgnoreDNT, "DNT: 1"}
 	}
 
 	// empty User-Agents are usually bots
 	userAgent := strings.TrimSpace(strings.ToLower(r.Header.Get("User-Agent")))
 
 	if userAgent == "" {
-		return true
+		return RequestClassification{Reason: IgnoreEmptyUserAgent}
 	}
 
 	// ignore browsers pre-fetching data
-	xPurpose := r.Header.Get("X-Purpose")
-	purpose := r.Header.Get("Purpose")
-
-	if r.Header.Get("X-Moz") == "prefetch" ||
-		xPurpose == "prefetch" ||
-		xPurpose == "preview" ||
-		purpose == "prefetch" ||
-		purpose == "preview" {
-		return true
+	if r.Header.Get("X-Moz") == "prefetch" {
+		return RequestClassification{IgnorePrefetch, "X-Moz: prefetch"}
+	}
+
+	for _, header := range []string{"X-Purpose", "Purpose"} {
+		if purpose := r.Header.Get(header); purpose == "prefetch" || purpose == "preview" {
+			return RequestClassification{IgnorePrefetch, header + ": " + purpose}
+		}
 	}
 
 	// filter referrer spammers
-	if ignoreReferrer(r) {
-		return true
+	if referrer := matchReferrerSpam(r); referrer != "" {
+		return RequestClassification{IgnoreReferrerSpam, referrer}
 	}
 
-	userAgentResult := ParseUserAgent(r.UserAgent())
+	userAgentResult := ParseUserAgentWithClientHints(r.UserAgent(), ParseClientHints(r))
 
 	if ignoreBrowserVersion(userAgentResult.Browser, userAgentResult.BrowserVersion) {
-		return true
+		return RequestClassification{IgnoreOldBrowser, userAgentResult.Browser + " " + userAgentResult.BrowserVersion}
 	}
 
 	// filter for bot keywords (most expensive operation last)
-	for _, botUserAgent := range userAgentBlacklist {
-		if strings.Contains(userAgent, botUserAgent) {
-			return true
+	if keyword := matchBotUserAgent(userAgent); keyword != "" {
+		if name := getBotName(userAgent); name != "" {
+			return RequestClassification{IgnoreBot, name}
+		}
+
+		return RequestClassification{IgnoreBot, keyword}
+	}
+
+	return RequestClassification{}
+}
+
+// matchBotUserAgent returns the first keyword on the User-Agent blacklist given lowercase User-Agent contains,
+// or an empty string if no keyword matches (see SetUserAgentBlacklist).
+func matchBotUserAgent(userAgent string) string {
+	return getUserAgentBlacklistMatcher().match(userAgent)
+}
+
+// getBotName returns the name of a well-known bot for given lowercase User-Agent, or an empty string otherwise.
+func getBotName(userAgent string) string {
+	for _, bot := range knownBots {
+		if strings.Contains(userAgent, bot.keyword) {
+			return bot.name
 		}
 	}
 
-	return false
+	return ""
 }
 
 // HitOptionsFromRequest returns the HitOptions for given client request.
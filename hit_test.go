@@ -33,6 +33,7 @@ func TestHitFromRequest(t *testing.T) {
 		hit.URL != "/test/path?query=param&foo=bar&utm_source=test+source&utm_medium=email&utm_campaign=newsletter&utm_content=signup&utm_term=keywords" ||
 		hit.Title != "title" ||
 		hit.Language != "de" ||
+		hit.LanguageRegion != "DE" ||
 		hit.Referrer != "http://ref/" ||
 		hit.OS != OSWindows ||
 		hit.OSVersion != "10" ||
@@ -65,14 +66,15 @@ func TestHitFromRequestSession(t *testing.T) {
 	assert.NotEmpty(t, hit1.Fingerprint)
 
 	// to count as page switch for time on page
-	assert.False(t, sessionCache.sessions[sessionCache.getKey(hit1.ClientID, hit1.Fingerprint)].Time.IsZero())
-	assert.False(t, sessionCache.sessions[sessionCache.getKey(hit1.ClientID, hit1.Fingerprint)].Session.IsZero())
-	assert.Equal(t, "/test/path", sessionCache.sessions[sessionCache.getKey(hit1.ClientID, hit1.Fingerprint)].Path)
-	sessionCache.sessions[sessionCache.getKey(hit1.ClientID, hit1.Fingerprint)] = Session{
+	session := sessionCache.Get(hit1.ClientID, hit1.Fingerprint, time.Time{})
+	assert.False(t, session.Time.IsZero())
+	assert.False(t, session.Session.IsZero())
+	assert.Equal(t, "/test/path", session.Path)
+	sessionCache.Put(hit1.ClientID, hit1.Fingerprint, Session{
 		Path:    "/different/path",
 		Time:    time.Now().UTC().Add(-time.Second * 5),
-		Session: sessionCache.sessions[sessionCache.getKey(hit1.ClientID, hit1.Fingerprint)].Session,
-	}
+		Session: session.Session,
+	}, defaultSessionMaxAge)
 
 	hit2 := HitFromRequest(req, "salt", &HitOptions{
 		SessionCache: sessionCache,
@@ -152,7 +154,7 @@ func TestHitFromRequestCountryCode(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "http://foo.bar/test/path?query=param&foo=bar#anchor", nil)
 	req.RemoteAddr = "81.2.69.142"
 	hit := HitFromRequest(req, "salt", &HitOptions{
-		geoDB: geoDB,
+		GeoResolver: geoDB,
 	})
 
 	if hit.CountryCode != "gb" {
@@ -162,7 +164,7 @@ func TestHitFromRequestCountryCode(t *testing.T) {
 	req = httptest.NewRequest(http.MethodGet, "http://foo.bar/test/path?query=param&foo=bar#anchor", nil)
 	req.RemoteAddr = "127.0.0.1"
 	hit = HitFromRequest(req, "salt", &HitOptions{
-		geoDB: geoDB,
+		GeoResolver: geoDB,
 	})
 
 	if hit.CountryCode != "" {
@@ -170,6 +172,36 @@ func TestHitFromRequestCountryCode(t *testing.T) {
 	}
 }
 
+func TestHitFromRequestClientHints(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36 Edg/118.0.2088.76")
+	req.Header.Set(HeaderSecCHUA, `"Chromium";v="118", "Microsoft Edge";v="118", "Not=A?Brand";v="99"`)
+	req.Header.Set(HeaderSecCHUAPlatform, `"Windows"`)
+	req.Header.Set(HeaderSecCHUAPlatformVersion, `"15.0.0"`)
+	hit := HitFromRequest(req, "salt", nil)
+	assert.Equal(t, BrowserEdge, hit.Browser)
+	assert.Equal(t, "118.0", hit.BrowserVersion)
+	assert.Equal(t, OSWindows, hit.OS)
+	assert.Equal(t, "11", hit.OSVersion)
+	assert.True(t, hit.Desktop)
+	assert.False(t, hit.Mobile)
+	assert.Equal(t, DeviceTypeDesktop, hit.DeviceType)
+}
+
+func TestHitFromRequestClientHintsModel(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Mobile Safari/537.36")
+	req.Header.Set(HeaderSecCHUA, `"Chromium";v="118", "Google Chrome";v="118", "Not=A?Brand";v="99"`)
+	req.Header.Set(HeaderSecCHUAPlatform, `"Android"`)
+	req.Header.Set(HeaderSecCHUAMobile, "?1")
+	hit := HitFromRequest(req, "salt", nil)
+	assert.Equal(t, DeviceTypeMobile, hit.DeviceType)
+	req.Header.Set(HeaderSecCHUAModel, `"Pixel Tablet"`)
+	hit = HitFromRequest(req, "salt", nil)
+	assert.Equal(t, OSAndroid, hit.OS)
+	assert.Equal(t, DeviceTypeTablet, hit.DeviceType)
+}
+
 func TestIgnoreHitPrefetch(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
@@ -315,6 +347,55 @@ func TestIgnoreHitDoNotTrack(t *testing.T) {
 	}
 }
 
+func TestClassifyRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/84.0.4147.135 Safari/537.36")
+	assert.Equal(t, RequestClassification{}, ClassifyRequest(req))
+	req.Header.Set("DNT", "1")
+	assert.Equal(t, RequestClassification{IgnoreDNT, "DNT: 1"}, ClassifyRequest(req))
+	req.Header.Del("DNT")
+	req.Header.Set("Purpose", "prefetch")
+	assert.Equal(t, RequestClassification{IgnorePrefetch, "Purpose: prefetch"}, ClassifyRequest(req))
+	req.Header.Del("Purpose")
+	req.Header.Set("X-Purpose", "preview")
+	assert.Equal(t, RequestClassification{IgnorePrefetch, "X-Purpose: preview"}, ClassifyRequest(req))
+	req.Header.Del("X-Purpose")
+	req.Header.Set("X-Moz", "prefetch")
+	assert.Equal(t, RequestClassification{IgnorePrefetch, "X-Moz: prefetch"}, ClassifyRequest(req))
+	req.Header.Del("X-Moz")
+	req.Header.Set("Referer", "https://www.2your.site/")
+	assert.Equal(t, RequestClassification{IgnoreReferrerSpam, "2your.site"}, ClassifyRequest(req))
+	req.Header.Del("Referer")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/61.0.4147.135 Safari/537.36")
+	assert.Equal(t, RequestClassification{IgnoreOldBrowser, "Chrome 61.0"}, ClassifyRequest(req))
+	req.Header.Set("User-Agent", "This is a bot request")
+	assert.Equal(t, RequestClassification{IgnoreBot, "bot"}, ClassifyRequest(req))
+	assert.True(t, ClassifyRequest(req).IsBot())
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)")
+	assert.Equal(t, RequestClassification{IgnoreBot, "Googlebot"}, ClassifyRequest(req))
+	req.Header.Set("User-Agent", "python-requests/2.31.0")
+	assert.Equal(t, RequestClassification{IgnoreBot, "python-requests"}, ClassifyRequest(req))
+	req.Header.Set("User-Agent", "")
+	assert.Equal(t, RequestClassification{Reason: IgnoreEmptyUserAgent}, ClassifyRequest(req))
+	assert.True(t, ClassifyRequest(req).IsBot())
+	req.Header.Set("DNT", "1")
+	assert.False(t, ClassifyRequest(req).IsBot())
+}
+
+func TestMatchBotUserAgent(t *testing.T) {
+	assert.Equal(t, "bot", matchBotUserAgent("this is a bot request"))
+	assert.Equal(t, "request", matchBotUserAgent("python-requests/2.31.0"))
+	assert.Empty(t, matchBotUserAgent("mozilla/5.0 (x11; linux x86_64; rv:89.0) gecko/20100101 firefox/89.0"))
+	assert.Empty(t, matchBotUserAgent(""))
+}
+
+func TestGetBotName(t *testing.T) {
+	assert.Equal(t, "Googlebot", getBotName("mozilla/5.0 (compatible; googlebot/2.1; +http://www.google.com/bot.html)"))
+	assert.Equal(t, "Bingbot", getBotName("mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)"))
+	assert.Equal(t, "curl", getBotName("curl/8.4.0"))
+	assert.Empty(t, getBotName("this is a bot request"))
+}
+
 func TestHitOptionsFromRequest(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "http://test.com/my/path", nil)
 	options := HitOptionsFromRequest(req)
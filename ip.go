@@ -81,3 +81,246 @@ func parseXForwardedForHeader(value string) string {
 func parseXRealIPHeader(value string) string {
 	return value
 }
+
+// AnonymizeIP truncates given IPv4 address to /24 and IPv6 address to /48, by setting the remaining bits to 0.
+// An empty string is returned if the IP is invalid.
+func AnonymizeIP(ip string) string {
+	parsedIP := net.ParseIP(ip)
+
+	if parsedIP == nil {
+		return ""
+	}
+
+	if ipv4 := parsedIP.To4(); ipv4 != nil {
+		return ipv4.Mask(net.CIDRMask(24, 32)).String()
+	}
+
+	return parsedIP.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// Headers used by the IPExtractor to look up the client IP.
+const (
+	HeaderCFConnectingIP = "CF-Connecting-IP"
+	HeaderTrueClientIP   = "True-Client-IP"
+	HeaderXForwardedFor  = "X-Forwarded-For"
+	HeaderForwarded      = "Forwarded"
+	HeaderXRealIP        = "X-Real-IP"
+)
+
+var (
+	// cloudflareRanges are the IP ranges of Cloudflare, see https://www.cloudflare.com/ips/.
+	cloudflareRanges = []string{
+		"173.245.48.0/20",
+		"103.21.244.0/22",
+		"103.22.200.0/22",
+		"103.31.4.0/22",
+		"141.101.64.0/18",
+		"108.162.192.0/18",
+		"190.93.240.0/20",
+		"188.114.96.0/20",
+		"197.234.240.0/22",
+		"198.41.128.0/17",
+		"162.158.0.0/15",
+		"104.16.0.0/13",
+		"104.24.0.0/14",
+		"172.64.0.0/13",
+		"131.0.72.0/22",
+		"2400:cb00::/32",
+		"2606:4700::/32",
+		"2803:f800::/32",
+		"2405:b500::/32",
+		"2405:8100::/32",
+		"2a06:98c0::/29",
+		"2c0f:f248::/32",
+	}
+
+	// privateRanges are the loopback and private network IP ranges, used by load balancers and reverse proxies in the same network.
+	privateRanges = []string{
+		"127.0.0.0/8",
+		"10.0.0.0/8",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"::1/128",
+		"fc00::/7",
+	}
+)
+
+// IPExtractor looks up the client IP from the headers set by trusted proxies.
+// The headers are only read if the request has been sent by a trusted proxy, so that clients cannot spoof their IP.
+type IPExtractor struct {
+	trustedProxies []*net.IPNet
+	headers        []string
+}
+
+// NewIPExtractor creates a new IPExtractor for given trusted proxies and headers.
+// The trusted proxies are IP ranges in CIDR notation (like 10.0.0.0/8) or single IPs.
+// The headers are checked in order, the first one containing a valid IP is used.
+// For X-Forwarded-For and Forwarded, the right-most IP not belonging to a trusted proxy is used.
+// If no header is set, or the request has not been sent by a trusted proxy, the remote address is used.
+func NewIPExtractor(trustedProxies, headers []string) (*IPExtractor, error) {
+	extractor := &IPExtractor{
+		trustedProxies: make([]*net.IPNet, 0, len(trustedProxies)),
+		headers:        headers,
+	}
+
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+
+		if err != nil {
+			return nil, err
+		}
+
+		extractor.trustedProxies = append(extractor.trustedProxies, ipNet)
+	}
+
+	return extractor, nil
+}
+
+// CloudflareIPExtractor returns an IPExtractor for applications behind Cloudflare.
+// It trusts the CF-Connecting-IP header for requests from the Cloudflare IP ranges.
+func CloudflareIPExtractor() *IPExtractor {
+	extractor, _ := NewIPExtractor(cloudflareRanges, []string{HeaderCFConnectingIP})
+	return extractor
+}
+
+// AWSALBIPExtractor returns an IPExtractor for applications behind an AWS Application Load Balancer.
+// It trusts the X-Forwarded-For header for requests from private networks, which includes the load balancer in your VPC.
+func AWSALBIPExtractor() *IPExtractor {
+	extractor, _ := NewIPExtractor(privateRanges, []string{HeaderXForwardedFor})
+	return extractor
+}
+
+// NginxIPExtractor returns an IPExtractor for applications behind an nginx reverse proxy on the same host or network.
+// It trusts the X-Real-IP and X-Forwarded-For headers for requests from loopback and private networks.
+func NginxIPExtractor() *IPExtractor {
+	extractor, _ := NewIPExtractor(privateRanges, []string{HeaderXRealIP, HeaderXForwardedFor})
+	return extractor
+}
+
+// ExtractIP returns the client IP for given request.
+func (extractor *IPExtractor) ExtractIP(r *http.Request) string {
+	remoteAddr := stripPort(r.RemoteAddr)
+
+	if !extractor.isTrusted(net.ParseIP(remoteAddr)) {
+		return remoteAddr
+	}
+
+	for _, header := range extractor.headers {
+		values := r.Header.Values(header)
+
+		if len(values) == 0 {
+			continue
+		}
+
+		var ip string
+
+		switch http.CanonicalHeaderKey(header) {
+		case HeaderXForwardedFor:
+			ip = extractor.rightMostUntrusted(extractor.splitHops(values, parseXForwardedForHop))
+		case HeaderForwarded:
+			ip = extractor.rightMostUntrusted(extractor.splitHops(values, parseForwardedHop))
+		default:
+			ip = parseIP(values[0])
+		}
+
+		if ip != "" {
+			return ip
+		}
+	}
+
+	return remoteAddr
+}
+
+func (extractor *IPExtractor) isTrusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range extractor.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitHops returns the IPs of all hops in given header values, from left (client) to right (last proxy).
+func (extractor *IPExtractor) splitHops(values []string, parse func(string) string) []string {
+	hops := make([]string, 0, len(values))
+
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, parse(hop))
+		}
+	}
+
+	return hops
+}
+
+// rightMostUntrusted returns the right-most IP not belonging to a trusted proxy, or the left-most one if all are trusted.
+// An empty string is returned if a hop is not a valid IP, as the hops left of it cannot be trusted.
+func (extractor *IPExtractor) rightMostUntrusted(hops []string) string {
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+
+		if ip == nil {
+			return ""
+		}
+
+		if !extractor.isTrusted(ip) || i == 0 {
+			return hops[i]
+		}
+	}
+
+	return ""
+}
+
+func parseXForwardedForHop(hop string) string {
+	return parseIP(hop)
+}
+
+func parseForwardedHop(hop string) string {
+	for _, part := range strings.Split(hop, ";") {
+		kv := strings.SplitN(part, "=", 2)
+
+		if len(kv) == 2 && strings.ToLower(strings.TrimSpace(kv[0])) == "for" {
+			return parseIP(strings.Trim(strings.TrimSpace(kv[1]), `"`))
+		}
+	}
+
+	return ""
+}
+
+// parseIP returns given IP without port and brackets, or an empty string if it's invalid.
+func parseIP(value string) string {
+	value = strings.TrimSpace(value)
+
+	if net.ParseIP(value) == nil {
+		value = stripPort(value)
+	}
+
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+
+	if net.ParseIP(value) == nil {
+		return ""
+	}
+
+	return value
+}
+
+func stripPort(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return addr
+}
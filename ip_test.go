@@ -73,3 +73,83 @@ func TestGetIP(t *testing.T) {
 	r.Header.Set("CF-Connecting-IP", "127.0.0.1, 23.21.45.67")
 	assert.Equal(t, "127.0.0.1", getIP(r))
 }
+
+func TestIPExtractor(t *testing.T) {
+	extractor, err := NewIPExtractor([]string{"10.0.0.0/8", "192.168.1.1", "fd00::/8"}, []string{HeaderXRealIP, HeaderXForwardedFor, HeaderForwarded})
+	assert.NoError(t, err)
+
+	// untrusted remote address
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "23.21.45.67:29302"
+	r.Header.Set("X-Real-IP", "103.0.53.43")
+	r.Header.Set("X-Forwarded-For", "103.0.53.43")
+	assert.Equal(t, "23.21.45.67", extractor.ExtractIP(r))
+
+	// trusted remote address
+	r.RemoteAddr = "10.1.2.3:29302"
+	assert.Equal(t, "103.0.53.43", extractor.ExtractIP(r))
+	r.Header.Set("X-Real-IP", "invalid")
+	r.Header.Set("X-Forwarded-For", "1.1.1.1, 103.0.53.43, 10.0.0.1")
+	assert.Equal(t, "103.0.53.43", extractor.ExtractIP(r), "the right-most untrusted hop must be used")
+	r.Header.Set("X-Forwarded-For", "1.1.1.1")
+	r.Header.Add("X-Forwarded-For", "103.0.53.44, 192.168.1.1")
+	assert.Equal(t, "103.0.53.44", extractor.ExtractIP(r))
+	r.Header.Set("X-Forwarded-For", "10.0.0.2, 10.0.0.1")
+	assert.Equal(t, "10.0.0.2", extractor.ExtractIP(r), "the left-most hop must be used if all are trusted")
+	r.Header.Set("X-Forwarded-For", "103.0.53.43, invalid, 10.0.0.1")
+	assert.Equal(t, "10.1.2.3", extractor.ExtractIP(r), "hops left of an invalid hop must not be trusted")
+	r.Header.Del("X-Forwarded-For")
+	r.Header.Set("Forwarded", `for=1.1.1.1, for="[2001:db8::1]:4711";proto=http, for=10.0.0.1`)
+	assert.Equal(t, "2001:db8::1", extractor.ExtractIP(r))
+	r.Header.Del("Forwarded")
+	r.Header.Del("X-Real-IP")
+	assert.Equal(t, "10.1.2.3", extractor.ExtractIP(r))
+
+	// IPv6
+	r.RemoteAddr = "[fd00::1]:29302"
+	r.Header.Set("X-Forwarded-For", "2001:db8::2")
+	assert.Equal(t, "2001:db8::2", extractor.ExtractIP(r))
+
+	_, err = NewIPExtractor([]string{"invalid"}, nil)
+	assert.Error(t, err)
+}
+
+func TestIPExtractorPresets(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "173.245.48.1:29302"
+	r.Header.Set("CF-Connecting-IP", "103.0.53.43")
+	r.Header.Set("X-Forwarded-For", "103.0.53.44")
+	r.Header.Set("X-Real-IP", "103.0.53.45")
+	assert.Equal(t, "103.0.53.43", CloudflareIPExtractor().ExtractIP(r))
+	assert.Equal(t, "173.245.48.1", AWSALBIPExtractor().ExtractIP(r))
+	assert.Equal(t, "173.245.48.1", NginxIPExtractor().ExtractIP(r))
+	r.RemoteAddr = "10.0.0.1:29302"
+	assert.Equal(t, "10.0.0.1", CloudflareIPExtractor().ExtractIP(r))
+	assert.Equal(t, "103.0.53.44", AWSALBIPExtractor().ExtractIP(r))
+	assert.Equal(t, "103.0.53.45", NginxIPExtractor().ExtractIP(r))
+}
+
+func TestAnonymizeIP(t *testing.T) {
+	input := []string{
+		"103.0.53.43",
+		"103.0.53.0",
+		"::ffff:103.0.53.43",
+		"2001:db8:85a3:8d3:1319:8a2e:370:7348",
+		"2001:db8::1",
+		"invalid",
+		"",
+	}
+	expected := []string{
+		"103.0.53.0",
+		"103.0.53.0",
+		"103.0.53.0",
+		"2001:db8:85a3::",
+		"2001:db8::",
+		"",
+		"",
+	}
+
+	for i, in := range input {
+		assert.Equal(t, expected[i], AnonymizeIP(in))
+	}
+}
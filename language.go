@@ -3,22 +3,120 @@ package pirsch
 import (
 	iso6391 "github.com/emvi/iso-639-1"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 )
 
-func getLanguage(r *http.Request) string {
-	lang := r.Header.Get("Accept-Language")
+// Locale is a language range from the Accept-Language header.
+type Locale struct {
+	// Language is the ISO 639-1 language code in lowercase.
+	Language string
 
-	if lang != "" {
-		langs := strings.Split(lang, ";")
-		parts := strings.Split(langs[0], ",")
-		parts = strings.Split(parts[0], "-")
-		code := strings.ToLower(strings.TrimSpace(parts[0]))
+	// Region is the ISO 3166-1 country code or UN M.49 area code (like 419 for Latin America) in uppercase.
+	// It is empty if the language range doesn't contain a region.
+	Region string
 
-		if iso6391.ValidCode(code) {
-			return code
+	// Quality is the weight (q) between 0 and 1.
+	Quality float64
+}
+
+// ParseAcceptLanguage parses given Accept-Language header (RFC 4647) and returns the locales sorted by quality.
+// Locales of the same quality keep their order. Language ranges without a valid ISO 639-1 language code, like the wildcard (*),
+// and ranges with a quality of 0 are left out.
+func ParseAcceptLanguage(header string) []Locale {
+	ranges := strings.Split(header, ",")
+	locales := make([]Locale, 0, len(ranges))
+
+	for _, r := range ranges {
+		params := strings.Split(r, ";")
+		locale, ok := parseLanguageTag(params[0])
+
+		if !ok {
+			continue
+		}
+
+		locale.Quality = 1
+
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+
+				if err != nil || q < 0 || q > 1 {
+					ok = false
+				}
+
+				locale.Quality = q
+			}
+		}
+
+		if ok && locale.Quality > 0 {
+			locales = append(locales, locale)
+		}
+	}
+
+	sort.SliceStable(locales, func(i, j int) bool {
+		return locales[i].Quality > locales[j].Quality
+	})
+	return locales
+}
+
+// parseLanguageTag returns the language and region of a BCP 47 language tag, like en-GB or zh-Hant-TW.
+func parseLanguageTag(tag string) (Locale, bool) {
+	subtags := strings.Split(strings.TrimSpace(tag), "-")
+	language := strings.ToLower(subtags[0])
+
+	if !iso6391.ValidCode(language) {
+		return Locale{}, false
+	}
+
+	locale := Locale{Language: language}
+
+	for i, subtag := range subtags[1:] {
+		// the script (four letters) may be in front of the region
+		if i == 0 && len(subtag) == 4 && isAlpha(subtag) {
+			continue
+		}
+
+		if (len(subtag) == 2 && isAlpha(subtag)) || (len(subtag) == 3 && isDigit(subtag)) {
+			locale.Region = strings.ToUpper(subtag)
+		}
+
+		break
+	}
+
+	return locale, true
+}
+
+// getLanguage returns the language and region of the preferred locale.
+func getLanguage(r *http.Request) (string, string) {
+	locales := ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+
+	if len(locales) == 0 {
+		return "", ""
+	}
+
+	return locales[0].Language, locales[0].Region
+}
+
+func isAlpha(s string) bool {
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isDigit(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
 		}
 	}
 
-	return ""
+	return true
 }
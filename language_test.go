@@ -1,6 +1,7 @@
 package pirsch
 
 import (
+	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -14,22 +15,52 @@ func TestGetLanguage(t *testing.T) {
 		"en-us, en",
 		"en-gb, en",
 		"invalid",
+		"de;q=0.5, en-GB;q=0.8, *",
+		"*, es-419;q=0.9",
+		"zh-Hant-TW, zh;q=0.8",
+		"sr-Latn, en;q=0.1",
+		"gsw-CH, de-CH;q=0.9",
+		"en-US;q=0, de-AT;q=0.3",
+		"en-GB;q=invalid, fr-FR;q=0.5",
+		"en-GB;q=2, it",
 	}
-	expected := []string{
-		"",
-		"",
-		"fr",
-		"en",
-		"en",
-		"",
+	expected := [][]string{
+		{"", ""},
+		{"", ""},
+		{"fr", "CH"},
+		{"en", "US"},
+		{"en", "GB"},
+		{"", ""},
+		{"en", "GB"},
+		{"es", "419"},
+		{"zh", "TW"},
+		{"sr", ""},
+		{"de", "CH"},
+		{"de", "AT"},
+		{"fr", "FR"},
+		{"it", ""},
 	}
 
 	for i, in := range input {
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.Header.Set("Accept-Language", in)
 
-		if lang := getLanguage(req); lang != expected[i] {
-			t.Fatalf("Expected '%v', but was: %v", expected[i], lang)
+		if lang, region := getLanguage(req); lang != expected[i][0] || region != expected[i][1] {
+			t.Fatalf("Expected '%v', but was: %v %v", expected[i], lang, region)
 		}
 	}
 }
+
+func TestParseAcceptLanguage(t *testing.T) {
+	locales := ParseAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8, de;q=0.9, *;q=0.5, en-GB ; q=0.8, it;q=0")
+	assert.Equal(t, []Locale{
+		{Language: "fr", Region: "CH", Quality: 1},
+		{Language: "fr", Quality: 0.9},
+		{Language: "de", Quality: 0.9},
+		{Language: "en", Quality: 0.8},
+		{Language: "en", Region: "GB", Quality: 0.8},
+	}, locales)
+	assert.Empty(t, ParseAcceptLanguage(""))
+	assert.Empty(t, ParseAcceptLanguage("*"))
+	assert.Empty(t, ParseAcceptLanguage(",;q=1,"))
+}
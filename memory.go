@@ -0,0 +1,114 @@
+package pirsch
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryClient is an in-memory Store implementation.
+// Unlike the MockClient, it answers all queries issued by the Analyzer, so it can be used to test statistics without a database server.
+// The statistics are calculated from the hits and events kept in memory.
+type MemoryClient struct {
+	Hits    []Hit
+	Events  []Event
+	BotHits []BotHit
+	m       sync.RWMutex
+}
+
+// NewMemoryClient returns a new empty in-memory client.
+func NewMemoryClient() *MemoryClient {
+	return &MemoryClient{
+		Hits:    make([]Hit, 0),
+		Events:  make([]Event, 0),
+		BotHits: make([]BotHit, 0),
+	}
+}
+
+// SaveHits implements the Store interface.
+func (client *MemoryClient) SaveHits(hits []Hit) error {
+	client.m.Lock()
+	defer client.m.Unlock()
+	client.Hits = append(client.Hits, hits...)
+	return nil
+}
+
+// SaveEvents implements the Store interface.
+func (client *MemoryClient) SaveEvents(events []Event) error {
+	client.m.Lock()
+	defer client.m.Unlock()
+	client.Events = append(client.Events, events...)
+	return nil
+}
+
+// SaveBotHits implements the Store interface.
+func (client *MemoryClient) SaveBotHits(hits []BotHit) error {
+	client.m.Lock()
+	defer client.m.Unlock()
+	client.BotHits = append(client.BotHits, hits...)
+	return nil
+}
+
+// Session implements the Store interface.
+func (client *MemoryClient) Session(clientID int64, fingerprint string, maxAge time.Time) (Session, error) {
+	client.m.RLock()
+	defer client.m.RUnlock()
+	var session Session
+
+	for _, hit := range client.Hits {
+		if hit.ClientID == clientID && hit.Fingerprint == fingerprint && hit.Time.After(maxAge) && hit.Time.After(session.Time) {
+			session.Path = hit.Path
+			session.Time = hit.Time
+			session.Session = hit.Session
+		}
+	}
+
+	return session, nil
+}
+
+// Query implements the Store interface.
+func (client *MemoryClient) Query(results interface{}, query *Query) error {
+	client.m.RLock()
+	hits := make([]memoryRow, 0, len(client.Hits))
+
+	for _, hit := range client.Hits {
+		hits = append(hits, memoryRow{Event: Event{Hit: hit}})
+	}
+
+	rows := hits
+
+	if query != nil && query.Filter != nil {
+		switch query.table() {
+		case "event":
+			rows = make([]memoryRow, 0, len(client.Events))
+
+			for _, event := range client.Events {
+				rows = append(rows, memoryRow{Event: event})
+			}
+		case "bot_hit":
+			rows = make([]memoryRow, 0, len(client.BotHits))
+
+			for _, hit := range client.BotHits {
+				rows = append(rows, memoryRow{
+					Event: Event{Hit: Hit{
+						ClientID:  hit.ClientID,
+						Time:      hit.Time,
+						UserAgent: hit.UserAgent,
+						Path:      hit.Path,
+						URL:       hit.URL,
+					}},
+					reason: hit.Reason,
+					rule:   hit.Rule,
+				})
+			}
+		}
+	}
+
+	client.m.RUnlock()
+	stats, err := evaluateMemoryQuery(query, hits, rows)
+
+	if err != nil {
+		return err
+	}
+
+	return scanQueryResults(results, stats)
+}
@@ -0,0 +1,197 @@
+package pirsch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMemoryClient(t *testing.T) {
+	client := NewMemoryClient()
+	now := time.Now().UTC()
+	assert.NoError(t, client.SaveHits([]Hit{
+		{ClientID: 1, Fingerprint: "fp", Time: now.Add(-time.Second * 20), Session: now.Add(-time.Second * 20), Path: "/path1"},
+		{ClientID: 1, Fingerprint: "fp", Time: now, Session: now, Path: "/path2"},
+		{ClientID: 2, Fingerprint: "fp", Time: now, Session: now, Path: "/path3"},
+	}))
+	assert.NoError(t, client.SaveEvents([]Event{{Name: "event", Hit: Hit{ClientID: 1, Fingerprint: "fp", Time: now}}}))
+	assert.Len(t, client.Hits, 3)
+	assert.NoError(t, client.SaveBotHits([]BotHit{{ClientID: 1, Time: now, Path: "/path1", Reason: IgnoreBot, Rule: "curl"}}))
+	assert.Len(t, client.Events, 1)
+	assert.Len(t, client.BotHits, 1)
+	session, err := client.Session(1, "fp", now.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, "/path2", session.Path)
+	assert.Equal(t, now, session.Time)
+	session, err = client.Session(1, "fp", now)
+	assert.NoError(t, err)
+	assert.Empty(t, session.Path)
+	assert.True(t, session.Time.IsZero())
+}
+
+func TestMemoryClient_Analyzer(t *testing.T) {
+	client := NewMemoryClient()
+	assert.NoError(t, client.SaveHits([]Hit{
+		{Fingerprint: "fp1", Time: pastDay(1), Session: pastDay(1), Path: "/", Referrer: "ref1"},
+		{Fingerprint: "fp1", Time: pastDay(1).Add(time.Minute), Session: pastDay(1), Path: "/foo", PreviousTimeOnPageSeconds: 60},
+		{Fingerprint: "fp1", Time: pastDay(1).Add(time.Minute * 3), Session: pastDay(1), Path: "/bar", PreviousTimeOnPageSeconds: 120},
+		{Fingerprint: "fp2", Time: pastDay(1), Session: pastDay(1), Path: "/", Referrer: "ref2"},
+		{Fingerprint: "fp3", Time: pastDay(0), Session: pastDay(0), Path: "/foo", Referrer: "ref1"},
+	}))
+	assert.NoError(t, client.SaveEvents([]Event{
+		{Name: "event", DurationSeconds: 4, Hit: Hit{Fingerprint: "fp1", Time: pastDay(1), Path: "/"}},
+		{Name: "event", DurationSeconds: 2, Hit: Hit{Fingerprint: "fp3", Time: pastDay(0), Path: "/foo"}},
+	}))
+	analyzer := NewAnalyzer(client)
+	visitors, err := analyzer.Visitors(&Filter{From: pastDay(1), To: pastDay(0)})
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 2)
+	assert.Equal(t, 2, visitors[0].Visitors)
+	assert.Equal(t, 2, visitors[0].Sessions)
+	assert.Equal(t, 4, visitors[0].Views)
+	assert.Equal(t, 1, visitors[0].Bounces)
+	assert.Equal(t, 1, visitors[1].Visitors)
+	pages, err := analyzer.Pages(nil)
+	assert.NoError(t, err)
+	assert.Len(t, pages, 3)
+	assert.Equal(t, "/", pages[0].Path)
+	assert.Equal(t, 2, pages[0].Visitors)
+	entries, err := analyzer.EntryPages(nil)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "/", entries[0].Path)
+	assert.Equal(t, 2, entries[0].Entries)
+	exits, err := analyzer.ExitPages(nil)
+	assert.NoError(t, err)
+	assert.Len(t, exits, 3)
+	referrer, err := analyzer.Referrer(nil)
+	assert.NoError(t, err)
+	assert.Len(t, referrer, 3)
+	assert.Equal(t, "ref1", referrer[0].Referrer)
+	assert.Equal(t, 2, referrer[0].Visitors)
+	events, err := analyzer.Events(nil)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, 2, events[0].Visitors)
+	assert.Equal(t, 3, events[0].AverageDurationSeconds)
+	timeOnPage, err := analyzer.TotalTimeOnPage(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 180, timeOnPage)
+	sessionDuration, err := analyzer.TotalSessionDuration(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 180, sessionDuration)
+}
+
+func TestMemoryClient_SQLite(t *testing.T) {
+	memory := NewMemoryClient()
+	db := newTestSQLiteClient(t)
+	now := time.Now().UTC().Add(-time.Minute)
+
+	for _, store := range []Store{memory, db} {
+		assert.NoError(t, store.SaveHits([]Hit{
+			{Fingerprint: "fp1", Time: pastDay(2).Add(time.Hour * 3), Session: pastDay(2).Add(time.Hour * 3), Path: "/", Title: "Home", Referrer: "ref1", ReferrerName: "Ref 1", Desktop: true, DeviceType: "desktop", Language: "en", LanguageRegion: "US", CountryCode: "us", Region: "New York", City: "New York", OS: OSWindows, OSVersion: "10", Browser: BrowserChrome, BrowserVersion: "90", ScreenClass: "XL", UTMSource: "source", UTMMedium: "medium", UTMCampaign: "campaign", UTMContent: "content", UTMTerm: "term"},
+			{Fingerprint: "fp1", Time: pastDay(2).Add(time.Hour*3 + time.Minute), Session: pastDay(2).Add(time.Hour * 3), PreviousTimeOnPageSeconds: 60, Path: "/simple/page", Title: "Page", Desktop: true, DeviceType: "desktop", Language: "en", CountryCode: "us", OS: OSWindows, OSVersion: "10", Browser: BrowserChrome, BrowserVersion: "90", ScreenClass: "XL"},
+			{Fingerprint: "fp1", Time: pastDay(2).Add(time.Hour*3 + time.Minute*5), Session: pastDay(2).Add(time.Hour * 3), PreviousTimeOnPageSeconds: 240, Path: "/", Title: "Home", Desktop: true, DeviceType: "desktop", Language: "en", CountryCode: "us", OS: OSWindows, OSVersion: "10", Browser: BrowserChrome, BrowserVersion: "90", ScreenClass: "XL"},
+			{Fingerprint: "fp2", Time: pastDay(2).Add(time.Hour * 23), Session: pastDay(2).Add(time.Hour * 23), Path: "/", Title: "Home", Referrer: "ref2", Mobile: true, DeviceType: "mobile", Language: "de", LanguageRegion: "AT", CountryCode: "at", Region: "Vienna", City: "Vienna", OS: OSAndroid, OSVersion: "11", Browser: BrowserFirefox, BrowserVersion: "88", ScreenClass: "S"},
+			{Fingerprint: "fp2", Time: pastDay(1).Add(time.Minute * 2), Session: pastDay(2).Add(time.Hour * 23), PreviousTimeOnPageSeconds: 3720, Path: "/siMple/page/", Title: "Page", Mobile: true, DeviceType: "mobile", Language: "de", CountryCode: "at", OS: OSAndroid, OSVersion: "11", Browser: BrowserFirefox, BrowserVersion: "88", ScreenClass: "S"},
+			{Fingerprint: "fp3", Time: pastDay(1).Add(time.Hour * 12), Session: pastDay(1).Add(time.Hour * 12), Path: "/bar", Referrer: "ref1", ReferrerName: "Ref 1", DeviceType: "tv", Language: "en", CountryCode: "gb", OS: OSLinux, Browser: BrowserChrome, BrowserVersion: "91"},
+			{Fingerprint: "fp4", Time: pastDay(1).Add(time.Hour * 12), Path: "/", Referrer: "ref1", ReferrerName: "Ref 1", Desktop: true, DeviceType: "desktop", Language: "en", CountryCode: "gb", OS: OSMac, Browser: BrowserSafari, BrowserVersion: "14"},
+			{Fingerprint: "fp4", Time: pastDay(1).Add(time.Hour*12 + time.Second*30), Path: "/bar", PreviousTimeOnPageSeconds: 30, Desktop: true, DeviceType: "desktop", Language: "en", CountryCode: "gb", OS: OSMac, Browser: BrowserSafari, BrowserVersion: "14"},
+			{Fingerprint: "fp5", Time: now.Add(-time.Minute * 2), Session: now.Add(-time.Minute * 2), Path: "/", Title: "Home", Mobile: true, DeviceType: "tablet", Language: "fr", CountryCode: "fr", OS: OSiOS, Browser: BrowserSafari},
+			{Fingerprint: "fp5", Time: now, Session: now.Add(-time.Minute * 2), PreviousTimeOnPageSeconds: 120, Path: "/foo", Title: "Foo", Mobile: true, DeviceType: "tablet", Language: "fr", CountryCode: "fr", OS: OSiOS, Browser: BrowserSafari},
+			{ClientID: 1, Fingerprint: "fp1", Time: pastDay(1), Session: pastDay(1), Path: "/other"},
+		}))
+		assert.NoError(t, store.SaveEvents([]Event{
+			{Name: "event", DurationSeconds: 5, MetaKeys: []string{"status", "price"}, MetaValues: []string{"in", "34.56"}, Hit: Hit{Fingerprint: "fp1", Time: pastDay(2).Add(time.Hour * 3), Path: "/", Desktop: true}},
+			{Name: "event", DurationSeconds: 2, MetaKeys: []string{"status"}, MetaValues: []string{"out"}, Hit: Hit{Fingerprint: "fp2", Time: pastDay(1), Path: "/siMple/page/", Mobile: true}},
+			{Name: "event", MetaKeys: []string{"status"}, MetaValues: []string{"in"}, Hit: Hit{Fingerprint: "fp3", Time: pastDay(1).Add(time.Hour * 12), Path: "/bar"}},
+			{Name: "other", DurationSeconds: 9, Hit: Hit{Fingerprint: "fp1", Time: pastDay(2).Add(time.Hour * 4), Path: "/simple/page", Desktop: true}},
+		}))
+		assert.NoError(t, store.SaveBotHits([]BotHit{
+			{Time: pastDay(1), Path: "/", Reason: IgnoreBot, Rule: "curl"},
+			{Time: pastDay(1).Add(time.Hour), Path: "/bar", Reason: IgnoreBot, Rule: "curl"},
+			{Time: pastDay(0), Path: "/", Reason: IgnoreReferrerSpam, Rule: "spam.com"},
+		}))
+	}
+
+	tz, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+	filters := []Filter{
+		{},
+		{From: pastDay(2), To: pastDay(0)},
+		{From: pastDay(2), To: pastDay(1), Timezone: tz},
+		{Day: pastDay(1)},
+		{ClientID: 1},
+		{Path: "/"},
+		{Path: "!/"},
+		{PathPattern: "(?i)^/simple/.*"},
+		{PathPattern: "!(?i)^/simple/.*"},
+		{Platform: PlatformDesktop},
+		{Platform: "!" + PlatformMobile},
+		{Platform: PlatformUnknown},
+		{Referrer: "ref1", Language: "en"},
+		{Country: "!us", DeviceType: "desktop"},
+		{IncludeTitle: true, IncludeAvgTimeOnPage: true},
+		{MaxTimeOnPageSeconds: 100},
+		{EventName: "event"},
+		{EventName: "event", EventMetaKey: "status"},
+		{Limit: 1},
+	}
+	queries := map[string]func(*Analyzer, *Filter) (interface{}, error){
+		"ActiveVisitors": func(analyzer *Analyzer, filter *Filter) (interface{}, error) {
+			stats, total, err := analyzer.ActiveVisitors(filter, time.Minute*10)
+			return []interface{}{stats, total}, err
+		},
+		"Visitors":        func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.Visitors(filter) },
+		"Growth":          func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.Growth(filter) },
+		"VisitorHours":    func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.VisitorHours(filter) },
+		"Pages":           func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.Pages(filter) },
+		"EntryPages":      func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.EntryPages(filter) },
+		"ExitPages":       func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.ExitPages(filter) },
+		"PageConversions": func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.PageConversions(filter) },
+		"Events":          func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.Events(filter) },
+		"Bots":            func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.Bots(filter) },
+		"EventBreakdown":  func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.EventBreakdown(filter) },
+		"Referrer":        func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.Referrer(filter) },
+		"Platform":        func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.Platform(filter) },
+		"Languages":       func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.Languages(filter) },
+		"Locales":         func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.Locales(filter) },
+		"Countries":       func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.Countries(filter) },
+		"Regions":         func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.Regions(filter) },
+		"Cities":          func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.Cities(filter) },
+		"Browser":         func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.Browser(filter) },
+		"OS":              func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.OS(filter) },
+		"ScreenClass":     func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.ScreenClass(filter) },
+		"UTMSource":       func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.UTMSource(filter) },
+		"UTMMedium":       func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.UTMMedium(filter) },
+		"UTMCampaign":     func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.UTMCampaign(filter) },
+		"UTMContent":      func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.UTMContent(filter) },
+		"UTMTerm":         func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.UTMTerm(filter) },
+		"OSVersion":       func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.OSVersion(filter) },
+		"BrowserVersion":  func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.BrowserVersion(filter) },
+		"AvgSessionDuration": func(analyzer *Analyzer, filter *Filter) (interface{}, error) {
+			return analyzer.AvgSessionDuration(filter)
+		},
+		"TotalSessionDuration": func(analyzer *Analyzer, filter *Filter) (interface{}, error) {
+			return analyzer.TotalSessionDuration(filter)
+		},
+		"AvgTimeOnPages":  func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.AvgTimeOnPages(filter) },
+		"AvgTimeOnPage":   func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.AvgTimeOnPage(filter) },
+		"TotalTimeOnPage": func(analyzer *Analyzer, filter *Filter) (interface{}, error) { return analyzer.TotalTimeOnPage(filter) },
+	}
+	memoryAnalyzer, dbAnalyzer := NewAnalyzer(memory), NewAnalyzer(db)
+
+	for name, query := range queries {
+		for i := range filters {
+			memoryFilter, dbFilter := filters[i], filters[i]
+			expected, expectedErr := query(dbAnalyzer, &dbFilter)
+			stats, err := query(memoryAnalyzer, &memoryFilter)
+			assert.Equal(t, expectedErr, err, "%s with filter %d", name, i)
+			assert.Equal(t, expected, stats, "%s with filter %d", name, i)
+		}
+	}
+}
+
+func TestMemoryClient_EntriesAndExits(t *testing.T) {
+	testQueryEntriesAndExits(t, NewMemoryClient())
+}
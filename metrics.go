@@ -0,0 +1,98 @@
+package pirsch
+
+import (
+	"time"
+)
+
+// TrackingKind distinguishes hits and events reported to the MetricsCollector.
+type TrackingKind string
+
+const (
+	// KindHit is used for page views tracked by Tracker.Hit.
+	KindHit = TrackingKind("hit")
+
+	// KindEvent is used for events tracked by Tracker.Event.
+	KindEvent = TrackingKind("event")
+
+	// KindBot is used for bot hits recorded by the Tracker (see TrackerConfig.RecordBots).
+	KindBot = TrackingKind("bot")
+)
+
+// IgnoreReason is the reason a request has been ignored by the Tracker.
+type IgnoreReason string
+
+const (
+	// IgnoreDNT is used for requests with the Do Not Track header set.
+	IgnoreDNT = IgnoreReason("dnt")
+
+	// IgnoreBot is used for requests with a User-Agent on the bot blacklist.
+	IgnoreBot = IgnoreReason("bot")
+
+	// IgnoreEmptyUserAgent is used for requests without User-Agent, which are usually sent by bots.
+	IgnoreEmptyUserAgent = IgnoreReason("empty_user_agent")
+
+	// IgnorePrefetch is used for requests of browsers pre-fetching pages.
+	IgnorePrefetch = IgnoreReason("prefetch")
+
+	// IgnoreReferrerSpam is used for requests from a referrer on the spam blacklist.
+	IgnoreReferrerSpam = IgnoreReason("referrer_spam")
+
+	// IgnoreOldBrowser is used for requests from outdated browsers.
+	IgnoreOldBrowser = IgnoreReason("old_browser")
+)
+
+// SessionCacheResult is the result of looking up a session in the SessionCache.
+type SessionCacheResult string
+
+const (
+	// SessionCacheHit is used if the session has been found in the cache.
+	SessionCacheHit = SessionCacheResult("hit")
+
+	// SessionCacheMiss is used if the session had to be looked up in the Store.
+	SessionCacheMiss = SessionCacheResult("miss")
+
+	// SessionCacheEviction is used for each session removed from the cache to make space for new sessions.
+	SessionCacheEviction = SessionCacheResult("eviction")
+
+	// SessionCacheExpiry is used for each session removed from the cache after the SessionMaxAge.
+	SessionCacheExpiry = SessionCacheResult("expiry")
+)
+
+// MetricsCollector receives metrics from the Tracker, SessionCache, and GeoDB lookups.
+// It can be used to export metrics to Prometheus or another monitoring system without depending on its client library.
+// All methods are called concurrently and on the request path, so they must be thread-safe and return quickly.
+type MetricsCollector interface {
+	// Enqueued is called for each hit or event added to the queue.
+	Enqueued(kind TrackingKind)
+
+	// Ignored is called for each request ignored by the Tracker.
+	Ignored(kind TrackingKind, reason IgnoreReason)
+
+	// Dropped is called for each hit or event dropped because the queue was full (see TrackerConfig.OverflowPolicy),
+	// or because it could not be saved from the spool (see TrackerConfig.SpoolMaxAttempts).
+	Dropped(kind TrackingKind)
+
+	// QueueDepth is called with the number of hits or events in the queue after it has changed.
+	QueueDepth(kind TrackingKind, depth int)
+
+	// BatchSaved is called after a batch of hits or events has been passed to the Store, including retries and spooled batches.
+	// The error is nil if the batch has been saved.
+	BatchSaved(kind TrackingKind, size int, duration time.Duration, err error)
+
+	// SessionCache is called for each session looked up in, evicted from, or expired in the SessionCache.
+	SessionCache(result SessionCacheResult)
+
+	// GeoDBLookupFailed is called if the country code of an IP could not be looked up, because the IP or database is invalid.
+	GeoDBLookupFailed()
+}
+
+// noopMetrics is the MetricsCollector used if none has been configured.
+type noopMetrics struct{}
+
+func (noopMetrics) Enqueued(TrackingKind)                              {}
+func (noopMetrics) Ignored(TrackingKind, IgnoreReason)                 {}
+func (noopMetrics) Dropped(TrackingKind)                               {}
+func (noopMetrics) QueueDepth(TrackingKind, int)                       {}
+func (noopMetrics) BatchSaved(TrackingKind, int, time.Duration, error) {}
+func (noopMetrics) SessionCache(SessionCacheResult)                    {}
+func (noopMetrics) GeoDBLookupFailed()                                 {}
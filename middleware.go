@@ -0,0 +1,165 @@
+package pirsch
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// StaticFileExtensions is the default list of file extensions ignored by the Middleware.
+var StaticFileExtensions = []string{
+	".css",
+	".js",
+	".map",
+	".json",
+	".xml",
+	".txt",
+	".ico",
+	".png",
+	".jpg",
+	".jpeg",
+	".gif",
+	".svg",
+	".webp",
+	".avif",
+	".woff",
+	".woff2",
+	".ttf",
+	".otf",
+	".eot",
+	".mp4",
+	".webm",
+	".mp3",
+	".wasm",
+}
+
+// MiddlewareOptions is the optional configuration for the Middleware.
+type MiddlewareOptions struct {
+	// ClientID is saved with all hits tracked by the Middleware.
+	ClientID int64
+
+	// IgnorePrefixes is a list of path prefixes that won't be tracked, like /api/ or /static/.
+	IgnorePrefixes []string
+
+	// StaticFileExtensions overwrites the list of file extensions that won't be tracked.
+	// The StaticFileExtensions list is used if this is not set.
+	StaticFileExtensions []string
+
+	// NotFoundEventName is the name of the event used to track 404 responses.
+	// 404 responses are not tracked if this is left empty.
+	NotFoundEventName string
+}
+
+// Middleware returns a new middleware to track all page requests passed to the wrapped http.Handler.
+// Requests are only tracked if the method is GET, the path does not point to a static file or starts with an ignored prefix,
+// and the response status code is 2xx. Pass nil for the options to use the defaults.
+func Middleware(tracker *Tracker, options *MiddlewareOptions) func(http.Handler) http.Handler {
+	if options == nil {
+		options = &MiddlewareOptions{}
+	}
+
+	extensions := options.StaticFileExtensions
+
+	if extensions == nil {
+		extensions = StaticFileExtensions
+	}
+
+	ignoreExtension := make(map[string]struct{}, len(extensions))
+
+	for _, ext := range extensions {
+		ignoreExtension[strings.ToLower(ext)] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || ignorePath(r.URL.Path, options.IgnorePrefixes, ignoreExtension) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			sw := &statusResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+			status := sw.statusCode()
+
+			// the hit is created before returning, as the request must not be used after ServeHTTP has returned
+			if status >= 200 && status < 300 {
+				if hit, ok := tracker.newHit(r, tracker.hitOptions(&HitOptions{ClientID: options.ClientID})); ok {
+					enqueue(tracker, func() { tracker.enqueueHit(hit) })
+				}
+			} else if status == http.StatusNotFound && options.NotFoundEventName != "" {
+				if event, ok := tracker.newEvent(r, EventOptions{Name: options.NotFoundEventName}, tracker.hitOptions(&HitOptions{ClientID: options.ClientID})); ok {
+					enqueue(tracker, func() { tracker.enqueueEvent(event) })
+				}
+			}
+		})
+	}
+}
+
+// enqueue calls given function in its own goroutine if the Tracker blocks while the queue is full, so that the response isn't delayed.
+func enqueue(tracker *Tracker, f func()) {
+	if tracker.overflowPolicy == OverflowBlock {
+		go f()
+	} else {
+		f()
+	}
+}
+
+func ignorePath(path string, prefixes []string, extensions map[string]struct{}) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	_, ignore := extensions[strings.ToLower(filepath.Ext(path))]
+	return ignore
+}
+
+// statusResponseWriter wraps a http.ResponseWriter to capture the status code.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements the http.ResponseWriter interface.
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements the http.Flusher interface if the wrapped http.ResponseWriter supports it.
+func (w *statusResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped http.ResponseWriter.
+func (w *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusResponseWriter) statusCode() int {
+	if w.status == 0 {
+		// nothing has been written, which results in 200 OK
+		return http.StatusOK
+	}
+
+	return w.status
+}
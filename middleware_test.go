@@ -0,0 +1,98 @@
+package pirsch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	client := NewMockClient()
+	metrics := newTestMetrics()
+	tracker := NewTracker(client, "salt", &TrackerConfig{Metrics: metrics})
+	handler := Middleware(tracker, &MiddlewareOptions{
+		ClientID:          42,
+		IgnorePrefixes:    []string{"/api/"},
+		NotFoundEventName: "404",
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/not-found":
+			w.WriteHeader(http.StatusNotFound)
+		case "/error":
+			http.Error(w, "error", http.StatusInternalServerError)
+		case "/empty":
+			// no response body
+		default:
+			_, _ = w.Write([]byte("Hello World!"))
+		}
+	}))
+
+	for _, path := range []string{"/", "/empty", "/not-found", "/error", "/api/user", "/static/style.css", "/favicon.ICO"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Hello World!", w.Body.String())
+	// the middleware tracks in the background, so wait for the hits and events to be enqueued before flushing them
+	assert.Eventually(t, func() bool {
+		metrics.m.Lock()
+		defer metrics.m.Unlock()
+		return metrics.enqueued[KindHit] == 2 && metrics.enqueued[KindEvent] == 1
+	}, time.Second*5, time.Millisecond)
+	tracker.Stop()
+	assert.Len(t, client.Hits, 2)
+	assert.Len(t, client.Events, 1)
+	paths := []string{client.Hits[0].Path, client.Hits[1].Path}
+	assert.Contains(t, paths, "/")
+	assert.Contains(t, paths, "/empty")
+	assert.Equal(t, int64(42), client.Hits[0].ClientID)
+	assert.Equal(t, "404", client.Events[0].Name)
+	assert.Equal(t, "/not-found", client.Events[0].Path)
+}
+
+func TestMiddlewareOverflowPolicy(t *testing.T) {
+	client := NewMockClient()
+	metrics := newTestMetrics()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Metrics:        metrics,
+		OverflowPolicy: OverflowDropNewest,
+	})
+	handler := Middleware(tracker, &MiddlewareOptions{NotFoundEventName: "404"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/not-found" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	for _, path := range []string{"/", "/not-found"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// the hit and event must have been enqueued before ServeHTTP returns if the Tracker doesn't block
+	metrics.m.Lock()
+	assert.Equal(t, 1, metrics.enqueued[KindHit])
+	assert.Equal(t, 1, metrics.enqueued[KindEvent])
+	metrics.m.Unlock()
+	tracker.Stop()
+	assert.Len(t, client.Hits, 1)
+	assert.Len(t, client.Events, 1)
+}
+
+func TestStatusResponseWriter(t *testing.T) {
+	w := &statusResponseWriter{ResponseWriter: httptest.NewRecorder()}
+	assert.Equal(t, http.StatusOK, w.statusCode())
+	w.WriteHeader(http.StatusNotFound)
+	_, err := w.Write([]byte("not found"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, w.statusCode())
+}
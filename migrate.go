@@ -10,20 +10,41 @@ import (
 	// Required for the ClickHouse database migration.
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 
+	// Required for the Postgres database migration.
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+
+	"database/sql"
 	"embed"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/source/httpfs"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
 )
 
+const sqliteScheme = "sqlite://"
+
 //go:embed schema
 var schema embed.FS
 
 // Migrate runs the database migration for given connection string.
 // This will use the embedded schema migration scripts.
-// You have to set the x-multi-statement to true, or else it will fail to run the queries.
+// The database is selected by the scheme of the connection string (clickhouse://, postgres://, or sqlite:// followed by the database file).
+// For ClickHouse, you have to set the x-multi-statement to true, or else it will fail to run the queries.
 func Migrate(connection string) error {
-	source, err := httpfs.New(http.FS(schema), "schema")
+	if strings.HasPrefix(connection, sqliteScheme) {
+		db, err := sql.Open("sqlite", strings.TrimPrefix(connection, sqliteScheme))
+
+		if err != nil {
+			return err
+		}
+
+		defer db.Close()
+		return migrateSQLite(db)
+	}
+
+	source, err := httpfs.New(http.FS(schema), schemaDir(connection))
 
 	if err != nil {
 		return err
@@ -49,3 +70,76 @@ func Migrate(connection string) error {
 
 	return nil
 }
+
+func schemaDir(connection string) string {
+	if strings.HasPrefix(connection, "postgres://") || strings.HasPrefix(connection, "postgresql://") {
+		return "schema/postgres"
+	}
+
+	return "schema"
+}
+
+// migrateSQLite runs the SQLite schema migration for given database.
+// golang-migrate only supports SQLite using cgo, so the applied versions are tracked in the schema_migrations table instead.
+func migrateSQLite(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+
+	var version int
+
+	if err := db.QueryRow(`SELECT coalesce(max(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return err
+	}
+
+	files, err := schema.ReadDir("schema/sqlite")
+
+	if err != nil {
+		return err
+	}
+
+	// the files are sorted by name and therefore by version
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".up.sql") {
+			continue
+		}
+
+		v, err := strconv.Atoi(strings.SplitN(file.Name(), "_", 2)[0])
+
+		if err != nil {
+			return err
+		}
+
+		if v <= version {
+			continue
+		}
+
+		query, err := schema.ReadFile(path.Join("schema/sqlite", file.Name()))
+
+		if err != nil {
+			return err
+		}
+
+		tx, err := db.Begin()
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(string(query)); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, v); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 type MockClient struct {
 	Hits          []Hit
 	Events        []Event
+	BotHits       []BotHit
 	ReturnSession *Session
 	m             sync.Mutex
 }
@@ -16,8 +17,9 @@ type MockClient struct {
 // NewMockClient returns a new mock client.
 func NewMockClient() *MockClient {
 	return &MockClient{
-		Hits:   make([]Hit, 0),
-		Events: make([]Event, 0),
+		Hits:    make([]Hit, 0),
+		Events:  make([]Event, 0),
+		BotHits: make([]BotHit, 0),
 	}
 }
 
@@ -37,6 +39,14 @@ func (client *MockClient) SaveEvents(events []Event) error {
 	return nil
 }
 
+// SaveBotHits implements the Store interface.
+func (client *MockClient) SaveBotHits(hits []BotHit) error {
+	client.m.Lock()
+	defer client.m.Unlock()
+	client.BotHits = append(client.BotHits, hits...)
+	return nil
+}
+
 // Session implements the Store interface.
 func (client *MockClient) Session(clientID int64, fingerprint string, maxAge time.Time) (Session, error) {
 	if client.ReturnSession != nil {
@@ -50,17 +60,7 @@ func (client *MockClient) Session(clientID int64, fingerprint string, maxAge tim
 	}, nil
 }
 
-// Count implements the Store interface.
-func (client *MockClient) Count(query string, args ...interface{}) (int, error) {
-	return 0, nil
-}
-
-// Get implements the Store interface.
-func (client *MockClient) Get(result interface{}, query string, args ...interface{}) error {
-	return nil
-}
-
-// Select implements the Store interface.
-func (client *MockClient) Select(results interface{}, query string, args ...interface{}) error {
+// Query implements the Store interface.
+func (client *MockClient) Query(results interface{}, query *Query) error {
 	return nil
 }
@@ -2,6 +2,7 @@ package pirsch
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -17,7 +18,12 @@ type Hit struct {
 	URL                       string
 	Title                     string
 	Language                  string
+	LanguageRegion            string `db:"language_region"`
 	CountryCode               string `db:"country_code"`
+	Region                    string
+	City                      string
+	ASN                       int
+	ASOrganization            string `db:"as_organization"`
 	Referrer                  string
 	ReferrerName              string `db:"referrer_name"`
 	ReferrerIcon              string `db:"referrer_icon"`
@@ -27,6 +33,7 @@ type Hit struct {
 	BrowserVersion            string `db:"browser_version"`
 	Desktop                   bool
 	Mobile                    bool
+	DeviceType                string `db:"device_type"`
 	ScreenWidth               int    `db:"screen_width"`
 	ScreenHeight              int    `db:"screen_height"`
 	ScreenClass               string `db:"screen_class"`
@@ -35,6 +42,9 @@ type Hit struct {
 	UTMCampaign               string `db:"utm_campaign"`
 	UTMContent                string `db:"utm_content"`
 	UTMTerm                   string `db:"utm_term"`
+
+	// journal is the spool log the hit has been written to when it was enqueued, if the spool is enabled
+	journal string
 }
 
 // String implements the Stringer interface.
@@ -59,6 +69,46 @@ func (event Event) String() string {
 	return string(out)
 }
 
+// BotHit is a request that has been classified as a bot by ClassifyRequest.
+// Bot hits are stored separately from hits if TrackerConfig.RecordBots is enabled, to report crawler activity.
+type BotHit struct {
+	ClientID  int64 `db:"client_id"`
+	Time      time.Time
+	UserAgent string `db:"user_agent"`
+	Path      string
+	URL       string
+	Reason    IgnoreReason `db:"bot_reason"`
+	Rule      string       `db:"bot_rule"`
+}
+
+// String implements the Stringer interface.
+func (hit BotHit) String() string {
+	out, _ := json.Marshal(hit)
+	return string(out)
+}
+
+// StringArray is a list of strings read from the database.
+// It can be scanned from an array (ClickHouse) or a JSON array (all other databases).
+type StringArray []string
+
+// Scan implements the sql.Scanner interface.
+func (array *StringArray) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*array = nil
+		return nil
+	case []string:
+		*array = v
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[]string)(array))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]string)(array))
+	}
+
+	return fmt.Errorf("cannot scan %T into StringArray", src)
+}
+
 // Session represents a visitor session as it is returned by the Client from the database.
 // This is not used for any actual statistic.
 type Session struct {
@@ -140,13 +190,13 @@ type PageConversionsStats struct {
 
 // EventStats is the result type for custom events.
 type EventStats struct {
-	Name                   string   `db:"event_name" json:"name"`
-	Visitors               int      `json:"visitors"`
-	Views                  int      `json:"views"`
-	CR                     float64  `json:"cr"`
-	AverageDurationSeconds int      `db:"average_duration_seconds" json:"average_duration_seconds"`
-	MetaKeys               []string `db:"meta_keys" json:"meta_keys"`
-	MetaValue              string   `db:"meta_value" json:"meta_value"`
+	Name                   string      `db:"event_name" json:"name"`
+	Visitors               int         `json:"visitors"`
+	Views                  int         `json:"views"`
+	CR                     float64     `json:"cr"`
+	AverageDurationSeconds int         `db:"average_duration_seconds" json:"average_duration_seconds"`
+	MetaKeys               StringArray `db:"meta_keys" json:"meta_keys"`
+	MetaValue              string      `db:"meta_value" json:"meta_value"`
 }
 
 // ReferrerStats is the result type for referrer statistics.
@@ -161,13 +211,28 @@ type ReferrerStats struct {
 }
 
 // PlatformStats is the result type for platform statistics.
+// Tablets are counted as mobile devices and all other device types (like TVs) as unknown for the platform.
 type PlatformStats struct {
-	PlatformDesktop         int     `db:"platform_desktop" json:"platform_desktop"`
-	PlatformMobile          int     `db:"platform_mobile" json:"platform_mobile"`
-	PlatformUnknown         int     `db:"platform_unknown" json:"platform_unknown"`
-	RelativePlatformDesktop float64 `db:"relative_platform_desktop" json:"relative_platform_desktop"`
-	RelativePlatformMobile  float64 `db:"relative_platform_mobile" json:"relative_platform_mobile"`
-	RelativePlatformUnknown float64 `db:"relative_platform_unknown" json:"relative_platform_unknown"`
+	PlatformDesktop         int               `db:"platform_desktop" json:"platform_desktop"`
+	PlatformMobile          int               `db:"platform_mobile" json:"platform_mobile"`
+	PlatformUnknown         int               `db:"platform_unknown" json:"platform_unknown"`
+	RelativePlatformDesktop float64           `db:"relative_platform_desktop" json:"relative_platform_desktop"`
+	RelativePlatformMobile  float64           `db:"relative_platform_mobile" json:"relative_platform_mobile"`
+	RelativePlatformUnknown float64           `db:"relative_platform_unknown" json:"relative_platform_unknown"`
+	DeviceTypes             []DeviceTypeStats `json:"device_types"`
+}
+
+// DeviceTypeStats is the result type for device type statistics.
+type DeviceTypeStats struct {
+	MetaStats
+	DeviceType string `db:"device_type" json:"device_type"`
+}
+
+// BotStats is the result type for bot statistics.
+type BotStats struct {
+	Reason IgnoreReason `db:"bot_reason" json:"reason"`
+	Rule   string       `db:"bot_rule" json:"rule"`
+	Views  int          `json:"views"`
 }
 
 // TimeSpentStats is the result type for average time spent statistics (sessions, time on page).
@@ -190,12 +255,34 @@ type LanguageStats struct {
 	Language string `json:"language"`
 }
 
+// LocaleStats is the result type for locale (language and region) statistics.
+type LocaleStats struct {
+	MetaStats
+	Language       string `json:"language"`
+	LanguageRegion string `db:"language_region" json:"language_region"`
+}
+
 // CountryStats is the result type for country statistics.
 type CountryStats struct {
 	MetaStats
 	CountryCode string `db:"country_code" json:"country_code"`
 }
 
+// RegionStats is the result type for region statistics.
+type RegionStats struct {
+	MetaStats
+	CountryCode string `db:"country_code" json:"country_code"`
+	Region      string `json:"region"`
+}
+
+// CityStats is the result type for city statistics.
+type CityStats struct {
+	MetaStats
+	CountryCode string `db:"country_code" json:"country_code"`
+	Region      string `json:"region"`
+	City        string `json:"city"`
+}
+
 // BrowserStats is the result type for browser statistics.
 type BrowserStats struct {
 	MetaStats
@@ -0,0 +1,19 @@
+package pirsch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStringArray_Scan(t *testing.T) {
+	var array StringArray
+	assert.NoError(t, array.Scan([]string{"a", "b"}))
+	assert.Equal(t, StringArray{"a", "b"}, array)
+	assert.NoError(t, array.Scan([]byte(`["c"]`)))
+	assert.Equal(t, StringArray{"c"}, array)
+	assert.NoError(t, array.Scan(`["d", "e"]`))
+	assert.Equal(t, StringArray{"d", "e"}, array)
+	assert.NoError(t, array.Scan(nil))
+	assert.Nil(t, array)
+	assert.Error(t, array.Scan(42))
+}
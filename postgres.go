@@ -0,0 +1,279 @@
+package pirsch
+
+import (
+	// Postgres driver used by the PostgresClient.
+	_ "github.com/lib/pq"
+
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"log"
+	"os"
+	"time"
+)
+
+// PostgresClient is a Postgres database client.
+// The database schema must be created by calling Migrate with a postgres:// connection string.
+type PostgresClient struct {
+	sqlx.DB
+	logger *log.Logger
+}
+
+// NewPostgresClient returns a new client for given database connection string.
+// The logger is optional.
+func NewPostgresClient(connection string, logger *log.Logger) (*PostgresClient, error) {
+	c, err := sqlx.Open("postgres", connection)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Ping(); err != nil {
+		return nil, err
+	}
+
+	if logger == nil {
+		logger = log.New(os.Stdout, "[pirsch] ", log.LstdFlags)
+	}
+
+	return &PostgresClient{
+		*c,
+		logger,
+	}, nil
+}
+
+// SaveHits implements the Store interface.
+func (client *PostgresClient) SaveHits(hits []Hit) error {
+	tx, err := client.Beginx()
+
+	if err != nil {
+		return err
+	}
+
+	query, err := tx.Prepare(`INSERT INTO "hit" (client_id, fingerprint, "time", "session", previous_time_on_page_seconds,
+		user_agent, path, url, title, language, language_region, country_code, region, city, asn, as_organization, referrer, referrer_name, referrer_icon, os, os_version,
+		browser, browser_version, desktop, mobile, device_type, screen_width, screen_height, screen_class,
+		utm_source, utm_medium, utm_campaign, utm_content, utm_term) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26,$27,$28,$29,$30,$31,$32,$33,$34)`)
+
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			client.logger.Printf("error rolling back transaction to save hits: %s", e)
+		}
+
+		return err
+	}
+
+	for _, hit := range hits {
+		_, err := query.Exec(hit.ClientID,
+			hit.Fingerprint,
+			hit.Time,
+			hit.Session,
+			hit.PreviousTimeOnPageSeconds,
+			hit.UserAgent,
+			hit.Path,
+			hit.URL,
+			hit.Title,
+			hit.Language,
+			hit.LanguageRegion,
+			hit.CountryCode,
+			hit.Region,
+			hit.City,
+			hit.ASN,
+			hit.ASOrganization,
+			hit.Referrer,
+			hit.ReferrerName,
+			hit.ReferrerIcon,
+			hit.OS,
+			hit.OSVersion,
+			hit.Browser,
+			hit.BrowserVersion,
+			hit.Desktop,
+			hit.Mobile,
+			hit.DeviceType,
+			hit.ScreenWidth,
+			hit.ScreenHeight,
+			hit.ScreenClass,
+			hit.UTMSource,
+			hit.UTMMedium,
+			hit.UTMCampaign,
+			hit.UTMContent,
+			hit.UTMTerm)
+
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				client.logger.Printf("error rolling back transaction to save hits: %s", e)
+			}
+
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveEvents implements the Store interface.
+func (client *PostgresClient) SaveEvents(events []Event) error {
+	tx, err := client.Beginx()
+
+	if err != nil {
+		return err
+	}
+
+	query, err := tx.Prepare(`INSERT INTO "event" (client_id, fingerprint, "time", "session", previous_time_on_page_seconds,
+		user_agent, path, url, title, language, language_region, country_code, region, city, asn, as_organization, referrer, referrer_name, referrer_icon, os, os_version,
+		browser, browser_version, desktop, mobile, device_type, screen_width, screen_height, screen_class,
+		utm_source, utm_medium, utm_campaign, utm_content, utm_term,
+		event_name, event_duration_seconds, event_meta_keys, event_meta_values) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26,$27,$28,$29,$30,$31,$32,$33,$34,$35,$36,$37,$38)`)
+
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			client.logger.Printf("error rolling back transaction to save events: %s", e)
+		}
+
+		return err
+	}
+
+	for _, event := range events {
+		_, err := query.Exec(event.ClientID,
+			event.Fingerprint,
+			event.Time,
+			event.Session,
+			event.PreviousTimeOnPageSeconds,
+			event.UserAgent,
+			event.Path,
+			event.URL,
+			event.Title,
+			event.Language,
+			event.LanguageRegion,
+			event.CountryCode,
+			event.Region,
+			event.City,
+			event.ASN,
+			event.ASOrganization,
+			event.Referrer,
+			event.ReferrerName,
+			event.ReferrerIcon,
+			event.OS,
+			event.OSVersion,
+			event.Browser,
+			event.BrowserVersion,
+			event.Desktop,
+			event.Mobile,
+			event.DeviceType,
+			event.ScreenWidth,
+			event.ScreenHeight,
+			event.ScreenClass,
+			event.UTMSource,
+			event.UTMMedium,
+			event.UTMCampaign,
+			event.UTMContent,
+			event.UTMTerm,
+			event.Name,
+			event.DurationSeconds,
+			client.array(event.MetaKeys),
+			client.array(event.MetaValues))
+
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				client.logger.Printf("error rolling back transaction to save events: %s", e)
+			}
+
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveBotHits implements the Store interface.
+func (client *PostgresClient) SaveBotHits(hits []BotHit) error {
+	tx, err := client.Beginx()
+
+	if err != nil {
+		return err
+	}
+
+	query, err := tx.Prepare(`INSERT INTO "bot_hit" (client_id, "time", user_agent, path, url, bot_reason, bot_rule) VALUES ($1,$2,$3,$4,$5,$6,$7)`)
+
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			client.logger.Printf("error rolling back transaction to save bot hits: %s", e)
+		}
+
+		return err
+	}
+
+	for _, hit := range hits {
+		_, err := query.Exec(hit.ClientID,
+			hit.Time,
+			hit.UserAgent,
+			hit.Path,
+			hit.URL,
+			string(hit.Reason),
+			hit.Rule)
+
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				client.logger.Printf("error rolling back transaction to save bot hits: %s", e)
+			}
+
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Session implements the Store interface.
+func (client *PostgresClient) Session(clientID int64, fingerprint string, maxAge time.Time) (Session, error) {
+	query := `SELECT path, "time", "session" FROM "hit" WHERE client_id = $1 AND fingerprint = $2 AND "time" > $3 ORDER BY "time" DESC LIMIT 1`
+	data := struct {
+		Path    string
+		Time    time.Time
+		Session time.Time
+	}{}
+
+	if err := client.DB.Get(&data, query, clientID, fingerprint, maxAge); err != nil && err != sql.ErrNoRows {
+		client.logger.Printf("error reading session: %s", err)
+		return Session{}, err
+	}
+
+	return data, nil
+}
+
+// Query implements the Store interface.
+func (client *PostgresClient) Query(results interface{}, query *Query) error {
+	q, args, err := buildSQLQuery(postgres, query)
+
+	if err != nil {
+		return err
+	}
+
+	if err := client.DB.Select(results, client.Rebind(q), args...); err != nil {
+		client.logger.Printf("error selecting results: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+func (client *PostgresClient) array(values []string) interface{} {
+	if values == nil {
+		// the meta data columns are not nullable
+		values = []string{}
+	}
+
+	return pq.Array(values)
+}
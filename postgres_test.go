@@ -0,0 +1,107 @@
+package pirsch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPostgresClient(t *testing.T) {
+	client := newTestPostgresClient(t)
+	now := time.Now().UTC()
+	assert.NoError(t, client.SaveHits([]Hit{
+		{ClientID: 1, Fingerprint: "fp", Time: now.Add(-time.Second * 20), Session: now.Add(-time.Second * 20), Path: "/path1", Desktop: true},
+		{ClientID: 1, Fingerprint: "fp", Time: now, Session: now, Path: "/path2"},
+		{ClientID: 1, Fingerprint: "fp", Time: now.Add(-time.Second * 10), Session: now.Add(-time.Second * 10), Path: "/path3"},
+	}))
+	assert.NoError(t, client.SaveEvents([]Event{
+		{Name: "event", MetaKeys: []string{"key"}, MetaValues: []string{"value"}, Hit: Hit{ClientID: 1, Fingerprint: "fp", Time: now}},
+		{Name: "event", Hit: Hit{ClientID: 1, Fingerprint: "fp", Time: now}},
+	}))
+	session, err := client.Session(1, "fp", time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, "/path2", session.Path)
+	assert.Equal(t, now.Unix(), session.Time.Unix())
+	assert.Equal(t, now.Unix(), session.Session.Unix())
+	assert.NoError(t, client.SaveBotHits([]BotHit{{ClientID: 1, Time: now, Path: "/path1", Reason: IgnoreBot, Rule: "curl"}}))
+	testQuerySavedData(t, client, now)
+}
+
+func TestPostgresClient_Analyzer(t *testing.T) {
+	client := newTestPostgresClient(t)
+	assert.NoError(t, client.SaveHits([]Hit{
+		{Fingerprint: "fp1", Time: pastDay(2), Path: "/"},
+		{Fingerprint: "fp1", Time: pastDay(2).Add(time.Minute), Path: "/simple/page", PreviousTimeOnPageSeconds: 60},
+		{Fingerprint: "fp2", Time: pastDay(2), Path: "/"},
+		{Fingerprint: "fp3", Time: pastDay(0), Path: "/siMple/page/"},
+	}))
+	assert.NoError(t, client.SaveEvents([]Event{
+		{Name: "event", MetaKeys: []string{"status", "price"}, MetaValues: []string{"in", "34.56"}, Hit: Hit{Fingerprint: "fp1", Time: pastDay(0)}},
+		{Name: "event", MetaKeys: []string{"status"}, MetaValues: []string{"out"}, Hit: Hit{Fingerprint: "fp2", Time: pastDay(0)}},
+	}))
+	analyzer := NewAnalyzer(client)
+	visitors, err := analyzer.Visitors(&Filter{From: pastDay(3), To: pastDay(0)})
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 4)
+	assert.Equal(t, pastDay(3), visitors[0].Day)
+	assert.Equal(t, pastDay(2), visitors[1].Day)
+	assert.Equal(t, 2, visitors[1].Visitors)
+	assert.Equal(t, 3, visitors[1].Views)
+	assert.Equal(t, 1, visitors[1].Bounces)
+	assert.InDelta(t, 0.5, visitors[1].BounceRate, 0.01)
+	assert.Equal(t, 1, visitors[3].Visitors)
+	pages, err := analyzer.Pages(&Filter{PathPattern: "(?i)^/simple/.*"})
+	assert.NoError(t, err)
+	assert.Len(t, pages, 2)
+	timeOnPage, err := analyzer.AvgTimeOnPages(nil)
+	assert.NoError(t, err)
+	assert.Len(t, timeOnPage, 1)
+	assert.Equal(t, "/", timeOnPage[0].Path)
+	assert.Equal(t, 60, timeOnPage[0].AverageTimeSpentSeconds)
+	entries, err := analyzer.EntryPages(nil)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "/", entries[0].Path)
+	assert.Equal(t, 2, entries[0].Entries)
+	events, err := analyzer.Events(nil)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, 2, events[0].Visitors)
+	assert.ElementsMatch(t, []string{"status", "price"}, events[0].MetaKeys)
+	breakdown, err := analyzer.EventBreakdown(&Filter{EventName: "event", EventMetaKey: "status"})
+	assert.NoError(t, err)
+	assert.Len(t, breakdown, 2)
+	assert.Equal(t, "in", breakdown[0].MetaValue)
+	assert.Equal(t, "out", breakdown[1].MetaValue)
+}
+
+func TestMigratePostgres(t *testing.T) {
+	connection := os.Getenv(postgresTestEnv)
+
+	if connection == "" {
+		t.Skipf("set %s to run the Postgres tests", postgresTestEnv)
+	}
+
+	assert.NoError(t, Migrate(connection))
+	assert.NoError(t, Migrate(connection))
+}
+
+func newTestPostgresClient(t *testing.T) *PostgresClient {
+	connection := os.Getenv(postgresTestEnv)
+
+	if connection == "" {
+		t.Skipf("set %s to run the Postgres tests", postgresTestEnv)
+	}
+
+	assert.NoError(t, Migrate(connection))
+	client, err := NewPostgresClient(connection, nil)
+	assert.NoError(t, err)
+	client.MustExec(`DELETE FROM "hit"`)
+	client.MustExec(`DELETE FROM "event"`)
+	client.MustExec(`DELETE FROM "bot_hit"`)
+	t.Cleanup(func() {
+		assert.NoError(t, client.Close())
+	})
+	return client
+}
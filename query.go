@@ -0,0 +1,378 @@
+package pirsch
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrInvalidQuery is returned by the Store if a Query combines dimensions and metrics that cannot be calculated together.
+var ErrInvalidQuery = errors.New("invalid combination of dimensions and metrics")
+
+// Dimension is a field the results of a Query are grouped by.
+// The value is the name of the field in the results.
+type Dimension string
+
+const (
+	// DimensionDay groups the results by the day in Filter.Timezone.
+	DimensionDay = Dimension("day")
+
+	// DimensionHour groups the results by the hour of the day in Filter.Timezone.
+	DimensionHour = Dimension("hour")
+
+	// DimensionPath groups the results by path.
+	DimensionPath = Dimension("path")
+
+	// DimensionTitle groups the results by page title.
+	DimensionTitle = Dimension("title")
+
+	// DimensionReferrer groups the results by referrer.
+	DimensionReferrer = Dimension("referrer")
+
+	// DimensionReferrerName groups the results by referrer name.
+	DimensionReferrerName = Dimension("referrer_name")
+
+	// DimensionReferrerIcon groups the results by referrer icon.
+	DimensionReferrerIcon = Dimension("referrer_icon")
+
+	// DimensionLanguage groups the results by language.
+	DimensionLanguage = Dimension("language")
+
+	// DimensionLanguageRegion groups the results by the region of the language.
+	DimensionLanguageRegion = Dimension("language_region")
+
+	// DimensionCountryCode groups the results by country code.
+	DimensionCountryCode = Dimension("country_code")
+
+	// DimensionRegion groups the results by region.
+	DimensionRegion = Dimension("region")
+
+	// DimensionCity groups the results by city.
+	DimensionCity = Dimension("city")
+
+	// DimensionBrowser groups the results by browser.
+	DimensionBrowser = Dimension("browser")
+
+	// DimensionBrowserVersion groups the results by browser version.
+	DimensionBrowserVersion = Dimension("browser_version")
+
+	// DimensionOS groups the results by operating system.
+	DimensionOS = Dimension("os")
+
+	// DimensionOSVersion groups the results by operating system version.
+	DimensionOSVersion = Dimension("os_version")
+
+	// DimensionDesktop groups the results by whether the visitor used a desktop device.
+	DimensionDesktop = Dimension("desktop")
+
+	// DimensionMobile groups the results by whether the visitor used a mobile device.
+	DimensionMobile = Dimension("mobile")
+
+	// DimensionDeviceType groups the results by device type.
+	DimensionDeviceType = Dimension("device_type")
+
+	// DimensionScreenClass groups the results by screen class.
+	DimensionScreenClass = Dimension("screen_class")
+
+	// DimensionUTMSource groups the results by utm source.
+	DimensionUTMSource = Dimension("utm_source")
+
+	// DimensionUTMMedium groups the results by utm medium.
+	DimensionUTMMedium = Dimension("utm_medium")
+
+	// DimensionUTMCampaign groups the results by utm campaign.
+	DimensionUTMCampaign = Dimension("utm_campaign")
+
+	// DimensionUTMContent groups the results by utm content.
+	DimensionUTMContent = Dimension("utm_content")
+
+	// DimensionUTMTerm groups the results by utm term.
+	DimensionUTMTerm = Dimension("utm_term")
+
+	// DimensionEventName groups the results by event name.
+	DimensionEventName = Dimension("event_name")
+
+	// DimensionEventMetaValue groups the results by the event meta value for Filter.EventMetaKey.
+	// Events without the meta key are excluded.
+	DimensionEventMetaValue = Dimension("meta_value")
+
+	// DimensionBotReason groups bot hits by the reason they have been classified as a bot (see TrackerConfig.RecordBots).
+	// Queries for bot hits only support MetricViews and filtering by time and path.
+	DimensionBotReason = Dimension("bot_reason")
+
+	// DimensionBotRule groups bot hits by the rule that matched (like "Googlebot").
+	DimensionBotRule = Dimension("bot_rule")
+)
+
+// Metric is a value calculated for each group of a Query.
+// Use Metric.Column to get the name of the field in the results.
+type Metric string
+
+const (
+	// MetricVisitors is the number of unique visitors.
+	MetricVisitors = Metric("visitors")
+
+	// MetricSessions is the number of sessions.
+	MetricSessions = Metric("sessions")
+
+	// MetricViews is the number of page views (or events).
+	MetricViews = Metric("views")
+
+	// MetricBounces is the number of visitors who viewed a single page.
+	MetricBounces = Metric("bounces")
+
+	// MetricEntries is the number of visitors who entered the website on a page.
+	// It can only be combined with MetricVisitors and dimensions stored with the hit, like the path.
+	MetricEntries = Metric("entries")
+
+	// MetricExits is the number of visitors who left the website on a page.
+	// It can only be combined with MetricVisitors and dimensions stored with the hit, like the path.
+	MetricExits = Metric("exits")
+
+	// MetricAvgEventDuration is the average event duration in seconds.
+	MetricAvgEventDuration = Metric("average_duration_seconds")
+
+	// MetricEventMetaKeys is the list of unique event meta keys.
+	MetricEventMetaKeys = Metric("meta_keys")
+
+	// MetricAvgSessionDuration is the average session duration in seconds.
+	// It can only be combined with DimensionDay.
+	MetricAvgSessionDuration = Metric("average_session_duration")
+
+	// MetricTotalSessionDuration is the total session duration in seconds.
+	// It cannot be combined with any dimension.
+	MetricTotalSessionDuration = Metric("total_session_duration")
+
+	// MetricAvgTimeOnPage is the average time on page in seconds.
+	// It can only be combined with DimensionDay, DimensionPath, and DimensionTitle.
+	MetricAvgTimeOnPage = Metric("average_time_on_page")
+
+	// MetricTotalTimeOnPage is the total time on page in seconds.
+	// It cannot be combined with any dimension.
+	MetricTotalTimeOnPage = Metric("total_time_on_page")
+)
+
+// Column returns the name of the field in the results of a Query.
+func (metric Metric) Column() string {
+	switch metric {
+	case MetricAvgSessionDuration, MetricAvgTimeOnPage:
+		return "average_time_spent_seconds"
+	case MetricTotalSessionDuration, MetricTotalTimeOnPage:
+		return "total_time_spent_seconds"
+	}
+
+	return string(metric)
+}
+
+// Order sorts the results of a Query by a dimension or a metric.
+// Only the dimension or the metric must be set.
+type Order struct {
+	Dimension Dimension
+	Metric    Metric
+	Desc      bool
+}
+
+// Query describes the statistics the Analyzer reads from the Store.
+// The Store calculates the metrics for the hits (or events, in case the Filter.EventName, an event dimension, or an event metric is set)
+// matching the filter, grouped by the dimensions. Without dimensions, a single result is returned.
+type Query struct {
+	// Filter selects the hits or events. It must be validated.
+	Filter *Filter
+
+	// Dimensions are the fields to group the results by.
+	Dimensions []Dimension
+
+	// Metrics are the values calculated for each group.
+	Metrics []Metric
+
+	// OrderBy sorts the results.
+	OrderBy []Order
+
+	// Limit limits the number of results if greater than 0.
+	Limit int
+}
+
+// table returns the table the statistics are read from.
+func (query *Query) table() string {
+	if query.hasDimension(DimensionBotReason) || query.hasDimension(DimensionBotRule) {
+		return "bot_hit"
+	}
+
+	if query.Filter.EventName != "" ||
+		query.hasDimension(DimensionEventName) ||
+		query.hasDimension(DimensionEventMetaValue) ||
+		query.hasMetric(MetricAvgEventDuration) ||
+		query.hasMetric(MetricEventMetaKeys) {
+		return "event"
+	}
+
+	return "hit"
+}
+
+func (query *Query) hasDimension(dimension Dimension) bool {
+	for _, d := range query.Dimensions {
+		if d == dimension {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (query *Query) hasMetric(metric Metric) bool {
+	for _, m := range query.Metrics {
+		if m == metric {
+			return true
+		}
+	}
+
+	return false
+}
+
+// queryResult is a single result of a Query, mapping the column names to the values.
+type queryResult map[string]interface{}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// scanQueryResults stores the results in a pointer to a slice of structs, like sqlx.Select does.
+// The fields are matched by their db tag or lowercase name, embedded structs are traversed.
+// Values are converted by the type of the field, so that dates returned as strings can be stored in a time.Time, for example.
+func scanQueryResults(dest interface{}, results []queryResult) error {
+	value := reflect.ValueOf(dest)
+
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("results must be a pointer to a slice, got %T", dest)
+	}
+
+	slice := value.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("results must be a pointer to a slice of structs, got %T", dest)
+	}
+
+	fields := make(map[string][]int)
+	queryResultFields(elemType, nil, fields)
+
+	for _, result := range results {
+		elem := reflect.New(elemType)
+
+		for column, v := range result {
+			index, ok := fields[column]
+
+			if !ok {
+				return fmt.Errorf("missing destination name %s in %T", column, dest)
+			}
+
+			if err := setQueryResultField(elem.Elem().FieldByIndex(index), v); err != nil {
+				return fmt.Errorf("cannot store %s: %s", column, err)
+			}
+		}
+
+		if isPtr {
+			slice = reflect.Append(slice, elem)
+		} else {
+			slice = reflect.Append(slice, elem.Elem())
+		}
+	}
+
+	value.Elem().Set(slice)
+	return nil
+}
+
+func queryResultFields(t reflect.Type, index []int, fields map[string][]int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("db")
+		fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+
+		if tag == "-" {
+			continue
+		}
+
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			queryResultFields(field.Type, fieldIndex, fields)
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if tag == "" {
+			tag = strings.ToLower(field.Name)
+		}
+
+		if _, ok := fields[tag]; !ok {
+			fields[tag] = fieldIndex
+		}
+	}
+}
+
+// setQueryResultField stores given value in the field, converting it to the type of the field if required.
+func setQueryResultField(field reflect.Value, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+
+	if field.Addr().Type().Implements(scannerType) {
+		return field.Addr().Interface().(sql.Scanner).Scan(v)
+	}
+
+	value := reflect.ValueOf(v)
+
+	switch {
+	case field.Type() == timeType:
+		if str, ok := v.(string); ok {
+			t, err := parseQueryResultTime(str)
+
+			if err != nil {
+				return err
+			}
+
+			field.Set(reflect.ValueOf(t))
+			return nil
+		}
+	case field.Kind() == reflect.Bool:
+		switch b := v.(type) {
+		case bool:
+			field.SetBool(b)
+			return nil
+		case int64:
+			field.SetBool(b != 0)
+			return nil
+		}
+	case field.Kind() == reflect.String:
+		// don't convert numbers to strings, as reflect would interpret them as runes
+		if value.Kind() != reflect.String && value.Type() != reflect.TypeOf([]byte(nil)) {
+			return fmt.Errorf("cannot convert %T to %s", v, field.Type())
+		}
+	}
+
+	if !value.Type().ConvertibleTo(field.Type()) {
+		return fmt.Errorf("cannot convert %T to %s", v, field.Type())
+	}
+
+	field.Set(value.Convert(field.Type()))
+	return nil
+}
+
+// parseQueryResultTime parses a date or time returned as a string, like SQLite does.
+func parseQueryResultTime(value string) (time.Time, error) {
+	if len(value) == len(sqliteDateFormat) {
+		return time.Parse(sqliteDateFormat, value)
+	}
+
+	return time.Parse(sqliteTimeFormat, value)
+}
@@ -0,0 +1,720 @@
+package pirsch
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// memoryRow is a hit, event, or bot hit the MemoryClient calculates statistics for.
+// Hits and bot hits are stored as an event without name.
+type memoryRow struct {
+	Event
+	reason IgnoreReason
+	rule   string
+}
+
+// memoryQuery evaluates a Query in memory.
+// It calculates the same results as the SQL queries built by the sqlQueryBuilder, so that the MemoryClient agrees with the databases.
+type memoryQuery struct {
+	query   *Query
+	tz      *time.Location
+	pattern *regexp.Regexp
+	negate  bool
+}
+
+// evaluateMemoryQuery returns the results for given Query.
+// The time on page and session duration are calculated from the hits, all other metrics from the rows, which are the hits, events, or bot hits for the table of the Query.
+func evaluateMemoryQuery(query *Query, hits, rows []memoryRow) ([]queryResult, error) {
+	if query == nil || query.Filter == nil || len(query.Metrics) == 0 {
+		return nil, ErrInvalidQuery
+	}
+
+	kind := sqlQueryKind(query)
+
+	if err := validateSQLQuery(query, kind); err != nil {
+		return nil, err
+	}
+
+	q := &memoryQuery{
+		query: query,
+		tz:    query.Filter.Timezone,
+	}
+
+	if q.tz == nil {
+		q.tz = time.UTC
+	}
+
+	if query.Filter.PathPattern != "" {
+		pattern := query.Filter.PathPattern
+
+		if strings.HasPrefix(pattern, "!") {
+			pattern = pattern[1:]
+			q.negate = true
+		}
+
+		regex, err := regexp.Compile(pattern)
+
+		if err != nil {
+			return nil, err
+		}
+
+		q.pattern = regex
+	}
+
+	var results []queryResult
+
+	switch kind {
+	case sqlQueryBounces:
+		results = q.bounces(rows)
+	case sqlQueryEntriesAndExits:
+		results = q.entriesAndExits(rows)
+	case sqlQueryTimeOnPage:
+		results = q.timeOnPage(hits)
+	case sqlQuerySessionDuration:
+		results = q.sessionDuration(hits)
+	default:
+		results = q.flat(rows)
+	}
+
+	q.orderBy(results)
+
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+
+	return results, nil
+}
+
+// flat calculates the metrics that can be calculated without grouping the rows by visitor first.
+func (q *memoryQuery) flat(rows []memoryRow) []queryResult {
+	groups := q.group(q.where(rows))
+	results := make([]queryResult, 0, len(groups))
+
+	for _, group := range groups {
+		result := q.result(group)
+
+		for _, metric := range q.query.Metrics {
+			switch metric {
+			case MetricVisitors:
+				result[string(metric)] = countVisitors(group.rows)
+			case MetricSessions:
+				result[string(metric)] = countSessions(group.rows)
+			case MetricViews:
+				result[string(metric)] = len(group.rows)
+			case MetricAvgEventDuration:
+				sum := 0
+
+				for _, row := range group.rows {
+					sum += row.DurationSeconds
+				}
+
+				result[string(metric)] = avg(sum, len(group.rows))
+			case MetricEventMetaKeys:
+				keys := make([]string, 0)
+				found := make(map[string]bool)
+
+				for _, row := range group.rows {
+					for _, key := range row.MetaKeys {
+						if !found[key] {
+							found[key] = true
+							keys = append(keys, key)
+						}
+					}
+				}
+
+				result[string(metric)] = keys
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// bounces groups the rows by visitor to count visitors who viewed a single page.
+func (q *memoryQuery) bounces(rows []memoryRow) []queryResult {
+	groups := q.group(q.where(rows))
+	results := make([]queryResult, 0, len(groups))
+
+	for _, group := range groups {
+		visitors := make(map[string][]memoryRow)
+		fingerprints := make([]string, 0)
+
+		for _, row := range group.rows {
+			if _, ok := visitors[row.Fingerprint]; !ok {
+				fingerprints = append(fingerprints, row.Fingerprint)
+			}
+
+			visitors[row.Fingerprint] = append(visitors[row.Fingerprint], row)
+		}
+
+		sessions, bounces := 0, 0
+
+		for _, fingerprint := range fingerprints {
+			sessions += countSessions(visitors[fingerprint])
+
+			if len(visitors[fingerprint]) == 1 {
+				bounces++
+			}
+		}
+
+		result := q.result(group)
+
+		for _, metric := range q.query.Metrics {
+			switch metric {
+			case MetricVisitors:
+				result[string(metric)] = len(fingerprints)
+			case MetricSessions:
+				result[string(metric)] = sessions
+			case MetricViews:
+				result[string(metric)] = len(group.rows)
+			case MetricBounces:
+				result[string(metric)] = bounces
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// entriesAndExits compares each page view to the previous and next one of the same visitor.
+// The path filter is applied afterwards, so that the page views for other paths are taken into account.
+func (q *memoryQuery) entriesAndExits(rows []memoryRow) []queryResult {
+	filter := *q.query.Filter
+	filter.Path = ""
+	selected := make([]memoryRow, 0, len(rows))
+
+	for _, row := range rows {
+		if q.matchTime(&filter, &row) && q.matchFields(&filter, &row) {
+			selected = append(selected, row)
+		}
+	}
+
+	sortByVisitor(selected)
+	pathRows := make([]memoryRow, 0, len(selected))
+	prev := make([]string, 0, len(selected))
+	next := make([]string, 0, len(selected))
+
+	for i := range selected {
+		if matchField(q.query.Filter.Path, selected[i].Path) {
+			pathRows = append(pathRows, selected[i])
+			prev = append(prev, "")
+			next = append(next, "")
+
+			if i > 0 {
+				prev[len(prev)-1] = selected[i-1].Fingerprint
+			}
+
+			if i < len(selected)-1 {
+				next[len(next)-1] = selected[i+1].Fingerprint
+			}
+		}
+	}
+
+	groups := q.group(pathRows)
+	results := make([]queryResult, 0, len(groups))
+
+	for _, group := range groups {
+		entries, exits := 0, 0
+
+		for _, i := range group.indices {
+			if prev[i] != pathRows[i].Fingerprint {
+				entries++
+			}
+
+			if next[i] != pathRows[i].Fingerprint {
+				exits++
+			}
+		}
+
+		if (!q.query.hasMetric(MetricEntries) || entries == 0) &&
+			(!q.query.hasMetric(MetricExits) || exits == 0) {
+			continue
+		}
+
+		result := q.result(group)
+
+		for _, metric := range q.query.Metrics {
+			switch metric {
+			case MetricVisitors:
+				result[string(metric)] = countVisitors(group.rows)
+			case MetricEntries:
+				result[string(metric)] = entries
+			case MetricExits:
+				result[string(metric)] = exits
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// timeOnPage calculates the time on page from the time stored with the next page view of the visitor.
+// Only the period is used to select the page views, so that the time on page is also available for the last page view that matches the filter.
+func (q *memoryQuery) timeOnPage(hits []memoryRow) []queryResult {
+	filter := q.query.Filter
+	selected := make([]memoryRow, 0, len(hits))
+
+	for _, hit := range hits {
+		if q.matchTime(filter, &hit) {
+			selected = append(selected, hit)
+		}
+	}
+
+	sortByVisitor(selected)
+	rows := make([]memoryRow, 0, len(selected))
+	timeOnPage := make([]int, 0, len(selected))
+
+	for i := range selected {
+		seconds := 0
+
+		if i < len(selected)-1 {
+			seconds = selected[i+1].PreviousTimeOnPageSeconds
+		}
+
+		if filter.MaxTimeOnPageSeconds > 0 && seconds > filter.MaxTimeOnPageSeconds {
+			seconds = filter.MaxTimeOnPageSeconds
+		}
+
+		if seconds > 0 && q.matchFields(filter, &selected[i]) {
+			rows = append(rows, selected[i])
+			timeOnPage = append(timeOnPage, seconds)
+		}
+	}
+
+	groups := q.group(rows)
+	results := make([]queryResult, 0, len(groups))
+
+	for _, group := range groups {
+		sum := 0
+
+		for _, i := range group.indices {
+			sum += timeOnPage[i]
+		}
+
+		results = append(results, q.timeSpent(group, MetricAvgTimeOnPage, MetricTotalTimeOnPage, sum, len(group.indices)))
+	}
+
+	return results
+}
+
+// sessionDuration calculates the duration of the sessions on each day.
+func (q *memoryQuery) sessionDuration(hits []memoryRow) []queryResult {
+	type session struct {
+		day         time.Time
+		fingerprint string
+		session     time.Time
+	}
+
+	sessions := make(map[session][]memoryRow)
+	keys := make([]session, 0)
+
+	for _, row := range q.where(hits) {
+		if !row.Session.IsZero() {
+			key := session{q.day(row.Time), row.Fingerprint, row.Session.UTC()}
+
+			if _, ok := sessions[key]; !ok {
+				keys = append(keys, key)
+			}
+
+			sessions[key] = append(sessions[key], row)
+		}
+	}
+
+	// represent each session by its first row, so that it can be grouped by day
+	sessionRows := make([]memoryRow, 0, len(keys))
+	durations := make([]int, 0, len(keys))
+
+	for _, key := range keys {
+		start, end := sessions[key][0].Time, sessions[key][0].Time
+
+		for _, row := range sessions[key] {
+			if row.Time.Before(start) {
+				start = row.Time
+			}
+
+			if row.Time.After(end) {
+				end = row.Time
+			}
+		}
+
+		if duration := int(end.Unix() - start.Unix()); duration != 0 {
+			sessionRows = append(sessionRows, sessions[key][0])
+			durations = append(durations, duration)
+		}
+	}
+
+	groups := q.group(sessionRows)
+	results := make([]queryResult, 0, len(groups))
+
+	for _, group := range groups {
+		sum := 0
+
+		for _, i := range group.indices {
+			sum += durations[i]
+		}
+
+		results = append(results, q.timeSpent(group, MetricAvgSessionDuration, MetricTotalSessionDuration, sum, len(group.indices)))
+	}
+
+	return results
+}
+
+func (q *memoryQuery) timeSpent(group *memoryGroup, avgMetric, totalMetric Metric, sum, n int) queryResult {
+	result := q.result(group)
+
+	for _, metric := range q.query.Metrics {
+		if metric == avgMetric {
+			result[metric.Column()] = avg(sum, n)
+		} else if metric == totalMetric {
+			result[metric.Column()] = sum
+		}
+	}
+
+	return result
+}
+
+// memoryGroup are the rows for a combination of dimension values.
+// The indices are the positions of the rows in the grouped slice.
+type memoryGroup struct {
+	values  []interface{}
+	rows    []memoryRow
+	indices []int
+}
+
+// group groups the rows by the dimensions of the Query in order of appearance.
+// Without dimensions, a single group is returned, even if there are no rows.
+func (q *memoryQuery) group(rows []memoryRow) []*memoryGroup {
+	if len(q.query.Dimensions) == 0 {
+		group := &memoryGroup{rows: rows, indices: make([]int, len(rows))}
+
+		for i := range rows {
+			group.indices[i] = i
+		}
+
+		return []*memoryGroup{group}
+	}
+
+	groups := make([]*memoryGroup, 0)
+	keys := make(map[string]*memoryGroup)
+
+	for i := range rows {
+		values := make([]interface{}, len(q.query.Dimensions))
+		var key strings.Builder
+
+		for j, dim := range q.query.Dimensions {
+			values[j] = q.dimension(&rows[i], dim)
+			key.WriteString(fmt.Sprintf("%v\x00", values[j]))
+		}
+
+		group, ok := keys[key.String()]
+
+		if !ok {
+			group = &memoryGroup{values: values}
+			keys[key.String()] = group
+			groups = append(groups, group)
+		}
+
+		group.rows = append(group.rows, rows[i])
+		group.indices = append(group.indices, i)
+	}
+
+	return groups
+}
+
+func (q *memoryQuery) result(group *memoryGroup) queryResult {
+	result := make(queryResult)
+
+	for i, dim := range q.query.Dimensions {
+		result[string(dim)] = group.values[i]
+	}
+
+	return result
+}
+
+// where returns the rows matching the filter of the Query.
+func (q *memoryQuery) where(rows []memoryRow) []memoryRow {
+	filter := q.query.Filter
+	metaValue := q.query.hasDimension(DimensionEventMetaValue)
+	selected := make([]memoryRow, 0, len(rows))
+
+	for _, row := range rows {
+		if q.matchTime(filter, &row) && q.matchFields(filter, &row) &&
+			(!metaValue || row.metaKeyIndex(filter.EventMetaKey) > -1) {
+			selected = append(selected, row)
+		}
+	}
+
+	return selected
+}
+
+// matchTime returns whether the row matches the client and period of the filter (see Filter.queryTime).
+func (q *memoryQuery) matchTime(filter *Filter, row *memoryRow) bool {
+	if row.ClientID != filter.ClientID {
+		return false
+	}
+
+	day := q.day(row.Time)
+	return (filter.From.IsZero() || !day.Before(filter.From)) &&
+		(filter.To.IsZero() || !day.After(filter.To)) &&
+		(filter.Day.IsZero() || day.Equal(filter.Day)) &&
+		(filter.Start.IsZero() || !row.Time.Before(filter.Start))
+}
+
+// matchFields returns whether the row matches the fields of the filter (see Filter.queryFields).
+func (q *memoryQuery) matchFields(filter *Filter, row *memoryRow) bool {
+	fields := []struct {
+		value, filter string
+	}{
+		{row.Path, filter.Path},
+		{row.Language, filter.Language},
+		{row.LanguageRegion, filter.LanguageRegion},
+		{row.CountryCode, filter.Country},
+		{row.Region, filter.Region},
+		{row.City, filter.City},
+		{row.Referrer, filter.Referrer},
+		{row.OS, filter.OS},
+		{row.OSVersion, filter.OSVersion},
+		{row.Browser, filter.Browser},
+		{row.BrowserVersion, filter.BrowserVersion},
+		{row.DeviceType, filter.DeviceType},
+		{row.ScreenClass, filter.ScreenClass},
+		{row.UTMSource, filter.UTMSource},
+		{row.UTMMedium, filter.UTMMedium},
+		{row.UTMCampaign, filter.UTMCampaign},
+		{row.UTMContent, filter.UTMContent},
+		{row.UTMTerm, filter.UTMTerm},
+		{row.Name, filter.EventName},
+	}
+
+	for _, field := range fields {
+		if !matchField(field.filter, field.value) {
+			return false
+		}
+	}
+
+	if filter.Platform != "" {
+		platform := strings.TrimPrefix(filter.Platform, "!")
+		var match bool
+
+		if platform == PlatformDesktop {
+			match = row.Desktop
+		} else if platform == PlatformMobile {
+			match = row.Mobile
+		} else {
+			match = !row.Desktop && !row.Mobile
+		}
+
+		if match == strings.HasPrefix(filter.Platform, "!") {
+			return false
+		}
+	}
+
+	if filter.PathPattern != "" && q.pattern != nil && q.pattern.MatchString(row.Path) == q.negate {
+		return false
+	}
+
+	return true
+}
+
+// dimension returns the value of the dimension for the row.
+func (q *memoryQuery) dimension(row *memoryRow, dim Dimension) interface{} {
+	switch dim {
+	case DimensionDay:
+		return q.day(row.Time)
+	case DimensionHour:
+		return row.Time.In(q.tz).Hour()
+	case DimensionPath:
+		return row.Path
+	case DimensionTitle:
+		return row.Title
+	case DimensionReferrer:
+		return row.Referrer
+	case DimensionReferrerName:
+		return row.ReferrerName
+	case DimensionReferrerIcon:
+		return row.ReferrerIcon
+	case DimensionLanguage:
+		return row.Language
+	case DimensionLanguageRegion:
+		return row.LanguageRegion
+	case DimensionCountryCode:
+		return row.CountryCode
+	case DimensionRegion:
+		return row.Region
+	case DimensionCity:
+		return row.City
+	case DimensionBrowser:
+		return row.Browser
+	case DimensionBrowserVersion:
+		return row.BrowserVersion
+	case DimensionOS:
+		return row.OS
+	case DimensionOSVersion:
+		return row.OSVersion
+	case DimensionDesktop:
+		return row.Desktop
+	case DimensionMobile:
+		return row.Mobile
+	case DimensionDeviceType:
+		return row.DeviceType
+	case DimensionScreenClass:
+		return row.ScreenClass
+	case DimensionUTMSource:
+		return row.UTMSource
+	case DimensionUTMMedium:
+		return row.UTMMedium
+	case DimensionUTMCampaign:
+		return row.UTMCampaign
+	case DimensionUTMContent:
+		return row.UTMContent
+	case DimensionUTMTerm:
+		return row.UTMTerm
+	case DimensionEventName:
+		return row.Name
+	case DimensionEventMetaValue:
+		if i := row.metaKeyIndex(q.query.Filter.EventMetaKey); i > -1 && i < len(row.MetaValues) {
+			return row.MetaValues[i]
+		}
+
+		return ""
+	case DimensionBotReason:
+		return string(row.reason)
+	case DimensionBotRule:
+		return row.rule
+	}
+
+	return nil
+}
+
+// day returns the date of given time in the timezone of the filter.
+func (q *memoryQuery) day(t time.Time) time.Time {
+	t = t.In(q.tz)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func (q *memoryQuery) orderBy(results []queryResult) {
+	if len(q.query.OrderBy) == 0 {
+		return
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		for _, order := range q.query.OrderBy {
+			column := string(order.Dimension)
+
+			if order.Metric != "" {
+				column = order.Metric.Column()
+			}
+
+			if c := compareMemoryValues(results[i][column], results[j][column]); c != 0 {
+				return (c < 0) != order.Desc
+			}
+		}
+
+		return false
+	})
+}
+
+func (row *memoryRow) metaKeyIndex(key string) int {
+	for i, k := range row.MetaKeys {
+		if k == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// matchField returns whether the value matches a field of the filter, which can be inverted by a "!" in front of it.
+func matchField(filter, value string) bool {
+	if filter == "" {
+		return true
+	}
+
+	if strings.HasPrefix(filter, "!") {
+		return value != filter[1:]
+	}
+
+	return value == filter
+}
+
+// sortByVisitor sorts the rows by fingerprint and time.
+func sortByVisitor(rows []memoryRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Fingerprint != rows[j].Fingerprint {
+			return rows[i].Fingerprint < rows[j].Fingerprint
+		}
+
+		return rows[i].Time.Before(rows[j].Time)
+	})
+}
+
+func countVisitors(rows []memoryRow) int {
+	visitors := make(map[string]bool)
+
+	for _, row := range rows {
+		visitors[row.Fingerprint] = true
+	}
+
+	return len(visitors)
+}
+
+func countSessions(rows []memoryRow) int {
+	type session struct {
+		fingerprint string
+		session     time.Time
+	}
+
+	sessions := make(map[session]bool)
+
+	for _, row := range rows {
+		sessions[session{row.Fingerprint, row.Session.UTC()}] = true
+	}
+
+	return len(sessions)
+}
+
+// avg returns the average truncated to an integer, like the databases do, or 0 if n is 0.
+func avg(sum, n int) int {
+	if n == 0 {
+		return 0
+	}
+
+	return sum / n
+}
+
+func compareMemoryValues(a, b interface{}) int {
+	switch x := a.(type) {
+	case int:
+		y, _ := b.(int)
+		return x - y
+	case string:
+		return strings.Compare(x, b.(string))
+	case bool:
+		if y, _ := b.(bool); x == y {
+			return 0
+		} else if x {
+			return 1
+		}
+
+		return -1
+	case time.Time:
+		y, _ := b.(time.Time)
+
+		if x.Before(y) {
+			return -1
+		} else if x.After(y) {
+			return 1
+		}
+	}
+
+	return 0
+}
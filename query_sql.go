@@ -0,0 +1,426 @@
+package pirsch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sqlColumnDimensions are the dimensions stored as a column in the database.
+var sqlColumnDimensions = map[Dimension]bool{
+	DimensionPath:           true,
+	DimensionTitle:          true,
+	DimensionReferrer:       true,
+	DimensionReferrerName:   true,
+	DimensionReferrerIcon:   true,
+	DimensionLanguage:       true,
+	DimensionLanguageRegion: true,
+	DimensionCountryCode:    true,
+	DimensionRegion:         true,
+	DimensionCity:           true,
+	DimensionBrowser:        true,
+	DimensionBrowserVersion: true,
+	DimensionOS:             true,
+	DimensionOSVersion:      true,
+	DimensionDesktop:        true,
+	DimensionMobile:         true,
+	DimensionDeviceType:     true,
+	DimensionScreenClass:    true,
+	DimensionUTMSource:      true,
+	DimensionUTMMedium:      true,
+	DimensionUTMCampaign:    true,
+	DimensionUTMContent:     true,
+	DimensionUTMTerm:        true,
+	DimensionEventName:      true,
+	DimensionBotReason:      true,
+	DimensionBotRule:        true,
+}
+
+// sqlBotHitDimensions are the dimensions that can be used to query bot hits.
+var sqlBotHitDimensions = map[Dimension]bool{
+	DimensionDay:       true,
+	DimensionHour:      true,
+	DimensionPath:      true,
+	DimensionBotReason: true,
+	DimensionBotRule:   true,
+}
+
+const (
+	sqlQueryFlat = iota
+	sqlQueryBounces
+	sqlQueryEntriesAndExits
+	sqlQueryTimeOnPage
+	sqlQuerySessionDuration
+)
+
+// sqlQueryMetrics are the metrics that can be calculated together by each kind of SQL query.
+var sqlQueryMetrics = map[int]map[Metric]bool{
+	sqlQueryFlat:            {MetricVisitors: true, MetricSessions: true, MetricViews: true, MetricAvgEventDuration: true, MetricEventMetaKeys: true},
+	sqlQueryBounces:         {MetricVisitors: true, MetricSessions: true, MetricViews: true, MetricBounces: true},
+	sqlQueryEntriesAndExits: {MetricVisitors: true, MetricEntries: true, MetricExits: true},
+	sqlQueryTimeOnPage:      {MetricAvgTimeOnPage: true, MetricTotalTimeOnPage: true},
+	sqlQuerySessionDuration: {MetricAvgSessionDuration: true, MetricTotalSessionDuration: true},
+}
+
+// sqlQueryBuilder compiles a Query to SQL for a dialect.
+// The SQL is written sequentially, so that the arguments are in the same order as the placeholders.
+type sqlQueryBuilder struct {
+	d     dialect
+	query *Query
+	sql   strings.Builder
+	args  []interface{}
+}
+
+// buildSQLQuery returns the SQL query and arguments for given Query.
+// The query uses ? as placeholders.
+func buildSQLQuery(d dialect, query *Query) (string, []interface{}, error) {
+	if query == nil || query.Filter == nil || len(query.Metrics) == 0 {
+		return "", nil, ErrInvalidQuery
+	}
+
+	kind := sqlQueryKind(query)
+
+	if err := validateSQLQuery(query, kind); err != nil {
+		return "", nil, err
+	}
+
+	b := &sqlQueryBuilder{
+		d:     d,
+		query: query,
+		args:  make([]interface{}, 0, 16),
+	}
+
+	switch kind {
+	case sqlQueryBounces:
+		b.bounces()
+	case sqlQueryEntriesAndExits:
+		b.entriesAndExits()
+	case sqlQueryTimeOnPage:
+		b.timeOnPage()
+	case sqlQuerySessionDuration:
+		b.sessionDuration()
+	default:
+		b.flat()
+	}
+
+	b.orderBy()
+
+	if query.Limit > 0 {
+		b.write(fmt.Sprintf("LIMIT %d ", query.Limit))
+	}
+
+	return b.sql.String(), b.args, nil
+}
+
+func sqlQueryKind(query *Query) int {
+	switch {
+	case query.hasMetric(MetricEntries) || query.hasMetric(MetricExits):
+		return sqlQueryEntriesAndExits
+	case query.hasMetric(MetricAvgTimeOnPage) || query.hasMetric(MetricTotalTimeOnPage):
+		return sqlQueryTimeOnPage
+	case query.hasMetric(MetricAvgSessionDuration) || query.hasMetric(MetricTotalSessionDuration):
+		return sqlQuerySessionDuration
+	case query.hasMetric(MetricBounces):
+		return sqlQueryBounces
+	}
+
+	return sqlQueryFlat
+}
+
+func validateSQLQuery(query *Query, kind int) error {
+	// bot hits are stored without fingerprint and sessions, so only page views can be counted
+	if query.table() == "bot_hit" {
+		for _, metric := range query.Metrics {
+			if metric != MetricViews {
+				return ErrInvalidQuery
+			}
+		}
+
+		for _, dim := range query.Dimensions {
+			if !sqlBotHitDimensions[dim] {
+				return ErrInvalidQuery
+			}
+		}
+	}
+
+	for _, metric := range query.Metrics {
+		if !sqlQueryMetrics[kind][metric] {
+			return ErrInvalidQuery
+		}
+	}
+
+	// the time on page and session duration are calculated from page views, which cannot be filtered by event
+	if (kind == sqlQueryTimeOnPage || kind == sqlQuerySessionDuration) && query.Filter.EventName != "" {
+		return ErrInvalidQuery
+	}
+
+	for _, dim := range query.Dimensions {
+		column := sqlColumnDimensions[dim]
+
+		if !column && dim != DimensionDay && dim != DimensionHour && dim != DimensionEventMetaValue {
+			return ErrInvalidQuery
+		}
+
+		// entries and exits are calculated from the order of page views, which can only be grouped by columns
+		if (kind == sqlQueryEntriesAndExits && !column) ||
+			(kind == sqlQueryTimeOnPage && dim != DimensionDay && dim != DimensionPath && dim != DimensionTitle) ||
+			(kind == sqlQuerySessionDuration && dim != DimensionDay) {
+			return ErrInvalidQuery
+		}
+	}
+
+	for _, order := range query.OrderBy {
+		if (order.Dimension == "") == (order.Metric == "") ||
+			(order.Dimension != "" && !query.hasDimension(order.Dimension)) ||
+			(order.Metric != "" && !query.hasMetric(order.Metric)) {
+			return ErrInvalidQuery
+		}
+	}
+
+	return nil
+}
+
+// flat selects the metrics that can be calculated without grouping the hits by visitor first.
+func (b *sqlQueryBuilder) flat() {
+	b.write("SELECT ")
+	b.selectDimensions()
+
+	for i, metric := range b.query.Metrics {
+		if i > 0 {
+			b.write(", ")
+		}
+
+		switch metric {
+		case MetricVisitors:
+			b.write("count(DISTINCT fingerprint) visitors")
+		case MetricSessions:
+			b.write(b.d.countDistinct("fingerprint", `"session"`) + " sessions")
+		case MetricViews:
+			b.write("count(*) views")
+		case MetricAvgEventDuration:
+			b.write(b.d.toInt("avg(event_duration_seconds)") + " average_duration_seconds")
+		case MetricEventMetaKeys:
+			b.write(b.d.groupUniqArray("event_meta_keys") + " meta_keys")
+		}
+	}
+
+	b.write(" FROM " + b.query.table() + " ")
+	b.where()
+	b.groupBy()
+}
+
+// bounces groups the hits by visitor to count visitors who viewed a single page.
+func (b *sqlQueryBuilder) bounces() {
+	b.write("SELECT ")
+	b.dimensionNames()
+
+	for i, metric := range b.query.Metrics {
+		if i > 0 {
+			b.write(", ")
+		}
+
+		if metric == MetricBounces {
+			b.write(b.d.countIf("bounce") + " bounces")
+		} else {
+			b.write(fmt.Sprintf("coalesce(sum(%s), 0) %s", metric, metric))
+		}
+	}
+
+	b.write(" FROM (SELECT ")
+	b.selectDimensions()
+	b.write(fmt.Sprintf(`count(DISTINCT fingerprint) visitors, %s sessions, count(*) views, count(*) = 1 bounce FROM %s `,
+		b.d.countDistinct("fingerprint", `"session"`), b.query.table()))
+	b.where()
+	b.write("GROUP BY ")
+	b.dimensionNames()
+	b.write("fingerprint) t ")
+	b.groupBy()
+}
+
+// entriesAndExits compares each page view to the previous and next one of the same visitor.
+// The path filter is applied afterwards, so that the page views for other paths are taken into account.
+func (b *sqlQueryBuilder) entriesAndExits() {
+	filter := *b.query.Filter
+	pathFilter := Filter{Path: filter.Path}
+	filter.Path = ""
+	columns := `fingerprint, "time", "path"`
+
+	for _, dim := range b.query.Dimensions {
+		if dim != DimensionPath {
+			columns += `, "` + string(dim) + `"`
+		}
+	}
+
+	b.write("SELECT * FROM (SELECT ")
+	b.dimensionNames()
+
+	for i, metric := range b.query.Metrics {
+		if i > 0 {
+			b.write(", ")
+		}
+
+		switch metric {
+		case MetricVisitors:
+			b.write("count(DISTINCT fingerprint) visitors")
+		case MetricEntries:
+			b.write(b.d.countIf("prev_fingerprint != fingerprint") + " entries")
+		case MetricExits:
+			b.write(b.d.countIf("next_fingerprint != fingerprint") + " exits")
+		}
+	}
+
+	b.write(fmt.Sprintf(` FROM (SELECT *, %s prev_fingerprint, %s next_fingerprint FROM (SELECT %s FROM %s `,
+		b.d.neighbor("fingerprint", -1, "''"), b.d.neighbor("fingerprint", 1, "''"), columns, b.query.table()))
+	args, filterQuery := filter.query(b.d)
+	b.writeCondition("WHERE ", filterQuery, args)
+	b.write(`ORDER BY fingerprint, "time") t) t `)
+
+	if args, pathQuery := pathFilter.queryFields(b.d); pathQuery != "" {
+		b.writeCondition("WHERE ", pathQuery, args)
+	}
+
+	b.groupBy()
+	b.write(") t ")
+
+	// pages are included if they are an entry or exit page for any of the requested metrics
+	if b.query.hasMetric(MetricEntries) && b.query.hasMetric(MetricExits) {
+		b.write("WHERE entries > 0 OR exits > 0 ")
+	} else if b.query.hasMetric(MetricEntries) {
+		b.write("WHERE entries > 0 ")
+	} else {
+		b.write("WHERE exits > 0 ")
+	}
+}
+
+// timeOnPage calculates the time on page from the time stored with the next page view of the visitor.
+// Only the period is used to select the page views, so that the time on page is also available for the last page view that matches the filter.
+func (b *sqlQueryBuilder) timeOnPage() {
+	filter := b.query.Filter
+	timeOnPage := b.d.neighbor("previous_time_on_page_seconds", 1, "0")
+
+	if filter.MaxTimeOnPageSeconds > 0 {
+		timeOnPage = b.d.least(timeOnPage, strconv.Itoa(filter.MaxTimeOnPageSeconds))
+	}
+
+	b.write("SELECT ")
+	b.dimensionNames()
+	b.timeSpentMetrics(MetricAvgTimeOnPage, MetricTotalTimeOnPage, "time_on_page")
+	b.write(" FROM (SELECT ")
+	b.selectDimensions()
+	b.write(fmt.Sprintf("time_on_page FROM (SELECT *, %s time_on_page FROM (SELECT * FROM hit ", timeOnPage))
+	args, timeQuery := filter.queryTime(b.d)
+	b.writeCondition("WHERE ", timeQuery, args)
+	b.write(`ORDER BY fingerprint, "time") t) t WHERE time_on_page > 0 `)
+
+	if args, fieldQuery := filter.queryFields(b.d); fieldQuery != "" {
+		b.writeCondition("AND ", fieldQuery, args)
+	}
+
+	b.write(") t ")
+	b.groupBy()
+}
+
+// sessionDuration calculates the duration of the sessions on each day.
+func (b *sqlQueryBuilder) sessionDuration() {
+	b.write("SELECT ")
+	b.dimensionNames()
+	b.timeSpentMetrics(MetricAvgSessionDuration, MetricTotalSessionDuration, "duration")
+	b.write(fmt.Sprintf(` FROM (SELECT %s "day", %s duration FROM hit `,
+		b.d.date(`"time"`), b.d.timeDiff(`max("time")`, `min("time")`)), b.d.timezone(b.query.Filter.Timezone))
+	args, filterQuery := b.query.Filter.query(b.d)
+	b.writeCondition("WHERE ", filterQuery, args)
+	b.write(fmt.Sprintf(`AND "session" != %s GROUP BY "day", fingerprint, "session") t WHERE duration != 0 `, b.d.zeroTime()))
+	b.groupBy()
+}
+
+func (b *sqlQueryBuilder) timeSpentMetrics(avg, total Metric, column string) {
+	for i, metric := range b.query.Metrics {
+		if i > 0 {
+			b.write(", ")
+		}
+
+		if metric == avg {
+			b.write(fmt.Sprintf("%s %s", b.d.toInt(fmt.Sprintf("avg(%s)", column)), metric.Column()))
+		} else if metric == total {
+			b.write(fmt.Sprintf("coalesce(sum(%s), 0) %s", column, metric.Column()))
+		}
+	}
+}
+
+// selectDimensions writes the expressions for the dimensions followed by a comma.
+func (b *sqlQueryBuilder) selectDimensions() {
+	filter := b.query.Filter
+
+	for _, dim := range b.query.Dimensions {
+		switch dim {
+		case DimensionDay:
+			b.write(b.d.date(`"time"`)+` "day", `, b.d.timezone(filter.Timezone))
+		case DimensionHour:
+			b.write(b.d.hour(`"time"`)+` "hour", `, b.d.timezone(filter.Timezone))
+		case DimensionEventMetaValue:
+			b.write(b.d.arrayValue("event_meta_values", "event_meta_keys")+` "meta_value", `, filter.EventMetaKey)
+		default:
+			b.write(`"` + string(dim) + `", `)
+		}
+	}
+}
+
+// dimensionNames writes the names of the dimensions followed by a comma.
+func (b *sqlQueryBuilder) dimensionNames() {
+	for _, dim := range b.query.Dimensions {
+		b.write(`"` + string(dim) + `", `)
+	}
+}
+
+func (b *sqlQueryBuilder) where() {
+	args, filterQuery := b.query.Filter.query(b.d)
+	b.writeCondition("WHERE ", filterQuery, args)
+
+	if b.query.hasDimension(DimensionEventMetaValue) {
+		b.write("AND "+b.d.arrayHas("event_meta_keys")+" ", b.query.Filter.EventMetaKey)
+	}
+}
+
+func (b *sqlQueryBuilder) groupBy() {
+	if len(b.query.Dimensions) > 0 {
+		names := make([]string, 0, len(b.query.Dimensions))
+
+		for _, dim := range b.query.Dimensions {
+			names = append(names, `"`+string(dim)+`"`)
+		}
+
+		b.write("GROUP BY " + strings.Join(names, ", ") + " ")
+	}
+}
+
+func (b *sqlQueryBuilder) orderBy() {
+	if len(b.query.OrderBy) > 0 {
+		order := make([]string, 0, len(b.query.OrderBy))
+
+		for _, o := range b.query.OrderBy {
+			column := string(o.Dimension)
+
+			if o.Metric != "" {
+				column = o.Metric.Column()
+			}
+
+			if o.Desc {
+				order = append(order, `"`+column+`" DESC`)
+			} else {
+				order = append(order, `"`+column+`" ASC`)
+			}
+		}
+
+		b.write("ORDER BY " + strings.Join(order, ", ") + " ")
+	}
+}
+
+// writeCondition writes the conditions of a filter with given prefix (WHERE or AND).
+func (b *sqlQueryBuilder) writeCondition(prefix, cond string, args []interface{}) {
+	b.write(prefix+strings.TrimSpace(cond)+" ", args...)
+}
+
+func (b *sqlQueryBuilder) write(sql string, args ...interface{}) {
+	b.sql.WriteString(sql)
+	b.args = append(b.args, args...)
+}
@@ -0,0 +1,118 @@
+package pirsch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildSQLQuery(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	filter := NewFilter(NullClient)
+	filter.Timezone = tz
+	filter.Day = pastDay(1)
+	filter.EventName = "event"
+	filter.EventMetaKey = "key"
+	query, args, err := buildSQLQuery(clickHouse, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionDay, DimensionEventMetaValue},
+		Metrics:    []Metric{MetricVisitors, MetricViews},
+		OrderBy:    []Order{{Metric: MetricVisitors, Desc: true}, {Dimension: DimensionDay}},
+		Limit:      10,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT toDate("time", ?) "day", event_meta_values[indexOf(event_meta_keys, ?)] "meta_value", count(DISTINCT fingerprint) visitors, count(*) views `+
+		`FROM event WHERE client_id = ? AND toDate(time, ?) = toDate(?) AND event_name = ? AND has(event_meta_keys, ?) `+
+		`GROUP BY "day", "meta_value" ORDER BY "visitors" DESC, "day" ASC LIMIT 10 `, query)
+	assert.Equal(t, []interface{}{"Europe/Berlin", "key", NullClient, "Europe/Berlin", filter.Day, "event", "key"}, args)
+}
+
+func TestBuildSQLQuery_Bounces(t *testing.T) {
+	filter := NewFilter(NullClient)
+	filter.validate()
+	query, args, err := buildSQLQuery(clickHouse, &Query{
+		Filter:  filter,
+		Metrics: []Metric{MetricVisitors, MetricBounces},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT coalesce(sum(visitors), 0) visitors, countIf(bounce) bounces `+
+		`FROM (SELECT count(DISTINCT fingerprint) visitors, count(DISTINCT(fingerprint, "session")) sessions, count(*) views, count(*) = 1 bounce `+
+		`FROM hit WHERE client_id = ? GROUP BY fingerprint) t `, query)
+	assert.Equal(t, []interface{}{NullClient}, args)
+}
+
+func TestBuildSQLQuery_EntriesAndExits(t *testing.T) {
+	filter := NewFilter(NullClient)
+	filter.Path = "/"
+	filter.validate()
+	query, args, err := buildSQLQuery(clickHouse, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionPath},
+		Metrics:    []Metric{MetricVisitors, MetricEntries},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM (SELECT "path", count(DISTINCT fingerprint) visitors, countIf(prev_fingerprint != fingerprint) entries `+
+		`FROM (SELECT *, neighbor(fingerprint, -1, '') prev_fingerprint, neighbor(fingerprint, 1, '') next_fingerprint `+
+		`FROM (SELECT fingerprint, "time", "path" FROM hit WHERE client_id = ? ORDER BY fingerprint, "time") t) t `+
+		`WHERE path = ? GROUP BY "path" ) t WHERE entries > 0 `, query)
+	assert.Equal(t, []interface{}{NullClient, "/"}, args)
+}
+
+func TestBuildSQLQuery_EntriesOrExits(t *testing.T) {
+	filter := NewFilter(NullClient)
+	filter.validate()
+	query, _, err := buildSQLQuery(clickHouse, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionPath},
+		Metrics:    []Metric{MetricEntries, MetricExits},
+	})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(query, `GROUP BY "path" ) t WHERE entries > 0 OR exits > 0 `), query)
+}
+
+func TestBuildSQLQuery_Bots(t *testing.T) {
+	filter := NewFilter(NullClient)
+	filter.validate()
+	query, args, err := buildSQLQuery(clickHouse, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionBotReason, DimensionBotRule},
+		Metrics:    []Metric{MetricViews},
+		OrderBy:    []Order{{Metric: MetricViews, Desc: true}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT "bot_reason", "bot_rule", count(*) views FROM bot_hit WHERE client_id = ? `+
+		`GROUP BY "bot_reason", "bot_rule" ORDER BY "views" DESC `, query)
+	assert.Equal(t, []interface{}{NullClient}, args)
+}
+
+func TestBuildSQLQuery_Invalid(t *testing.T) {
+	filter := NewFilter(NullClient)
+	filter.validate()
+	eventFilter := &Filter{EventName: "event"}
+	eventFilter.validate()
+	queries := []*Query{
+		nil,
+		{Metrics: []Metric{MetricVisitors}},
+		{Filter: filter},
+		{Filter: filter, Metrics: []Metric{Metric("unknown")}},
+		{Filter: filter, Dimensions: []Dimension{Dimension("unknown")}, Metrics: []Metric{MetricVisitors}},
+		{Filter: filter, Metrics: []Metric{MetricBounces, MetricEventMetaKeys}},
+		{Filter: filter, Metrics: []Metric{MetricVisitors, MetricAvgTimeOnPage}},
+		{Filter: filter, Dimensions: []Dimension{DimensionDay}, Metrics: []Metric{MetricEntries}},
+		{Filter: filter, Dimensions: []Dimension{DimensionPath}, Metrics: []Metric{MetricAvgSessionDuration}},
+		{Filter: filter, Dimensions: []Dimension{DimensionLanguage}, Metrics: []Metric{MetricTotalTimeOnPage}},
+		{Filter: eventFilter, Metrics: []Metric{MetricTotalTimeOnPage}},
+		{Filter: eventFilter, Dimensions: []Dimension{DimensionDay}, Metrics: []Metric{MetricAvgSessionDuration}},
+		{Filter: filter, Metrics: []Metric{MetricVisitors}, OrderBy: []Order{{Metric: MetricViews}}},
+		{Filter: filter, Metrics: []Metric{MetricVisitors}, OrderBy: []Order{{Dimension: DimensionPath}}},
+		{Filter: filter, Metrics: []Metric{MetricVisitors}, OrderBy: []Order{{}}},
+		{Filter: filter, Dimensions: []Dimension{DimensionBotReason}, Metrics: []Metric{MetricVisitors}},
+		{Filter: filter, Dimensions: []Dimension{DimensionBotRule, DimensionLanguage}, Metrics: []Metric{MetricViews}},
+	}
+
+	for _, query := range queries {
+		_, _, err := buildSQLQuery(clickHouse, query)
+		assert.Equal(t, ErrInvalidQuery, err)
+	}
+}
@@ -0,0 +1,100 @@
+package pirsch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMetric_Column(t *testing.T) {
+	assert.Equal(t, "visitors", MetricVisitors.Column())
+	assert.Equal(t, "average_time_spent_seconds", MetricAvgSessionDuration.Column())
+	assert.Equal(t, "average_time_spent_seconds", MetricAvgTimeOnPage.Column())
+	assert.Equal(t, "total_time_spent_seconds", MetricTotalSessionDuration.Column())
+	assert.Equal(t, "total_time_spent_seconds", MetricTotalTimeOnPage.Column())
+}
+
+func TestQuery_Table(t *testing.T) {
+	query := &Query{Filter: NewFilter(NullClient), Metrics: []Metric{MetricVisitors}}
+	assert.Equal(t, "hit", query.table())
+	query.Filter.EventName = "event"
+	assert.Equal(t, "event", query.table())
+	query.Filter.EventName = ""
+	query.Dimensions = []Dimension{DimensionEventName}
+	assert.Equal(t, "event", query.table())
+	query.Dimensions = nil
+	query.Metrics = []Metric{MetricVisitors, MetricEventMetaKeys}
+	assert.Equal(t, "event", query.table())
+}
+
+func TestQuery_EntriesAndExits(t *testing.T) {
+	cleanupDB()
+	testQueryEntriesAndExits(t, dbClient)
+}
+
+// testQueryEntriesAndExits checks that pages being only an entry or exit page are returned if both metrics are requested.
+func testQueryEntriesAndExits(t *testing.T, store Store) {
+	assert.NoError(t, store.SaveHits([]Hit{
+		{Fingerprint: "fp1", Time: pastDay(1), Path: "/"},
+		{Fingerprint: "fp1", Time: pastDay(1).Add(time.Minute), Path: "/a"},
+		{Fingerprint: "fp2", Time: pastDay(1), Path: "/a"},
+		{Fingerprint: "fp2", Time: pastDay(1).Add(time.Minute), Path: "/b"},
+	}))
+	filter := NewFilter(NullClient)
+	filter.validate()
+	var results []struct {
+		Path    string
+		Entries int
+		Exits   int
+	}
+	assert.NoError(t, store.Query(&results, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionPath},
+		Metrics:    []Metric{MetricEntries, MetricExits},
+		OrderBy:    []Order{{Dimension: DimensionPath}},
+	}))
+	assert.Len(t, results, 3)
+	assert.Equal(t, "/", results[0].Path)
+	assert.Equal(t, 1, results[0].Entries)
+	assert.Equal(t, 0, results[0].Exits)
+	assert.Equal(t, "/a", results[1].Path)
+	assert.Equal(t, 1, results[1].Entries)
+	assert.Equal(t, 1, results[1].Exits)
+	assert.Equal(t, "/b", results[2].Path)
+	assert.Equal(t, 0, results[2].Entries)
+	assert.Equal(t, 1, results[2].Exits)
+}
+
+// testQuerySavedData checks the hits, events, and bot hits saved by the database client tests using the Query API.
+func testQuerySavedData(t *testing.T, store Store, now time.Time) {
+	filter := &Filter{ClientID: 1, Start: now.Add(-time.Second * 15)}
+	filter.validate()
+	var views []struct {
+		Views int
+	}
+	assert.NoError(t, store.Query(&views, &Query{Filter: filter, Metrics: []Metric{MetricViews}}))
+	assert.Len(t, views, 1)
+	assert.Equal(t, 2, views[0].Views)
+	filter = &Filter{ClientID: 1}
+	filter.validate()
+	var events []EventStats
+	assert.NoError(t, store.Query(&events, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionEventName},
+		Metrics:    []Metric{MetricViews, MetricEventMetaKeys},
+	}))
+	assert.Len(t, events, 1)
+	assert.Equal(t, "event", events[0].Name)
+	assert.Equal(t, 2, events[0].Views)
+	assert.Equal(t, StringArray{"key"}, events[0].MetaKeys)
+	var bots []BotStats
+	assert.NoError(t, store.Query(&bots, &Query{
+		Filter:     filter,
+		Dimensions: []Dimension{DimensionBotReason, DimensionBotRule},
+		Metrics:    []Metric{MetricViews},
+	}))
+	assert.Len(t, bots, 1)
+	assert.Equal(t, IgnoreBot, bots[0].Reason)
+	assert.Equal(t, "curl", bots[0].Rule)
+	assert.Equal(t, 1, bots[0].Views)
+}
@@ -0,0 +1,417 @@
+package pirsch
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRedisKeyPrefix        = "pirsch:session:"
+	defaultRedisTimeout          = time.Second
+	defaultRedisMaxIdleConns     = 10
+	defaultRedisRetryInterval    = time.Second
+	defaultRedisMaxRetryInterval = time.Second * 30
+)
+
+var (
+	// ErrRedisProtocol is returned by the RedisSessionStore if the server sends an unexpected reply.
+	ErrRedisProtocol = errors.New("unexpected reply from redis server")
+
+	// ErrRedisUnavailable is returned by the RedisSessionStore while the server cannot be reached.
+	ErrRedisUnavailable = errors.New("redis server unavailable")
+)
+
+// RedisSessionStoreConfig is the configuration for the RedisSessionStore.
+type RedisSessionStoreConfig struct {
+	// Addr is the host and port of the server, like "127.0.0.1:6379".
+	Addr string
+
+	// Password is used to authenticate if set.
+	Password string
+
+	// DB is the database selected after connecting.
+	DB int
+
+	// KeyPrefix is prepended to all keys.
+	// If you leave it empty, "pirsch:session:" is used.
+	KeyPrefix string
+
+	// Timeout is used to connect to the server and for each command.
+	// If you leave it 0, the default of one second is used.
+	Timeout time.Duration
+
+	// MaxIdleConns sets the maximum number of connections kept open to be reused.
+	// If you leave it 0, the default of 10 is used.
+	MaxIdleConns int
+
+	// RetryInterval is the time to wait before connecting again after the server could not be reached.
+	// Sessions are looked up in the Store in the meantime. The interval is doubled for each failed attempt.
+	// If you leave it 0, the default of one second is used.
+	RetryInterval time.Duration
+
+	// MaxRetryInterval is the maximum time to wait before connecting again.
+	// If you leave it 0, the default of 30 seconds is used.
+	MaxRetryInterval time.Duration
+
+	// Metrics receives the session hits and misses, if set.
+	Metrics MetricsCollector
+
+	// Logger is the log.Logger used for logging.
+	// The default log will be used printing to os.Stdout with "pirsch" in its prefix in case it is not set.
+	Logger *log.Logger
+}
+
+func (config *RedisSessionStoreConfig) validate() {
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = defaultRedisKeyPrefix
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultRedisTimeout
+	}
+
+	if config.MaxIdleConns < 1 {
+		config.MaxIdleConns = defaultRedisMaxIdleConns
+	}
+
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = defaultRedisRetryInterval
+	}
+
+	if config.MaxRetryInterval <= 0 {
+		config.MaxRetryInterval = defaultRedisMaxRetryInterval
+	}
+
+	if config.MaxRetryInterval < config.RetryInterval {
+		config.MaxRetryInterval = config.RetryInterval
+	}
+
+	if config.Metrics == nil {
+		config.Metrics = noopMetrics{}
+	}
+
+	if config.Logger == nil {
+		config.Logger = logger
+	}
+}
+
+// RedisSessionStore is a SessionStore saving the sessions in Redis, or any other server speaking the Redis protocol.
+// It can be shared by multiple instances of your application to keep the sessions of visitors switching between them.
+// Sessions not found in Redis are looked up in the Store. Each session expires after the SessionMaxAge.
+// While the server cannot be reached, sessions are looked up in the Store without connecting to it,
+// until the retry interval has passed.
+type RedisSessionStore struct {
+	client           Store
+	addr             string
+	password         string
+	db               int
+	keyPrefix        string
+	timeout          time.Duration
+	conns            chan *redisConn
+	minRetryInterval time.Duration
+	maxRetryInterval time.Duration
+	metrics          MetricsCollector
+	logger           *log.Logger
+
+	// unavailable is set while the server cannot be reached, no connection is opened before retryAt
+	unavailable   bool
+	retryAt       time.Time
+	retryInterval time.Duration
+	m             sync.Mutex
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	reused bool
+}
+
+// NewRedisSessionStore creates a new RedisSessionStore for given client and configuration.
+// It returns an error if the server cannot be reached.
+func NewRedisSessionStore(client Store, config RedisSessionStoreConfig) (*RedisSessionStore, error) {
+	config.validate()
+	store := &RedisSessionStore{
+		client:           client,
+		addr:             config.Addr,
+		password:         config.Password,
+		db:               config.DB,
+		keyPrefix:        config.KeyPrefix,
+		timeout:          config.Timeout,
+		conns:            make(chan *redisConn, config.MaxIdleConns),
+		minRetryInterval: config.RetryInterval,
+		maxRetryInterval: config.MaxRetryInterval,
+		metrics:          config.Metrics,
+		logger:           config.Logger,
+	}
+
+	if _, _, err := store.do("PING"); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Get implements the SessionStore interface.
+func (store *RedisSessionStore) Get(clientID int64, fingerprint string, maxAge time.Time) Session {
+	value, found, err := store.do("GET", store.getKey(clientID, fingerprint))
+
+	if err != nil && err != ErrRedisUnavailable {
+		store.logger.Printf("error reading session from redis: %s", err)
+	} else if found {
+		var session Session
+
+		if err := json.Unmarshal([]byte(value), &session); err != nil {
+			store.logger.Printf("error decoding session from redis: %s", err)
+		} else if session.Time.After(maxAge) {
+			store.metrics.SessionCache(SessionCacheHit)
+			return session
+		}
+	}
+
+	store.metrics.SessionCache(SessionCacheMiss)
+	s, _ := store.client.Session(clientID, fingerprint, maxAge)
+	return s
+}
+
+// Put implements the SessionStore interface.
+func (store *RedisSessionStore) Put(clientID int64, fingerprint string, session Session, maxAge time.Duration) {
+	value, err := json.Marshal(session)
+
+	if err != nil {
+		store.logger.Printf("error encoding session for redis: %s", err)
+		return
+	}
+
+	ttl := maxAge.Milliseconds()
+
+	if ttl < 1 {
+		ttl = 1
+	}
+
+	if _, _, err := store.do("SET", store.getKey(clientID, fingerprint), string(value), "PX", strconv.FormatInt(ttl, 10)); err != nil && err != ErrRedisUnavailable {
+		store.logger.Printf("error saving session to redis: %s", err)
+	}
+}
+
+// Close closes all idle connections.
+func (store *RedisSessionStore) Close() {
+	for {
+		select {
+		case conn := <-store.conns:
+			conn.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (store *RedisSessionStore) getKey(clientID int64, fingerprint string) string {
+	return store.keyPrefix + strconv.FormatInt(clientID, 10) + ":" + fingerprint
+}
+
+// do sends given command and returns the reply, and false if the reply is nil.
+// It returns ErrRedisUnavailable without connecting while the server cannot be reached.
+func (store *RedisSessionStore) do(args ...string) (string, bool, error) {
+	if !store.available() {
+		return "", false, ErrRedisUnavailable
+	}
+
+	conn, err := store.getConn()
+
+	if err != nil {
+		store.unreachable(err)
+		return "", false, err
+	}
+
+	reply, found, err := conn.do(store.timeout, args...)
+
+	if err != nil {
+		var redisErr redisError
+
+		// the connection can be reused if the server returned an error
+		if !errors.As(err, &redisErr) {
+			conn.conn.Close()
+
+			// an idle connection might have been closed by the server, so only new connections mark it unreachable
+			if !conn.reused {
+				store.unreachable(err)
+			}
+
+			return "", false, err
+		}
+	}
+
+	store.reachable()
+	conn.reused = true
+
+	select {
+	case store.conns <- conn:
+	default:
+		conn.conn.Close()
+	}
+
+	return reply, found, err
+}
+
+// available returns whether a command can be sent to the server.
+// After the retry interval has passed, a single command is sent to check whether the server can be reached again.
+func (store *RedisSessionStore) available() bool {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	if !store.unavailable {
+		return true
+	}
+
+	now := time.Now()
+
+	if now.Before(store.retryAt) {
+		return false
+	}
+
+	store.retryAt = now.Add(store.retryInterval)
+	return true
+}
+
+// unreachable marks the server unavailable and doubles the retry interval.
+func (store *RedisSessionStore) unreachable(err error) {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	if store.unavailable {
+		store.retryInterval *= 2
+
+		if store.retryInterval > store.maxRetryInterval {
+			store.retryInterval = store.maxRetryInterval
+		}
+	} else {
+		store.unavailable = true
+		store.retryInterval = store.minRetryInterval
+		store.logger.Printf("redis server unavailable, looking up sessions in the store: %s", err)
+	}
+
+	store.retryAt = time.Now().Add(store.retryInterval)
+}
+
+// reachable marks the server available again.
+func (store *RedisSessionStore) reachable() {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	if store.unavailable {
+		store.unavailable = false
+		store.logger.Println("redis server available again")
+	}
+}
+
+func (store *RedisSessionStore) getConn() (*redisConn, error) {
+	select {
+	case conn := <-store.conns:
+		return conn, nil
+	default:
+	}
+
+	c, err := net.DialTimeout("tcp", store.addr, store.timeout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{
+		conn:   c,
+		reader: bufio.NewReader(c),
+	}
+
+	if store.password != "" {
+		if _, _, err := conn.do(store.timeout, "AUTH", store.password); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	if store.db != 0 {
+		if _, _, err := conn.do(store.timeout, "SELECT", strconv.Itoa(store.db)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// redisError is an error returned by the server.
+type redisError string
+
+func (err redisError) Error() string {
+	return string(err)
+}
+
+// do sends given command as an array of bulk strings and reads the reply.
+func (conn *redisConn) do(timeout time.Duration, args ...string) (string, bool, error) {
+	if err := conn.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return "", false, err
+	}
+
+	var cmd strings.Builder
+	cmd.WriteString(fmt.Sprintf("*%d\r\n", len(args)))
+
+	for _, arg := range args {
+		cmd.WriteString(fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg))
+	}
+
+	if _, err := io.WriteString(conn.conn, cmd.String()); err != nil {
+		return "", false, err
+	}
+
+	return conn.readReply()
+}
+
+func (conn *redisConn) readReply() (string, bool, error) {
+	line, err := conn.reader.ReadString('\n')
+
+	if err != nil {
+		return "", false, err
+	}
+
+	line = strings.TrimSuffix(line, "\r\n")
+
+	if line == "" {
+		return "", false, ErrRedisProtocol
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], true, nil
+	case '-':
+		return "", false, redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+
+		if err != nil {
+			return "", false, ErrRedisProtocol
+		}
+
+		if n < 0 {
+			return "", false, nil
+		}
+
+		data := make([]byte, n+2)
+
+		if _, err := io.ReadFull(conn.reader, data); err != nil {
+			return "", false, err
+		}
+
+		return string(data[:n]), true, nil
+	}
+
+	return "", false, ErrRedisProtocol
+}
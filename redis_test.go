@@ -0,0 +1,325 @@
+package pirsch
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRedisSessionStore(t *testing.T) {
+	addr := redisTestAddr(t)
+	client := NewMockClient()
+	store, err := NewRedisSessionStore(client, RedisSessionStoreConfig{
+		Addr:      addr,
+		KeyPrefix: fmt.Sprintf("pirschtest:%d:", time.Now().UnixNano()),
+	})
+	assert.NoError(t, err)
+	defer store.Close()
+	session := store.Get(1, "fp", time.Now().Add(-time.Minute))
+	assert.Empty(t, session.Path)
+	client.ReturnSession = &Session{
+		Path:    "/store",
+		Time:    time.Now().Add(-time.Second * 15),
+		Session: time.Now().Add(-time.Second * 20),
+	}
+	session = store.Get(1, "fp", time.Now().Add(-time.Minute))
+	assert.Equal(t, "/store", session.Path)
+	client.ReturnSession = nil
+	now := time.Now().UTC()
+	store.Put(1, "fp", Session{
+		Path:    "/",
+		Time:    now,
+		Session: now.Add(-time.Second),
+	}, time.Minute)
+	session = store.Get(1, "fp", time.Now().Add(-time.Minute))
+	assert.Equal(t, "/", session.Path)
+	assert.True(t, now.Equal(session.Time))
+	assert.True(t, now.Add(-time.Second).Equal(session.Session))
+	session = store.Get(1, "fp", time.Now().Add(time.Minute))
+	assert.Empty(t, session.Path, "the session must be inactive")
+	session = store.Get(2, "fp", time.Now().Add(-time.Minute))
+	assert.Empty(t, session.Path)
+	store.Put(1, "fp", Session{Path: "/", Time: now}, time.Millisecond*10)
+	time.Sleep(time.Millisecond * 50)
+	session = store.Get(1, "fp", time.Now().Add(-time.Minute))
+	assert.Empty(t, session.Path, "the session must have expired")
+}
+
+func TestRedisSessionStoreAuth(t *testing.T) {
+	server := newTestRedisServer(t, "secret")
+	_, err := NewRedisSessionStore(NewMockClient(), RedisSessionStoreConfig{Addr: server.addr()})
+	assert.Error(t, err)
+	store, err := NewRedisSessionStore(NewMockClient(), RedisSessionStoreConfig{
+		Addr:     server.addr(),
+		Password: "secret",
+		DB:       3,
+	})
+	assert.NoError(t, err)
+	store.Close()
+	_, err = NewRedisSessionStore(NewMockClient(), RedisSessionStoreConfig{Addr: "127.0.0.1:1"})
+	assert.Error(t, err)
+}
+
+func TestRedisSessionStoreUnavailable(t *testing.T) {
+	server := newTestRedisServer(t, "")
+	client := NewMockClient()
+	client.ReturnSession = &Session{Path: "/store", Time: time.Now()}
+	store, err := NewRedisSessionStore(client, RedisSessionStoreConfig{
+		Addr:          server.addr(),
+		RetryInterval: time.Millisecond * 100,
+	})
+	assert.NoError(t, err)
+	store.Close()
+	server.setDown(true)
+	session := store.Get(1, "fp", time.Now().Add(-time.Minute))
+	assert.Equal(t, "/store", session.Path)
+	accepted := server.acceptedConns()
+
+	for i := 0; i < 10; i++ {
+		session = store.Get(1, "fp", time.Now().Add(-time.Minute))
+		assert.Equal(t, "/store", session.Path)
+		store.Put(1, "fp", Session{Path: "/", Time: time.Now()}, time.Minute)
+	}
+
+	assert.Equal(t, accepted, server.acceptedConns(), "no connection must be opened within the retry interval")
+	_, _, err = store.do("PING")
+	assert.Equal(t, ErrRedisUnavailable, err)
+	time.Sleep(time.Millisecond * 120)
+	_, _, err = store.do("PING")
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrRedisUnavailable, err)
+	assert.Equal(t, accepted+1, server.acceptedConns())
+	time.Sleep(time.Millisecond * 120)
+	_, _, err = store.do("PING")
+	assert.Equal(t, ErrRedisUnavailable, err, "the retry interval must be doubled")
+	server.setDown(false)
+	time.Sleep(time.Millisecond * 150)
+	_, _, err = store.do("PING")
+	assert.NoError(t, err)
+	now := time.Now().UTC()
+	store.Put(1, "fp", Session{Path: "/", Time: now}, time.Minute)
+	session = store.Get(1, "fp", time.Now().Add(-time.Minute))
+	assert.Equal(t, "/", session.Path)
+	store.Close()
+}
+
+func TestRedisSessionStoreTracker(t *testing.T) {
+	addr := redisTestAddr(t)
+	client := NewMockClient()
+	config := RedisSessionStoreConfig{
+		Addr:      addr,
+		KeyPrefix: fmt.Sprintf("pirschtest:%d:", time.Now().UnixNano()),
+	}
+	store1, err := NewRedisSessionStore(client, config)
+	assert.NoError(t, err)
+	defer store1.Close()
+	store2, err := NewRedisSessionStore(client, config)
+	assert.NoError(t, err)
+	defer store2.Close()
+	tracker1 := NewTracker(client, "salt", &TrackerConfig{SessionStore: store1})
+	tracker2 := NewTracker(client, "salt", &TrackerConfig{SessionStore: store2})
+	req := httptest.NewRequest(http.MethodGet, "/foo", nil)
+	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	tracker1.Hit(req, nil)
+	time.Sleep(time.Millisecond * 1100)
+	req = httptest.NewRequest(http.MethodGet, "/bar", nil)
+	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	tracker2.Hit(req, nil)
+	tracker1.Stop()
+	tracker2.Stop()
+	assert.Len(t, client.Hits, 2)
+	assert.Equal(t, client.Hits[0].Session, client.Hits[1].Session, "the session must be continued by the second instance")
+	assert.Equal(t, 1, client.Hits[1].PreviousTimeOnPageSeconds)
+}
+
+// redisTestAddr returns the address of the server set in REDIS_ADDR, or starts a test server otherwise.
+func redisTestAddr(t *testing.T) string {
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		return addr
+	}
+
+	return newTestRedisServer(t, "").addr()
+}
+
+// testRedisServer implements the Redis commands used by the RedisSessionStore.
+type testRedisServer struct {
+	listener net.Listener
+	password string
+	values   map[string]string
+	expires  map[string]time.Time
+	down     bool
+	accepted int
+	m        sync.Mutex
+}
+
+func newTestRedisServer(t *testing.T, password string) *testRedisServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := &testRedisServer{
+		listener: listener,
+		password: password,
+		values:   make(map[string]string),
+		expires:  make(map[string]time.Time),
+	}
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	go server.serve()
+	return server
+}
+
+func (server *testRedisServer) addr() string {
+	return server.listener.Addr().String()
+}
+
+func (server *testRedisServer) serve() {
+	for {
+		conn, err := server.listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		server.m.Lock()
+		server.accepted++
+		down := server.down
+		server.m.Unlock()
+
+		if down {
+			conn.Close()
+			continue
+		}
+
+		go server.handle(conn)
+	}
+}
+
+// setDown closes new connections immediately if set, to simulate a server that cannot be reached.
+func (server *testRedisServer) setDown(down bool) {
+	server.m.Lock()
+	defer server.m.Unlock()
+	server.down = down
+}
+
+func (server *testRedisServer) acceptedConns() int {
+	server.m.Lock()
+	defer server.m.Unlock()
+	return server.accepted
+}
+
+func (server *testRedisServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := server.password == ""
+
+	for {
+		args, err := server.readCommand(reader)
+
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(args[0])
+
+		if !authenticated && cmd != "AUTH" {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+
+		switch cmd {
+		case "AUTH":
+			if len(args) == 2 && args[1] == server.password {
+				authenticated = true
+				io.WriteString(conn, "+OK\r\n")
+			} else {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+			}
+		case "PING":
+			io.WriteString(conn, "+PONG\r\n")
+		case "SELECT":
+			io.WriteString(conn, "+OK\r\n")
+		case "GET":
+			if value, found := server.get(args[1]); found {
+				io.WriteString(conn, fmt.Sprintf("$%d\r\n%s\r\n", len(value), value))
+			} else {
+				io.WriteString(conn, "$-1\r\n")
+			}
+		case "SET":
+			ttl, _ := strconv.Atoi(args[4])
+			server.set(args[1], args[2], time.Duration(ttl)*time.Millisecond)
+			io.WriteString(conn, "+OK\r\n")
+		default:
+			io.WriteString(conn, "-ERR unknown command\r\n")
+		}
+	}
+}
+
+func (server *testRedisServer) readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+
+	for i := range args {
+		line, err := reader.ReadString('\n')
+
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+
+		if err != nil {
+			return nil, err
+		}
+
+		data := make([]byte, size+2)
+
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+
+		args[i] = string(data[:size])
+	}
+
+	return args, nil
+}
+
+func (server *testRedisServer) get(key string) (string, bool) {
+	server.m.Lock()
+	defer server.m.Unlock()
+
+	if time.Now().After(server.expires[key]) {
+		delete(server.values, key)
+		delete(server.expires, key)
+	}
+
+	value, found := server.values[key]
+	return value, found
+}
+
+func (server *testRedisServer) set(key, value string, ttl time.Duration) {
+	server.m.Lock()
+	defer server.m.Unlock()
+	server.values[key] = value
+	server.expires[key] = time.Now().Add(ttl)
+}
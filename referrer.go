@@ -22,11 +22,12 @@ var referrerQueryParams = []string{
 	"utm_source",
 }
 
-func ignoreReferrer(r *http.Request) bool {
+// matchReferrerSpam returns the domain on the referrer blacklist the referrer of given request matches, or an empty string otherwise.
+func matchReferrerSpam(r *http.Request) string {
 	referrer := getReferrerFromHeaderOrQuery(r)
 
 	if referrer == "" {
-		return false
+		return ""
 	}
 
 	u, err := url.ParseRequestURI(referrer)
@@ -36,8 +37,12 @@ func ignoreReferrer(r *http.Request) bool {
 	}
 
 	referrer = stripSubdomain(referrer)
-	_, found := referrerBlacklist[referrer]
-	return found
+
+	if _, found := referrerBlacklist[referrer]; found {
+		return referrer
+	}
+
+	return ""
 }
 
 func getReferrer(r *http.Request, ref string, domainBlacklist []string, ignoreSubdomain bool) (string, string, string) {
@@ -0,0 +1,105 @@
+package pirsch
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultRetryInitialBackoff = time.Second
+	defaultRetryMaxBackoff     = time.Second * 30
+	defaultRetryMultiplier     = 2
+)
+
+// RetryPolicy configures how the Tracker retries saving a batch of hits or events if the Store returns an error.
+// The workers wait between the attempts. Tracker.Flush and Tracker.Stop interrupt the wait,
+// so that the batch is written to the spool (or dropped) instead of retrying it any further.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts to save a batch, including the first one.
+	// Values less than 1 are set to 1, which disables retries.
+	MaxAttempts int
+
+	// InitialBackoff is the time to wait before the first retry.
+	// If you leave it 0, the default of one second is used.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum time to wait between two attempts.
+	// If you leave it 0, the default of 30 seconds is used.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the backoff is multiplied with after each failed retry.
+	// Values less than 1 are set to the default of 2.
+	Multiplier float64
+
+	// Jitter randomizes the backoff by up to the given fraction (between 0 and 1) in both directions,
+	// so that multiple Trackers don't retry at the same time.
+	Jitter float64
+}
+
+func (policy *RetryPolicy) validate() {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = defaultRetryInitialBackoff
+	}
+
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = defaultRetryMaxBackoff
+	}
+
+	if policy.MaxBackoff < policy.InitialBackoff {
+		policy.MaxBackoff = policy.InitialBackoff
+	}
+
+	if policy.Multiplier < 1 {
+		policy.Multiplier = defaultRetryMultiplier
+	}
+
+	if policy.Jitter < 0 {
+		policy.Jitter = 0
+	} else if policy.Jitter > 1 {
+		policy.Jitter = 1
+	}
+}
+
+// backoff returns the time to wait before given retry (starting at 1).
+func (policy *RetryPolicy) backoff(retry int) time.Duration {
+	backoff := float64(policy.InitialBackoff)
+
+	for i := 1; i < retry && backoff < float64(policy.MaxBackoff); i++ {
+		backoff *= policy.Multiplier
+	}
+
+	if backoff > float64(policy.MaxBackoff) {
+		backoff = float64(policy.MaxBackoff)
+	}
+
+	if policy.Jitter > 0 {
+		backoff += backoff * policy.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(backoff)
+}
+
+// retry calls save until it succeeds or the maximum number of attempts has been reached and returns the last error.
+// It stops waiting for the next attempt and returns the last error if the context is cancelled.
+func (policy *RetryPolicy) retry(ctx context.Context, save func() error) error {
+	err := save()
+
+	for attempt := 1; err != nil && attempt < policy.MaxAttempts; attempt++ {
+		timer := time.NewTimer(policy.backoff(attempt))
+
+		select {
+		case <-timer.C:
+			err = save()
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+
+	return err
+}
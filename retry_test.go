@@ -0,0 +1,83 @@
+package pirsch
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Validate(t *testing.T) {
+	policy := &RetryPolicy{}
+	policy.validate()
+	assert.Equal(t, 1, policy.MaxAttempts)
+	assert.Equal(t, defaultRetryInitialBackoff, policy.InitialBackoff)
+	assert.Equal(t, defaultRetryMaxBackoff, policy.MaxBackoff)
+	assert.InDelta(t, defaultRetryMultiplier, policy.Multiplier, 0.001)
+	assert.InDelta(t, 0, policy.Jitter, 0.001)
+	policy = &RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Minute, MaxBackoff: time.Second, Multiplier: 0.5, Jitter: 3}
+	policy.validate()
+	assert.Equal(t, 5, policy.MaxAttempts)
+	assert.Equal(t, time.Minute, policy.MaxBackoff)
+	assert.InDelta(t, defaultRetryMultiplier, policy.Multiplier, 0.001)
+	assert.InDelta(t, 1, policy.Jitter, 0.001)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := &RetryPolicy{InitialBackoff: time.Second, MaxBackoff: time.Second * 5}
+	policy.validate()
+	assert.Equal(t, time.Second, policy.backoff(1))
+	assert.Equal(t, time.Second*2, policy.backoff(2))
+	assert.Equal(t, time.Second*4, policy.backoff(3))
+	assert.Equal(t, time.Second*5, policy.backoff(4))
+	assert.Equal(t, time.Second*5, policy.backoff(100))
+	policy.Jitter = 0.5
+
+	for i := 0; i < 100; i++ {
+		backoff := policy.backoff(2)
+		assert.True(t, backoff >= time.Second && backoff <= time.Second*3)
+	}
+}
+
+func TestRetryPolicy_Retry(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	policy.validate()
+	attempts := 0
+	err := policy.retry(context.Background(), func() error {
+		attempts++
+		return errors.New("error")
+	})
+	assert.EqualError(t, err, "error")
+	assert.Equal(t, 3, attempts)
+	attempts = 0
+	assert.NoError(t, policy.retry(context.Background(), func() error {
+		attempts++
+
+		if attempts < 2 {
+			return errors.New("error")
+		}
+
+		return nil
+	}))
+	assert.Equal(t, 2, attempts)
+}
+
+func TestRetryPolicy_RetryCancel(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour}
+	policy.validate()
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		cancel()
+	}()
+	start := time.Now()
+	err := policy.retry(ctx, func() error {
+		attempts++
+		return errors.New("error")
+	})
+	assert.EqualError(t, err, "error")
+	assert.Equal(t, 1, attempts)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,101 @@
+package pirsch
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// SaltRotation defines how the salt used to generate fingerprints changes over time.
+type SaltRotation int
+
+const (
+	// SaltStatic uses the salt passed to NewTracker as is.
+	// The fingerprint of a visitor stays the same across days.
+	SaltStatic = SaltRotation(iota)
+
+	// SaltDaily derives a new salt from the salt passed to NewTracker (used as a secret) and the date at midnight (UTC).
+	// All instances using the same secret generate the same fingerprints for the same day.
+	SaltDaily
+
+	// SaltRandom generates a new random salt at midnight (UTC), which is never persisted.
+	// The salt passed to NewTracker is ignored, and each instance and restart uses a different salt.
+	SaltRandom
+)
+
+// saltRotator holds the current salt and rotates it at midnight.
+// The previous salt is kept, so that sessions can be continued across midnight.
+type saltRotator struct {
+	rotation SaltRotation
+	secret   string
+	current  string
+	previous string
+	rotated  time.Time
+	m        sync.RWMutex
+}
+
+func newSaltRotator(secret string, rotation SaltRotation) *saltRotator {
+	rotator := &saltRotator{
+		rotation: rotation,
+		secret:   secret,
+	}
+
+	if rotation == SaltStatic {
+		rotator.current = secret
+	} else {
+		now := time.Now().UTC()
+		rotator.rotate(now.Add(-time.Hour * 24))
+		rotator.rotate(now)
+
+		// random salts from before the process started are unknown
+		if rotation == SaltRandom {
+			rotator.previous = ""
+		}
+	}
+
+	return rotator
+}
+
+// rotate sets the salt for the day of given time and keeps the current salt as the previous one.
+func (rotator *saltRotator) rotate(now time.Time) {
+	if rotator.rotation == SaltStatic {
+		return
+	}
+
+	var salt string
+
+	if rotator.rotation == SaltDaily {
+		mac := hmac.New(sha256.New, []byte(rotator.secret))
+		mac.Write([]byte(now.UTC().Format("2006-01-02")))
+		salt = hex.EncodeToString(mac.Sum(nil))
+	} else {
+		salt = rotator.random()
+	}
+
+	rotator.m.Lock()
+	defer rotator.m.Unlock()
+	rotator.previous = rotator.current
+	rotator.current = salt
+	rotator.rotated = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// get returns the current salt, the previous salt (if any), and the time the salt has been rotated.
+func (rotator *saltRotator) get() (string, string, time.Time) {
+	rotator.m.RLock()
+	defer rotator.m.RUnlock()
+	return rotator.current, rotator.previous, rotator.rotated
+}
+
+func (rotator *saltRotator) random() string {
+	salt := make([]byte, 32)
+
+	if _, err := rand.Read(salt); err != nil {
+		// this should not fail, but the salt must never be predictable
+		panic(err)
+	}
+
+	return hex.EncodeToString(salt)
+}
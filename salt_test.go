@@ -0,0 +1,44 @@
+package pirsch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSaltRotatorStatic(t *testing.T) {
+	rotator := newSaltRotator("salt", SaltStatic)
+	rotator.rotate(time.Now().Add(time.Hour * 24))
+	salt, previous, _ := rotator.get()
+	assert.Equal(t, "salt", salt)
+	assert.Empty(t, previous)
+}
+
+func TestSaltRotatorDaily(t *testing.T) {
+	rotator := newSaltRotator("secret", SaltDaily)
+	salt, previous, rotated := rotator.get()
+	assert.Len(t, salt, 64)
+	assert.Len(t, previous, 64)
+	assert.NotEqual(t, salt, previous)
+	assert.NotEqual(t, "secret", salt)
+	now := time.Now().UTC()
+	assert.Equal(t, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), rotated)
+	assert.Equal(t, salt, newSaltRotator("secret", SaltDaily).current, "the salt must be derived from the secret and date")
+	assert.NotEqual(t, salt, newSaltRotator("other", SaltDaily).current)
+	rotator.rotate(now.Add(time.Hour * 24))
+	nextSalt, nextPrevious, _ := rotator.get()
+	assert.NotEqual(t, salt, nextSalt)
+	assert.Equal(t, salt, nextPrevious)
+}
+
+func TestSaltRotatorRandom(t *testing.T) {
+	rotator := newSaltRotator("salt", SaltRandom)
+	salt, previous, _ := rotator.get()
+	assert.Len(t, salt, 64)
+	assert.Empty(t, previous)
+	assert.NotEqual(t, salt, newSaltRotator("salt", SaltRandom).current)
+	rotator.rotate(time.Now())
+	nextSalt, nextPrevious, _ := rotator.get()
+	assert.NotEqual(t, salt, nextSalt)
+	assert.Equal(t, salt, nextPrevious)
+}
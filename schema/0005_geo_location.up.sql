@@ -0,0 +1,8 @@
+ALTER TABLE "hit" ADD COLUMN "region" LowCardinality(String) AFTER "country_code";
+ALTER TABLE "hit" ADD COLUMN "city" String AFTER "region";
+ALTER TABLE "hit" ADD COLUMN "asn" UInt32 DEFAULT 0 AFTER "city";
+ALTER TABLE "hit" ADD COLUMN "as_organization" String AFTER "asn";
+ALTER TABLE "event" ADD COLUMN "region" LowCardinality(String) AFTER "country_code";
+ALTER TABLE "event" ADD COLUMN "city" String AFTER "region";
+ALTER TABLE "event" ADD COLUMN "asn" UInt32 DEFAULT 0 AFTER "city";
+ALTER TABLE "event" ADD COLUMN "as_organization" String AFTER "asn";
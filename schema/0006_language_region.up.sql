@@ -0,0 +1,2 @@
+ALTER TABLE "hit" ADD COLUMN "language_region" LowCardinality(String) AFTER "language";
+ALTER TABLE "event" ADD COLUMN "language_region" LowCardinality(String) AFTER "language";
@@ -0,0 +1,2 @@
+ALTER TABLE "hit" ADD COLUMN "device_type" LowCardinality(String) AFTER "mobile";
+ALTER TABLE "event" ADD COLUMN "device_type" LowCardinality(String) AFTER "mobile";
@@ -0,0 +1,12 @@
+CREATE TABLE "bot_hit" (
+    client_id UInt64,
+    time DateTime('UTC'),
+    user_agent String,
+    path String,
+    url String,
+    bot_reason LowCardinality(String),
+    bot_rule LowCardinality(String)
+) ENGINE = MergeTree()
+PARTITION BY toYYYYMM(time)
+ORDER BY (client_id, time)
+;
@@ -0,0 +1,82 @@
+CREATE TABLE "hit" (
+    client_id bigint NOT NULL DEFAULT 0,
+    fingerprint varchar(32) NOT NULL,
+    "time" timestamptz NOT NULL,
+    "session" timestamptz NOT NULL,
+    previous_time_on_page_seconds integer NOT NULL DEFAULT 0,
+    user_agent text NOT NULL DEFAULT '',
+    path text NOT NULL DEFAULT '',
+    url text NOT NULL DEFAULT '',
+    title text NOT NULL DEFAULT '',
+    language varchar(10) NOT NULL DEFAULT '',
+    country_code varchar(2) NOT NULL DEFAULT '',
+    referrer text NOT NULL DEFAULT '',
+    referrer_name text NOT NULL DEFAULT '',
+    referrer_icon text NOT NULL DEFAULT '',
+    os varchar(20) NOT NULL DEFAULT '',
+    os_version varchar(20) NOT NULL DEFAULT '',
+    browser varchar(20) NOT NULL DEFAULT '',
+    browser_version varchar(20) NOT NULL DEFAULT '',
+    desktop boolean NOT NULL DEFAULT FALSE,
+    mobile boolean NOT NULL DEFAULT FALSE,
+    screen_width integer NOT NULL DEFAULT 0,
+    screen_height integer NOT NULL DEFAULT 0,
+    screen_class varchar(10) NOT NULL DEFAULT '',
+    utm_source text NOT NULL DEFAULT '',
+    utm_medium text NOT NULL DEFAULT '',
+    utm_campaign text NOT NULL DEFAULT '',
+    utm_content text NOT NULL DEFAULT '',
+    utm_term text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX hit_client_id_time_index ON "hit" (client_id, "time");
+CREATE INDEX hit_client_id_fingerprint_time_index ON "hit" (client_id, fingerprint, "time");
+
+CREATE TABLE "event" (
+    client_id bigint NOT NULL DEFAULT 0,
+    fingerprint varchar(32) NOT NULL,
+    "time" timestamptz NOT NULL,
+    "session" timestamptz NOT NULL,
+    previous_time_on_page_seconds integer NOT NULL DEFAULT 0,
+    user_agent text NOT NULL DEFAULT '',
+    path text NOT NULL DEFAULT '',
+    url text NOT NULL DEFAULT '',
+    title text NOT NULL DEFAULT '',
+    language varchar(10) NOT NULL DEFAULT '',
+    country_code varchar(2) NOT NULL DEFAULT '',
+    referrer text NOT NULL DEFAULT '',
+    referrer_name text NOT NULL DEFAULT '',
+    referrer_icon text NOT NULL DEFAULT '',
+    os varchar(20) NOT NULL DEFAULT '',
+    os_version varchar(20) NOT NULL DEFAULT '',
+    browser varchar(20) NOT NULL DEFAULT '',
+    browser_version varchar(20) NOT NULL DEFAULT '',
+    desktop boolean NOT NULL DEFAULT FALSE,
+    mobile boolean NOT NULL DEFAULT FALSE,
+    screen_width integer NOT NULL DEFAULT 0,
+    screen_height integer NOT NULL DEFAULT 0,
+    screen_class varchar(10) NOT NULL DEFAULT '',
+    utm_source text NOT NULL DEFAULT '',
+    utm_medium text NOT NULL DEFAULT '',
+    utm_campaign text NOT NULL DEFAULT '',
+    utm_content text NOT NULL DEFAULT '',
+    utm_term text NOT NULL DEFAULT '',
+    event_name text NOT NULL,
+    event_duration_seconds integer NOT NULL DEFAULT 0,
+    event_meta_keys text[] NOT NULL DEFAULT '{}',
+    event_meta_values text[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX event_client_id_time_index ON "event" (client_id, "time");
+
+-- concatenates all arrays in a group, used to collect the event meta keys
+CREATE AGGREGATE array_cat_agg(text[]) (
+    SFUNC = array_cat,
+    STYPE = text[],
+    INITCOND = '{}'
+);
+
+-- removes duplicates from an array
+CREATE FUNCTION array_distinct(text[]) RETURNS text[] AS $$
+    SELECT array(SELECT DISTINCT unnest($1) ORDER BY 1)
+$$ LANGUAGE sql IMMUTABLE;
@@ -0,0 +1,8 @@
+ALTER TABLE "hit" ADD COLUMN region varchar(200) NOT NULL DEFAULT '';
+ALTER TABLE "hit" ADD COLUMN city varchar(200) NOT NULL DEFAULT '';
+ALTER TABLE "hit" ADD COLUMN asn bigint NOT NULL DEFAULT 0;
+ALTER TABLE "hit" ADD COLUMN as_organization varchar(200) NOT NULL DEFAULT '';
+ALTER TABLE "event" ADD COLUMN region varchar(200) NOT NULL DEFAULT '';
+ALTER TABLE "event" ADD COLUMN city varchar(200) NOT NULL DEFAULT '';
+ALTER TABLE "event" ADD COLUMN asn bigint NOT NULL DEFAULT 0;
+ALTER TABLE "event" ADD COLUMN as_organization varchar(200) NOT NULL DEFAULT '';
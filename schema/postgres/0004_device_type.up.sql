@@ -0,0 +1,2 @@
+ALTER TABLE "hit" ADD COLUMN device_type varchar(10) NOT NULL DEFAULT '';
+ALTER TABLE "event" ADD COLUMN device_type varchar(10) NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+CREATE TABLE "bot_hit" (
+    client_id bigint NOT NULL DEFAULT 0,
+    "time" timestamptz NOT NULL,
+    user_agent text NOT NULL DEFAULT '',
+    path text NOT NULL DEFAULT '',
+    url text NOT NULL DEFAULT '',
+    bot_reason varchar(20) NOT NULL DEFAULT '',
+    bot_rule text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX bot_hit_client_id_time_index ON "bot_hit" (client_id, "time");
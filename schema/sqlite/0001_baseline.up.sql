@@ -0,0 +1,71 @@
+CREATE TABLE "hit" (
+    client_id INTEGER NOT NULL DEFAULT 0,
+    fingerprint TEXT NOT NULL,
+    "time" DATETIME NOT NULL,
+    "session" DATETIME NOT NULL,
+    previous_time_on_page_seconds INTEGER NOT NULL DEFAULT 0,
+    user_agent TEXT NOT NULL DEFAULT '',
+    path TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    language TEXT NOT NULL DEFAULT '',
+    country_code TEXT NOT NULL DEFAULT '',
+    referrer TEXT NOT NULL DEFAULT '',
+    referrer_name TEXT NOT NULL DEFAULT '',
+    referrer_icon TEXT NOT NULL DEFAULT '',
+    os TEXT NOT NULL DEFAULT '',
+    os_version TEXT NOT NULL DEFAULT '',
+    browser TEXT NOT NULL DEFAULT '',
+    browser_version TEXT NOT NULL DEFAULT '',
+    desktop INTEGER NOT NULL DEFAULT 0,
+    mobile INTEGER NOT NULL DEFAULT 0,
+    screen_width INTEGER NOT NULL DEFAULT 0,
+    screen_height INTEGER NOT NULL DEFAULT 0,
+    screen_class TEXT NOT NULL DEFAULT '',
+    utm_source TEXT NOT NULL DEFAULT '',
+    utm_medium TEXT NOT NULL DEFAULT '',
+    utm_campaign TEXT NOT NULL DEFAULT '',
+    utm_content TEXT NOT NULL DEFAULT '',
+    utm_term TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX hit_client_id_time_index ON "hit" (client_id, "time");
+CREATE INDEX hit_client_id_fingerprint_time_index ON "hit" (client_id, fingerprint, "time");
+
+CREATE TABLE "event" (
+    client_id INTEGER NOT NULL DEFAULT 0,
+    fingerprint TEXT NOT NULL,
+    "time" DATETIME NOT NULL,
+    "session" DATETIME NOT NULL,
+    previous_time_on_page_seconds INTEGER NOT NULL DEFAULT 0,
+    user_agent TEXT NOT NULL DEFAULT '',
+    path TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    language TEXT NOT NULL DEFAULT '',
+    country_code TEXT NOT NULL DEFAULT '',
+    referrer TEXT NOT NULL DEFAULT '',
+    referrer_name TEXT NOT NULL DEFAULT '',
+    referrer_icon TEXT NOT NULL DEFAULT '',
+    os TEXT NOT NULL DEFAULT '',
+    os_version TEXT NOT NULL DEFAULT '',
+    browser TEXT NOT NULL DEFAULT '',
+    browser_version TEXT NOT NULL DEFAULT '',
+    desktop INTEGER NOT NULL DEFAULT 0,
+    mobile INTEGER NOT NULL DEFAULT 0,
+    screen_width INTEGER NOT NULL DEFAULT 0,
+    screen_height INTEGER NOT NULL DEFAULT 0,
+    screen_class TEXT NOT NULL DEFAULT '',
+    utm_source TEXT NOT NULL DEFAULT '',
+    utm_medium TEXT NOT NULL DEFAULT '',
+    utm_campaign TEXT NOT NULL DEFAULT '',
+    utm_content TEXT NOT NULL DEFAULT '',
+    utm_term TEXT NOT NULL DEFAULT '',
+    event_name TEXT NOT NULL,
+    event_duration_seconds INTEGER NOT NULL DEFAULT 0,
+    -- the meta data is stored as JSON arrays
+    event_meta_keys TEXT NOT NULL DEFAULT '[]',
+    event_meta_values TEXT NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX event_client_id_time_index ON "event" (client_id, "time");
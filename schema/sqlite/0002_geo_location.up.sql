@@ -0,0 +1,8 @@
+ALTER TABLE "hit" ADD COLUMN region TEXT NOT NULL DEFAULT '';
+ALTER TABLE "hit" ADD COLUMN city TEXT NOT NULL DEFAULT '';
+ALTER TABLE "hit" ADD COLUMN asn INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "hit" ADD COLUMN as_organization TEXT NOT NULL DEFAULT '';
+ALTER TABLE "event" ADD COLUMN region TEXT NOT NULL DEFAULT '';
+ALTER TABLE "event" ADD COLUMN city TEXT NOT NULL DEFAULT '';
+ALTER TABLE "event" ADD COLUMN asn INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "event" ADD COLUMN as_organization TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE "hit" ADD COLUMN language_region TEXT NOT NULL DEFAULT '';
+ALTER TABLE "event" ADD COLUMN language_region TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+CREATE TABLE "bot_hit" (
+    client_id INTEGER NOT NULL DEFAULT 0,
+    "time" DATETIME NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    path TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL DEFAULT '',
+    bot_reason TEXT NOT NULL DEFAULT '',
+    bot_rule TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX bot_hit_client_id_time_index ON "bot_hit" (client_id, "time");
@@ -1,21 +1,45 @@
 package pirsch
 
 import (
-	"fmt"
+	"container/list"
+	"context"
+	"hash/fnv"
+	"strconv"
 	"sync"
 	"time"
 )
 
 const (
-	defaultMaxSessions = 100_000
+	defaultMaxSessions        = 100_000
+	maxSessionCacheShards     = 64
+	minSessionCacheShardSize  = 1024
+	sessionCacheSweepInterval = time.Minute
 )
 
-// SessionCache caches sessions.
+// SessionCache caches sessions in memory and implements the SessionStore interface.
+// It is split into shards to reduce lock contention. Each shard evicts the least recently used session once it's full.
+// Expired sessions are removed when they are looked up, or by a background sweeper if started by SessionCache.StartSweeper.
 type SessionCache struct {
-	sessions    map[string]Session
+	shards  []*sessionCacheShard
+	client  Store
+	metrics MetricsCollector
+	cancel  context.CancelFunc
+	done    chan bool
+	start   sync.Once
+	stop    sync.Once
+}
+
+type sessionCacheShard struct {
+	sessions    map[string]*list.Element
+	lru         *list.List
 	maxSessions int
-	client      Store
-	m           sync.RWMutex
+	m           sync.Mutex
+}
+
+type sessionCacheEntry struct {
+	key     string
+	session Session
+	expires time.Time
 }
 
 // NewSessionCache creates a new cache for given client and maximum size.
@@ -24,43 +48,217 @@ func NewSessionCache(client Store, maxSessions int) *SessionCache {
 		maxSessions = defaultMaxSessions
 	}
 
+	shardCount := maxSessions / minSessionCacheShardSize
+
+	if shardCount < 1 {
+		shardCount = 1
+	} else if shardCount > maxSessionCacheShards {
+		shardCount = maxSessionCacheShards
+	}
+
+	shards := make([]*sessionCacheShard, shardCount)
+
+	for i := range shards {
+		shards[i] = &sessionCacheShard{
+			sessions:    make(map[string]*list.Element),
+			lru:         list.New(),
+			maxSessions: (maxSessions + shardCount - 1) / shardCount,
+		}
+	}
+
 	return &SessionCache{
-		sessions:    make(map[string]Session),
-		maxSessions: maxSessions,
-		client:      client,
+		shards:  shards,
+		client:  client,
+		metrics: noopMetrics{},
+		done:    make(chan bool),
 	}
 }
 
-func (cache *SessionCache) get(clientID int64, fingerprint string, maxAge time.Time) Session {
+// StartSweeper starts a background sweeper removing expired sessions in given interval (one minute if 0 or less),
+// so that they don't take up memory until they are evicted. Call SessionCache.Stop to stop the sweeper.
+// It is started at most once and never after the cache has been stopped.
+func (cache *SessionCache) StartSweeper(interval time.Duration) {
+	if interval <= 0 {
+		interval = sessionCacheSweepInterval
+	}
+
+	cache.start.Do(func() {
+		ctx, cancelFunc := context.WithCancel(context.Background())
+		cache.cancel = cancelFunc
+		go cache.sweepExpired(ctx, interval)
+	})
+}
+
+// Stop stops the background sweeper, if it has been started.
+// It can be called more than once.
+func (cache *SessionCache) Stop() {
+	cache.stop.Do(func() {
+		// prevent the sweeper from being started afterwards
+		cache.start.Do(func() {})
+
+		if cache.cancel != nil {
+			cache.cancel()
+			<-cache.done
+		}
+	})
+}
+
+// Get implements the SessionStore interface.
+func (cache *SessionCache) Get(clientID int64, fingerprint string, maxAge time.Time) Session {
 	key := cache.getKey(clientID, fingerprint)
-	cache.m.RLock()
-	session, ok := cache.sessions[key]
-	cache.m.RUnlock()
+	shard := cache.getShard(key)
+	now := time.Now()
+	shard.m.Lock()
+	session, expired := shard.get(key, now)
+	shard.m.Unlock()
+
+	if expired {
+		cache.metrics.SessionCache(SessionCacheExpiry)
+	}
 
-	if ok && session.Time.After(maxAge) {
-		return session
+	if session != nil && session.Time.After(maxAge) {
+		cache.metrics.SessionCache(SessionCacheHit)
+		return *session
 	}
 
+	cache.metrics.SessionCache(SessionCacheMiss)
 	s, _ := cache.client.Session(clientID, fingerprint, maxAge)
 	return s
 }
 
-func (cache *SessionCache) put(clientID int64, fingerprint, path string, now, session time.Time) {
+// Put implements the SessionStore interface. The session expires after the maxAge, starting at Session.Time.
+func (cache *SessionCache) Put(clientID int64, fingerprint string, session Session, maxAge time.Duration) {
 	key := cache.getKey(clientID, fingerprint)
-	cache.m.Lock()
-	defer cache.m.Unlock()
+	shard := cache.getShard(key)
+	shard.m.Lock()
+	evicted := shard.put(key, session, session.Time.Add(maxAge))
+	shard.m.Unlock()
+
+	if evicted {
+		cache.metrics.SessionCache(SessionCacheEviction)
+	}
+}
+
+// len returns the number of sessions in the cache, including expired sessions that have not been removed yet.
+func (cache *SessionCache) len() int {
+	n := 0
+
+	for _, shard := range cache.shards {
+		shard.m.Lock()
+		n += len(shard.sessions)
+		shard.m.Unlock()
+	}
+
+	return n
+}
+
+func (cache *SessionCache) sweepExpired(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			cache.sweep(time.Now())
+		case <-ctx.Done():
+			cache.done <- true
+			return
+		}
+	}
+}
 
-	if len(cache.sessions) >= cache.maxSessions {
-		cache.sessions = make(map[string]Session)
+// sweep removes all sessions expired at given time.
+func (cache *SessionCache) sweep(now time.Time) {
+	for _, shard := range cache.shards {
+		shard.m.Lock()
+		expired := shard.sweep(now)
+		shard.m.Unlock()
+
+		for i := 0; i < expired; i++ {
+			cache.metrics.SessionCache(SessionCacheExpiry)
+		}
 	}
+}
 
-	cache.sessions[key] = Session{
-		Path:    path,
-		Time:    now,
-		Session: session,
+func (cache *SessionCache) getShard(key string) *sessionCacheShard {
+	if len(cache.shards) == 1 {
+		return cache.shards[0]
 	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return cache.shards[h.Sum32()%uint32(len(cache.shards))]
 }
 
 func (cache *SessionCache) getKey(clientID int64, fingerprint string) string {
-	return fmt.Sprintf("%d%s", clientID, fingerprint)
+	return strconv.FormatInt(clientID, 10) + fingerprint
+}
+
+// get returns the session for given key, or nil if it is not cached, and marks it as recently used.
+// It returns true if the session has expired and has been removed.
+func (shard *sessionCacheShard) get(key string, now time.Time) (*Session, bool) {
+	element, found := shard.sessions[key]
+
+	if !found {
+		return nil, false
+	}
+
+	entry := element.Value.(*sessionCacheEntry)
+
+	if !entry.expires.After(now) {
+		shard.remove(element)
+		return nil, true
+	}
+
+	shard.lru.MoveToFront(element)
+	session := entry.session
+	return &session, false
+}
+
+// put adds or updates the session for given key and returns true if the least recently used session has been evicted.
+func (shard *sessionCacheShard) put(key string, session Session, expires time.Time) bool {
+	if element, found := shard.sessions[key]; found {
+		entry := element.Value.(*sessionCacheEntry)
+		entry.session = session
+		entry.expires = expires
+		shard.lru.MoveToFront(element)
+		return false
+	}
+
+	evicted := false
+
+	if len(shard.sessions) >= shard.maxSessions {
+		shard.remove(shard.lru.Back())
+		evicted = true
+	}
+
+	shard.sessions[key] = shard.lru.PushFront(&sessionCacheEntry{
+		key:     key,
+		session: session,
+		expires: expires,
+	})
+	return evicted
+}
+
+// sweep removes all sessions expired at given time and returns how many have been removed.
+func (shard *sessionCacheShard) sweep(now time.Time) int {
+	expired := 0
+
+	for element := shard.lru.Back(); element != nil; {
+		prev := element.Prev()
+
+		if !element.Value.(*sessionCacheEntry).expires.After(now) {
+			shard.remove(element)
+			expired++
+		}
+
+		element = prev
+	}
+
+	return expired
+}
+
+func (shard *sessionCacheShard) remove(element *list.Element) {
+	shard.lru.Remove(element)
+	delete(shard.sessions, element.Value.(*sessionCacheEntry).key)
 }
@@ -11,34 +11,113 @@ func TestSessionCache(t *testing.T) {
 	cleanupDB()
 	client := NewMockClient()
 	cache := NewSessionCache(client, 10)
-	session := cache.get(1, "fp", time.Now().Add(-time.Second*10))
+	defer cache.Stop()
+	session := cache.Get(1, "fp", time.Now().Add(-time.Second*10))
 	assert.Empty(t, session.Path)
 	client.ReturnSession = &Session{
 		Path:    "/",
 		Time:    time.Now().Add(-time.Second * 15),
 		Session: time.Now().Add(-time.Second * 20),
 	}
-	session = cache.get(1, "fp", time.Now().Add(-time.Second*20))
+	session = cache.Get(1, "fp", time.Now().Add(-time.Second*20))
 	assert.Equal(t, "/", session.Path)
 	client.ReturnSession = nil
-	cache.put(1, "fp", session.Path, session.Time, session.Session)
-	session = cache.get(1, "fp", time.Now().Add(-time.Second*20))
+	cache.Put(1, "fp", session, time.Minute)
+	session = cache.Get(1, "fp", time.Now().Add(-time.Second*20))
 	assert.Equal(t, "/", session.Path)
-	cache.put(1, "fp", session.Path, time.Now().Add(-time.Second*21), time.Now().Add(-time.Second*21))
-	session = cache.get(1, "fp", time.Now().Add(-time.Second*20))
+	cache.Put(1, "fp", Session{Path: session.Path, Time: time.Now().Add(-time.Second * 21), Session: time.Now().Add(-time.Second * 21)}, time.Minute)
+	session = cache.Get(1, "fp", time.Now().Add(-time.Second*20))
 	assert.Empty(t, session.Path)
 
 	for i := 0; i < 9; i++ {
-		cache.put(1, fmt.Sprintf("fp%d", i), "/foo", time.Now(), time.Now())
+		cache.Put(1, fmt.Sprintf("fp%d", i), Session{Path: "/foo", Time: time.Now(), Session: time.Now()}, time.Minute)
 	}
 
-	assert.Len(t, cache.sessions, 10)
-	session = cache.get(1, "fp", time.Now().Add(-time.Minute))
+	assert.Equal(t, 10, cache.len())
+	session = cache.Get(1, "fp", time.Now().Add(-time.Minute))
 	assert.Equal(t, "/", session.Path)
-	cache.put(1, "fp10", "/foo", time.Now(), time.Now())
-	assert.Len(t, cache.sessions, 1)
-	session = cache.get(1, "fp", time.Now().Add(-time.Minute))
-	assert.Empty(t, session.Path)
-	session = cache.get(1, "fp10", time.Now().Add(-time.Minute))
+	cache.Put(1, "fp10", Session{Path: "/foo", Time: time.Now(), Session: time.Now()}, time.Minute)
+	assert.Equal(t, 10, cache.len())
+	session = cache.Get(1, "fp", time.Now().Add(-time.Minute))
+	assert.Equal(t, "/", session.Path, "the session must be kept, as it has been used recently")
+	session = cache.Get(1, "fp0", time.Now().Add(-time.Minute))
+	assert.Empty(t, session.Path, "the least recently used session must be evicted")
+	session = cache.Get(1, "fp10", time.Now().Add(-time.Minute))
 	assert.Equal(t, "/foo", session.Path)
 }
+
+func TestSessionCacheExpiry(t *testing.T) {
+	cache := NewSessionCache(NewMockClient(), 10)
+	defer cache.Stop()
+	cache.Put(1, "fp", Session{Path: "/", Time: time.Now(), Session: time.Now()}, time.Millisecond)
+	cache.Put(1, "fp2", Session{Path: "/", Time: time.Now(), Session: time.Now()}, time.Minute)
+	cache.Put(1, "fp3", Session{Path: "/", Time: time.Now(), Session: time.Now()}, time.Millisecond)
+	time.Sleep(time.Millisecond * 5)
+	session := cache.Get(1, "fp", time.Time{})
+	assert.Empty(t, session.Path)
+	assert.Equal(t, 2, cache.len())
+	cache.sweep(time.Now())
+	assert.Equal(t, 1, cache.len())
+	session = cache.Get(1, "fp2", time.Time{})
+	assert.Equal(t, "/", session.Path)
+	cache.sweep(time.Now().Add(time.Minute))
+	assert.Equal(t, 0, cache.len())
+}
+
+func TestSessionCacheShards(t *testing.T) {
+	cache := NewSessionCache(NewMockClient(), 1)
+	assert.Len(t, cache.shards, 1)
+	cache.Stop()
+	cache = NewSessionCache(NewMockClient(), minSessionCacheShardSize*4)
+	assert.Len(t, cache.shards, 4)
+	cache.Stop()
+	cache = NewSessionCache(NewMockClient(), 0)
+	defer cache.Stop()
+	assert.Len(t, cache.shards, maxSessionCacheShards)
+
+	for i := 0; i < defaultMaxSessions*2; i++ {
+		cache.Put(1, fmt.Sprintf("fp%d", i), Session{Path: "/", Time: time.Now(), Session: time.Now()}, time.Minute)
+	}
+
+	assert.True(t, cache.len() <= defaultMaxSessions+len(cache.shards))
+
+	for _, shard := range cache.shards {
+		assert.True(t, shard.lru.Len() > 0)
+	}
+}
+
+func TestSessionCacheMetrics(t *testing.T) {
+	metrics := newTestMetrics()
+	cache := NewSessionCache(NewMockClient(), 2)
+	defer cache.Stop()
+	cache.metrics = metrics
+	cache.Get(1, "fp", time.Now().Add(-time.Minute))
+	cache.Put(1, "fp", Session{Path: "/", Time: time.Now(), Session: time.Now()}, time.Minute)
+	cache.Get(1, "fp", time.Now().Add(-time.Minute))
+	cache.Put(1, "fp2", Session{Path: "/", Time: time.Now(), Session: time.Now()}, time.Millisecond)
+	cache.Put(1, "fp3", Session{Path: "/", Time: time.Now(), Session: time.Now()}, time.Minute)
+	cache.Put(1, "fp4", Session{Path: "/", Time: time.Now(), Session: time.Now()}, time.Millisecond)
+	cache.sweep(time.Now().Add(time.Second))
+	assert.Equal(t, 1, metrics.sessionCache[SessionCacheHit])
+	assert.Equal(t, 1, metrics.sessionCache[SessionCacheMiss])
+	assert.Equal(t, 2, metrics.sessionCache[SessionCacheEviction])
+	assert.Equal(t, 1, metrics.sessionCache[SessionCacheExpiry])
+}
+
+func TestSessionCacheSweeper(t *testing.T) {
+	cache := NewSessionCache(NewMockClient(), 10)
+	cache.Stop()
+	cache.Stop()
+	cache.StartSweeper(time.Millisecond)
+	assert.Nil(t, cache.cancel, "the sweeper must not be started after the cache has been stopped")
+	cache = NewSessionCache(NewMockClient(), 10)
+	cache.StartSweeper(time.Millisecond)
+	cache.StartSweeper(time.Millisecond)
+	cache.Put(1, "fp", Session{Path: "/", Time: time.Now(), Session: time.Now()}, time.Millisecond)
+	cache.Put(1, "fp2", Session{Path: "/", Time: time.Now(), Session: time.Now()}, time.Minute)
+	assert.Eventually(t, func() bool {
+		return cache.len() == 1
+	}, time.Second, time.Millisecond)
+	cache.Stop()
+	cache.Stop()
+}
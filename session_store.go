@@ -0,0 +1,18 @@
+package pirsch
+
+import (
+	"time"
+)
+
+// SessionStore looks up and stores the sessions used to group hits of a visitor and to calculate the time on page.
+// The SessionCache is the default implementation, which keeps the sessions in memory.
+// Use the RedisSessionStore (or your own implementation) to share the sessions between multiple instances of your application.
+// The implementation must be thread-safe.
+type SessionStore interface {
+	// Get returns the session for given client ID and fingerprint, if it has been active after maxAge.
+	// Sessions that are not stored must be looked up in the Store. An empty session is returned if there is none.
+	Get(clientID int64, fingerprint string, maxAge time.Time) Session
+
+	// Put stores the session for given client ID and fingerprint. The session must be kept for at least maxAge.
+	Put(clientID int64, fingerprint string, session Session, maxAge time.Duration)
+}
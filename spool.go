@@ -0,0 +1,418 @@
+package pirsch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	spoolHits          = "hits"
+	spoolEvents        = "events"
+	spoolSegmentExt    = ".seg"
+	spoolActiveExt     = ".tmp"
+	spoolLogExt        = ".log"
+	spoolDeadExt       = ".dead"
+	spoolMaxLineLength = 1024 * 1024
+	spoolMaxLogSize    = 1024 * 1024
+)
+
+// spool is a write-ahead buffer for hits and events on disk.
+// Each hit and event is appended to a journal log when it is enqueued, and acknowledged once its batch has been saved.
+// Journal logs are rotated by size or time, and removed once all of their entries have been acknowledged.
+// Batches that could not be saved are written to their own segment file, to be saved later by the replay loop.
+// Hits and events spilled because the queue was full are appended to a spill log, which becomes a segment once it is rotated.
+// While a segment is being saved, it has the .tmp extension, so that it won't be replayed at the same time.
+// Segments that failed to be saved too often are moved to a dead letter file with the .dead extension, which is never replayed.
+// Logs are synced to disk when they are rotated, so that a crash of the process doesn't lose any entries, but a crash of the system might.
+type spool struct {
+	dir      string
+	seq      uint64
+	attempts map[string]int
+	journals map[string]*spoolLog
+	spills   map[string]*spoolLog
+	sealed   map[string]*spoolLog
+	logger   *log.Logger
+	m        sync.Mutex
+}
+
+// spoolLog is an append-only log of hits or events.
+type spoolLog struct {
+	path    string
+	file    *os.File
+	size    int
+	created time.Time
+
+	// pending is the number of entries in a journal log that have not been acknowledged yet
+	pending int
+}
+
+// newSpool opens the spool in given directory, creating it if required.
+// Segments that were being saved and logs that were being written when the process stopped are marked as unsaved,
+// so that they will be replayed. This includes the hits and events that were still in the queue.
+func newSpool(dir string, logger *log.Logger) (*spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	for _, ext := range []string{spoolActiveExt, spoolLogExt} {
+		active, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range active {
+			if err := os.Rename(file, strings.TrimSuffix(file, ext)+spoolSegmentExt); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &spool{
+		dir:      dir,
+		attempts: make(map[string]int),
+		journals: make(map[string]*spoolLog),
+		spills:   make(map[string]*spoolLog),
+		sealed:   make(map[string]*spoolLog),
+		logger:   logger,
+	}, nil
+}
+
+// journalHit appends given hit to the journal log and returns its path, which must be passed to ack once the hit has been saved.
+func (s *spool) journalHit(hit Hit) (string, error) {
+	return s.append(s.journals, spoolHits, hit, true)
+}
+
+// journalEvent appends given event to the journal log and returns its path, which must be passed to ack once the event has been saved.
+func (s *spool) journalEvent(event Event) (string, error) {
+	return s.append(s.journals, spoolEvents, event, true)
+}
+
+// spillHit appends given hit to the spill log, to be saved by the replay loop once the log has been rotated.
+func (s *spool) spillHit(hit Hit) error {
+	_, err := s.append(s.spills, spoolHits, hit, false)
+	return err
+}
+
+// spillEvent appends given event to the spill log, to be saved by the replay loop once the log has been rotated.
+func (s *spool) spillEvent(event Event) error {
+	_, err := s.append(s.spills, spoolEvents, event, false)
+	return err
+}
+
+func (s *spool) append(logs map[string]*spoolLog, kind string, entry interface{}, journal bool) (string, error) {
+	data, err := json.Marshal(entry)
+
+	if err != nil {
+		return "", err
+	}
+
+	data = append(data, '\n')
+	s.m.Lock()
+	defer s.m.Unlock()
+	l := logs[kind]
+
+	if l == nil {
+		path := filepath.Join(s.dir, s.name(kind, spoolLogExt))
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0600)
+
+		if err != nil {
+			return "", err
+		}
+
+		l = &spoolLog{
+			path:    path,
+			file:    file,
+			created: time.Now(),
+		}
+		logs[kind] = l
+	}
+
+	// an incomplete line is skipped when the log is read
+	if _, err := l.file.Write(data); err != nil {
+		return "", err
+	}
+
+	l.size += len(data)
+
+	if journal {
+		l.pending++
+	}
+
+	if l.size >= spoolMaxLogSize {
+		delete(logs, kind)
+		s.seal(l, journal)
+	}
+
+	return l.path, nil
+}
+
+// ack acknowledges an entry of given journal log after it has been saved, spooled as a segment, or dropped.
+func (s *spool) ack(journal string) {
+	if journal == "" {
+		return
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for _, l := range s.journals {
+		if l.path == journal {
+			l.pending--
+			return
+		}
+	}
+
+	if l, ok := s.sealed[journal]; ok {
+		l.pending--
+
+		if l.pending <= 0 {
+			delete(s.sealed, journal)
+			s.removeLog(l)
+		}
+	}
+}
+
+// rotate seals all logs created at least the given duration ago,
+// so that spilled hits and events are saved and journal logs can be removed.
+func (s *spool) rotate(maxAge time.Duration) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	now := time.Now()
+
+	for kind, l := range s.journals {
+		if now.Sub(l.created) >= maxAge {
+			delete(s.journals, kind)
+			s.seal(l, true)
+		}
+	}
+
+	for kind, l := range s.spills {
+		if now.Sub(l.created) >= maxAge {
+			delete(s.spills, kind)
+			s.seal(l, false)
+		}
+	}
+}
+
+// close seals all logs. Journal logs with unacknowledged entries are kept to be replayed when the spool is opened again.
+func (s *spool) close() {
+	s.rotate(0)
+}
+
+// seal syncs and closes given log. A spill log becomes a segment, a journal log is removed once all entries have been acknowledged.
+func (s *spool) seal(l *spoolLog, journal bool) {
+	if err := l.file.Sync(); err != nil {
+		s.logger.Printf("error syncing spool log: %s", err)
+	}
+
+	if err := l.file.Close(); err != nil {
+		s.logger.Printf("error closing spool log: %s", err)
+	}
+
+	if !journal {
+		if err := os.Rename(l.path, strings.TrimSuffix(l.path, spoolLogExt)+spoolSegmentExt); err != nil {
+			s.logger.Printf("error rotating spool log: %s", err)
+		}
+	} else if l.pending <= 0 {
+		s.removeLog(l)
+	} else {
+		s.sealed[l.path] = l
+	}
+}
+
+func (s *spool) removeLog(l *spoolLog) {
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		s.logger.Printf("error removing spool log: %s", err)
+	}
+}
+
+// writeHits writes given hits to a new active segment and returns its path.
+func (s *spool) writeHits(hits []Hit) (string, error) {
+	entries := make([]interface{}, len(hits))
+
+	for i := range hits {
+		entries[i] = hits[i]
+	}
+
+	return s.write(spoolHits, entries)
+}
+
+// writeEvents writes given events to a new active segment and returns its path.
+func (s *spool) writeEvents(events []Event) (string, error) {
+	entries := make([]interface{}, len(events))
+
+	for i := range events {
+		entries[i] = events[i]
+	}
+
+	return s.write(spoolEvents, entries)
+}
+
+func (s *spool) write(kind string, entries []interface{}) (string, error) {
+	path := filepath.Join(s.dir, s.name(kind, spoolActiveExt))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0600)
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.writeEntries(file, entries); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+
+	return path, nil
+}
+
+// writeEntries writes the entries as JSON lines and closes the file.
+func (s *spool) writeEntries(file *os.File, entries []interface{}) error {
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// remove removes given segment after it has been saved.
+func (s *spool) remove(segment string) error {
+	s.forget(segment)
+	return os.Remove(segment)
+}
+
+// failed counts a failed attempt to save given segment and returns the number of failed attempts so far.
+func (s *spool) failed(segment string) int {
+	s.m.Lock()
+	defer s.m.Unlock()
+	key := s.segmentKey(segment)
+	s.attempts[key]++
+	return s.attempts[key]
+}
+
+// bury moves given active segment to a dead letter file, so that it won't be replayed anymore.
+func (s *spool) bury(segment string) error {
+	if err := os.Rename(segment, strings.TrimSuffix(segment, spoolActiveExt)+spoolDeadExt); err != nil {
+		return err
+	}
+
+	s.forget(segment)
+	return nil
+}
+
+func (s *spool) forget(segment string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	delete(s.attempts, s.segmentKey(segment))
+}
+
+// release marks given active segment as unsaved.
+func (s *spool) release(segment string) error {
+	return os.Rename(segment, strings.TrimSuffix(segment, spoolActiveExt)+spoolSegmentExt)
+}
+
+// acquire marks given unsaved segment as active and returns its new path.
+func (s *spool) acquire(segment string) (string, error) {
+	path := strings.TrimSuffix(segment, spoolSegmentExt) + spoolActiveExt
+
+	if err := os.Rename(segment, path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// segments returns the unsaved segments in the order they were written.
+func (s *spool) segments() ([]string, error) {
+	segments, err := filepath.Glob(filepath.Join(s.dir, "*"+spoolSegmentExt))
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return s.segmentName(segments[i]) < s.segmentName(segments[j])
+	})
+	return segments, nil
+}
+
+// read returns the hits or events stored in given segment.
+// Lines that cannot be read (because the process stopped while writing the segment) are skipped.
+func (s *spool) read(segment string) ([]Hit, []Event, error) {
+	file, err := os.Open(segment)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer file.Close()
+	events := strings.HasPrefix(filepath.Base(segment), spoolEvents)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 4096), spoolMaxLineLength)
+	var hitList []Hit
+	var eventList []Event
+
+	for scanner.Scan() {
+		if events {
+			var event Event
+
+			if err := json.Unmarshal(scanner.Bytes(), &event); err == nil {
+				eventList = append(eventList, event)
+			}
+		} else {
+			var hit Hit
+
+			if err := json.Unmarshal(scanner.Bytes(), &hit); err == nil {
+				hitList = append(hitList, hit)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return hitList, eventList, nil
+}
+
+// name returns a new file name for given kind and extension.
+// The files are named by time and sequence number, so that they are replayed in order.
+func (s *spool) name(kind, ext string) string {
+	return fmt.Sprintf("%s-%020d-%010d%s", kind, time.Now().UnixNano(), atomic.AddUint64(&s.seq, 1), ext)
+}
+
+func (s *spool) segmentName(segment string) string {
+	// strip the kind, so that hits and events are sorted by time
+	name := filepath.Base(segment)
+	return name[strings.Index(name, "-")+1:]
+}
+
+// segmentKey returns the name of given segment without extension, which stays the same while it is acquired and released.
+func (s *spool) segmentKey(segment string) string {
+	name := filepath.Base(segment)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
@@ -0,0 +1,167 @@
+package pirsch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpool(t *testing.T) {
+	s, err := newSpool(t.TempDir(), logger)
+	assert.NoError(t, err)
+	hitSegment, err := s.writeHits([]Hit{{Fingerprint: "fp1", Path: "/"}, {Fingerprint: "fp2", Path: "/foo"}})
+	assert.NoError(t, err)
+	eventSegment, err := s.writeEvents([]Event{{Name: "event", MetaKeys: []string{"key"}, MetaValues: []string{"value"}, Hit: Hit{Fingerprint: "fp1"}}})
+	assert.NoError(t, err)
+	segments, err := s.segments()
+	assert.NoError(t, err)
+	assert.Empty(t, segments)
+	assert.NoError(t, s.release(hitSegment))
+	assert.NoError(t, s.release(eventSegment))
+	segments, err = s.segments()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 2)
+	hitSegment, err = s.acquire(segments[0])
+	assert.NoError(t, err)
+	hits, events, err := s.read(hitSegment)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 2)
+	assert.Empty(t, events)
+	assert.Equal(t, "fp1", hits[0].Fingerprint)
+	assert.Equal(t, "/foo", hits[1].Path)
+	assert.NoError(t, s.remove(hitSegment))
+	hits, events, err = s.read(segments[1])
+	assert.NoError(t, err)
+	assert.Empty(t, hits)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "event", events[0].Name)
+	assert.Equal(t, []string{"key"}, events[0].MetaKeys)
+	assert.Equal(t, "fp1", events[0].Fingerprint)
+	segments, err = s.segments()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 1)
+}
+
+func TestSpool_Recover(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSpool(dir, logger)
+	assert.NoError(t, err)
+	segment, err := s.writeHits([]Hit{{Fingerprint: "fp1"}, {Fingerprint: "fp2"}})
+	assert.NoError(t, err)
+	file, err := os.OpenFile(segment, os.O_WRONLY|os.O_APPEND, 0600)
+	assert.NoError(t, err)
+	_, err = file.WriteString(`{"Fingerprint":"fp`)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+	s, err = newSpool(dir, logger)
+	assert.NoError(t, err)
+	segments, err := s.segments()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 1)
+	hits, _, err := s.read(segments[0])
+	assert.NoError(t, err)
+	assert.Len(t, hits, 2)
+}
+
+func TestSpool_Bury(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSpool(dir, logger)
+	assert.NoError(t, err)
+	segment, err := s.writeHits([]Hit{{Fingerprint: "fp1"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.failed(segment))
+	assert.NoError(t, s.release(segment))
+	segments, err := s.segments()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 1)
+	segment, err = s.acquire(segments[0])
+	assert.NoError(t, err)
+	assert.Equal(t, 2, s.failed(segment))
+	assert.NoError(t, s.bury(segment))
+	segments, err = s.segments()
+	assert.NoError(t, err)
+	assert.Empty(t, segments)
+	dead, err := filepath.Glob(filepath.Join(dir, "*"+spoolDeadExt))
+	assert.NoError(t, err)
+	assert.Len(t, dead, 1)
+	s, err = newSpool(dir, logger)
+	assert.NoError(t, err)
+	segments, err = s.segments()
+	assert.NoError(t, err)
+	assert.Empty(t, segments)
+}
+
+func TestSpool_Journal(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSpool(dir, logger)
+	assert.NoError(t, err)
+	journal, err := s.journalHit(Hit{Fingerprint: "fp1"})
+	assert.NoError(t, err)
+	_, err = s.journalHit(Hit{Fingerprint: "fp2"})
+	assert.NoError(t, err)
+	s.ack(journal)
+	s.rotate(0)
+	_, err = os.Stat(journal)
+	assert.NoError(t, err, "the journal must be kept until all entries have been acknowledged")
+	s.ack(journal)
+	_, err = os.Stat(journal)
+	assert.True(t, os.IsNotExist(err))
+
+	// unacknowledged entries are replayed when the spool is opened again
+	_, err = s.journalEvent(Event{Name: "event", Hit: Hit{Fingerprint: "fp1"}})
+	assert.NoError(t, err)
+	s, err = newSpool(dir, logger)
+	assert.NoError(t, err)
+	segments, err := s.segments()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 1)
+	_, events, err := s.read(segments[0])
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "event", events[0].Name)
+}
+
+func TestSpool_Spill(t *testing.T) {
+	s, err := newSpool(t.TempDir(), logger)
+	assert.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, s.spillHit(Hit{Fingerprint: "fp", Path: "/"}))
+	}
+
+	assert.NoError(t, s.spillEvent(Event{Name: "event"}))
+	segments, err := s.segments()
+	assert.NoError(t, err)
+	assert.Empty(t, segments, "the spill log must not be replayed before it has been rotated")
+	s.rotate(time.Minute)
+	segments, err = s.segments()
+	assert.NoError(t, err)
+	assert.Empty(t, segments)
+	s.rotate(0)
+	segments, err = s.segments()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 2, "spilled hits and events must be appended to one log each")
+	hits, _, err := s.read(segments[0])
+	assert.NoError(t, err)
+	assert.Len(t, hits, 100)
+	_, events, err := s.read(segments[1])
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestSpool_RotateSize(t *testing.T) {
+	s, err := newSpool(t.TempDir(), logger)
+	assert.NoError(t, err)
+	hit := Hit{Path: "/" + strings.Repeat("a", 1024)}
+
+	for i := 0; i < spoolMaxLogSize/1024+1; i++ {
+		assert.NoError(t, s.spillHit(hit))
+	}
+
+	segments, err := s.segments()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 1)
+}
@@ -0,0 +1,421 @@
+package pirsch
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"github.com/jmoiron/sqlx"
+	"log"
+	sqlitedriver "modernc.org/sqlite"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	sqliteTimeFormat = "2006-01-02 15:04:05.999999999"
+	sqliteDateFormat = "2006-01-02"
+)
+
+var (
+	sqliteRegexCache    sync.Map
+	sqliteLocationCache sync.Map
+)
+
+func init() {
+	sqlitedriver.MustRegisterDeterministicScalarFunction("pirsch_match", 2, sqliteMatch)
+	sqlitedriver.MustRegisterDeterministicScalarFunction("pirsch_timezone", 2, sqliteTimezone)
+}
+
+// SQLiteClient is a SQLite database client.
+// It uses a pure Go driver, so it doesn't require cgo or an external database server.
+// The database schema must be created by calling Migrate with a sqlite:// connection string.
+type SQLiteClient struct {
+	sqlx.DB
+	logger *log.Logger
+}
+
+// NewSQLiteClient returns a new client for given database file.
+// The logger is optional.
+func NewSQLiteClient(file string, logger *log.Logger) (*SQLiteClient, error) {
+	c, err := sqlx.Open("sqlite", file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite does not support concurrent writes
+	c.SetMaxOpenConns(1)
+
+	if err := c.Ping(); err != nil {
+		return nil, err
+	}
+
+	if logger == nil {
+		logger = log.New(os.Stdout, "[pirsch] ", log.LstdFlags)
+	}
+
+	return &SQLiteClient{
+		*c,
+		logger,
+	}, nil
+}
+
+// SaveHits implements the Store interface.
+func (client *SQLiteClient) SaveHits(hits []Hit) error {
+	tx, err := client.Beginx()
+
+	if err != nil {
+		return err
+	}
+
+	query, err := tx.Prepare(`INSERT INTO "hit" (client_id, fingerprint, "time", "session", previous_time_on_page_seconds,
+		user_agent, path, url, title, language, language_region, country_code, region, city, asn, as_organization, referrer, referrer_name, referrer_icon, os, os_version,
+		browser, browser_version, desktop, mobile, device_type, screen_width, screen_height, screen_class,
+		utm_source, utm_medium, utm_campaign, utm_content, utm_term) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
+
+	if err != nil {
+		client.rollback(tx, "hits")
+		return err
+	}
+
+	for _, hit := range hits {
+		_, err := query.Exec(hit.ClientID,
+			hit.Fingerprint,
+			client.time(hit.Time),
+			client.time(hit.Session),
+			hit.PreviousTimeOnPageSeconds,
+			hit.UserAgent,
+			hit.Path,
+			hit.URL,
+			hit.Title,
+			hit.Language,
+			hit.LanguageRegion,
+			hit.CountryCode,
+			hit.Region,
+			hit.City,
+			hit.ASN,
+			hit.ASOrganization,
+			hit.Referrer,
+			hit.ReferrerName,
+			hit.ReferrerIcon,
+			hit.OS,
+			hit.OSVersion,
+			hit.Browser,
+			hit.BrowserVersion,
+			hit.Desktop,
+			hit.Mobile,
+			hit.DeviceType,
+			hit.ScreenWidth,
+			hit.ScreenHeight,
+			hit.ScreenClass,
+			hit.UTMSource,
+			hit.UTMMedium,
+			hit.UTMCampaign,
+			hit.UTMContent,
+			hit.UTMTerm)
+
+		if err != nil {
+			client.rollback(tx, "hits")
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveEvents implements the Store interface.
+func (client *SQLiteClient) SaveEvents(events []Event) error {
+	tx, err := client.Beginx()
+
+	if err != nil {
+		return err
+	}
+
+	query, err := tx.Prepare(`INSERT INTO "event" (client_id, fingerprint, "time", "session", previous_time_on_page_seconds,
+		user_agent, path, url, title, language, language_region, country_code, region, city, asn, as_organization, referrer, referrer_name, referrer_icon, os, os_version,
+		browser, browser_version, desktop, mobile, device_type, screen_width, screen_height, screen_class,
+		utm_source, utm_medium, utm_campaign, utm_content, utm_term,
+		event_name, event_duration_seconds, event_meta_keys, event_meta_values) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
+
+	if err != nil {
+		client.rollback(tx, "events")
+		return err
+	}
+
+	for _, event := range events {
+		metaKeys, err := client.array(event.MetaKeys)
+
+		if err != nil {
+			client.rollback(tx, "events")
+			return err
+		}
+
+		metaValues, err := client.array(event.MetaValues)
+
+		if err != nil {
+			client.rollback(tx, "events")
+			return err
+		}
+
+		_, err = query.Exec(event.ClientID,
+			event.Fingerprint,
+			client.time(event.Time),
+			client.time(event.Session),
+			event.PreviousTimeOnPageSeconds,
+			event.UserAgent,
+			event.Path,
+			event.URL,
+			event.Title,
+			event.Language,
+			event.LanguageRegion,
+			event.CountryCode,
+			event.Region,
+			event.City,
+			event.ASN,
+			event.ASOrganization,
+			event.Referrer,
+			event.ReferrerName,
+			event.ReferrerIcon,
+			event.OS,
+			event.OSVersion,
+			event.Browser,
+			event.BrowserVersion,
+			event.Desktop,
+			event.Mobile,
+			event.DeviceType,
+			event.ScreenWidth,
+			event.ScreenHeight,
+			event.ScreenClass,
+			event.UTMSource,
+			event.UTMMedium,
+			event.UTMCampaign,
+			event.UTMContent,
+			event.UTMTerm,
+			event.Name,
+			event.DurationSeconds,
+			metaKeys,
+			metaValues)
+
+		if err != nil {
+			client.rollback(tx, "events")
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveBotHits implements the Store interface.
+func (client *SQLiteClient) SaveBotHits(hits []BotHit) error {
+	tx, err := client.Beginx()
+
+	if err != nil {
+		return err
+	}
+
+	query, err := tx.Prepare(`INSERT INTO "bot_hit" (client_id, "time", user_agent, path, url, bot_reason, bot_rule) VALUES (?,?,?,?,?,?,?)`)
+
+	if err != nil {
+		client.rollback(tx, "bot hits")
+		return err
+	}
+
+	for _, hit := range hits {
+		_, err := query.Exec(hit.ClientID,
+			client.time(hit.Time),
+			hit.UserAgent,
+			hit.Path,
+			hit.URL,
+			string(hit.Reason),
+			hit.Rule)
+
+		if err != nil {
+			client.rollback(tx, "bot hits")
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Session implements the Store interface.
+func (client *SQLiteClient) Session(clientID int64, fingerprint string, maxAge time.Time) (Session, error) {
+	query := `SELECT path, "time", "session" FROM "hit" WHERE client_id = ? AND fingerprint = ? AND "time" > ? ORDER BY "time" DESC LIMIT 1`
+	data := struct {
+		Path    string
+		Time    time.Time
+		Session time.Time
+	}{}
+
+	if err := client.DB.Get(&data, query, clientID, fingerprint, client.time(maxAge)); err != nil && err != sql.ErrNoRows {
+		client.logger.Printf("error reading session: %s", err)
+		return Session{}, err
+	}
+
+	return data, nil
+}
+
+// Query implements the Store interface.
+// SQLite returns dates as strings, so the results are converted to the types of the fields by scanQueryResults.
+func (client *SQLiteClient) Query(results interface{}, query *Query) error {
+	q, args, err := buildSQLQuery(sqlite, query)
+
+	if err != nil {
+		return err
+	}
+
+	rows, err := client.DB.Queryx(q, client.args(args)...)
+
+	if err != nil {
+		client.logger.Printf("error selecting results: %s", err)
+		return err
+	}
+
+	defer rows.Close()
+	var list []queryResult
+
+	for rows.Next() {
+		result := make(queryResult)
+
+		if err := rows.MapScan(result); err != nil {
+			return err
+		}
+
+		list = append(list, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return scanQueryResults(results, list)
+}
+
+// rollback rolls back given transaction, so that it doesn't hold on to the only connection, and logs errors.
+func (client *SQLiteClient) rollback(tx *sqlx.Tx, kind string) {
+	if err := tx.Rollback(); err != nil {
+		client.logger.Printf("error rolling back transaction to save %s: %s", kind, err)
+	}
+}
+
+// time formats given time in UTC, so that it can be compared to the times stored in the database.
+func (client *SQLiteClient) time(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+func (client *SQLiteClient) args(args []interface{}) []interface{} {
+	result := make([]interface{}, len(args))
+
+	for i := range args {
+		if t, ok := args[i].(time.Time); ok {
+			result[i] = client.time(t)
+		} else {
+			result[i] = args[i]
+		}
+	}
+
+	return result
+}
+
+func (client *SQLiteClient) array(values []string) (string, error) {
+	if values == nil {
+		return "[]", nil
+	}
+
+	out, err := json.Marshal(values)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+func sqliteMatch(ctx *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+	value, _ := args[0].(string)
+	pattern, _ := args[1].(string)
+	regex, ok := sqliteRegexCache.Load(pattern)
+
+	if !ok {
+		r, err := regexp.Compile(pattern)
+
+		if err != nil {
+			return nil, err
+		}
+
+		regex, _ = sqliteRegexCache.LoadOrStore(pattern, r)
+	}
+
+	if regex.(*regexp.Regexp).MatchString(value) {
+		return int64(1), nil
+	}
+
+	return int64(0), nil
+}
+
+// sqliteTimezone converts a time stored in UTC to the timezone returned by sqliteDialect.timezone.
+// SQLite only supports fixed offsets, so this is done per row to respect daylight saving time.
+func sqliteTimezone(ctx *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+	var t time.Time
+
+	switch value := args[0].(type) {
+	case string:
+		parsed, err := time.Parse(sqliteTimeFormat, value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		t = parsed
+	case time.Time:
+		t = value
+	default:
+		return nil, nil
+	}
+
+	name, _ := args[1].(string)
+	location, err := loadSQLiteLocation(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return t.In(location).Format(sqliteDateFormat + " 15:04:05"), nil
+}
+
+// loadSQLiteLocation returns the location for given timezone name or offset in seconds.
+func loadSQLiteLocation(name string) (*time.Location, error) {
+	if location, ok := sqliteLocationCache.Load(name); ok {
+		return location.(*time.Location), nil
+	}
+
+	var location *time.Location
+
+	if offset, err := strconv.Atoi(name); err == nil {
+		location = time.FixedZone(name, offset)
+	} else {
+		location, err = time.LoadLocation(name)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sqliteLocationCache.Store(name, location)
+	return location, nil
+}
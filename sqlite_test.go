@@ -0,0 +1,137 @@
+package pirsch
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteClient(t *testing.T) {
+	client := newTestSQLiteClient(t)
+	now := time.Now().UTC()
+	assert.NoError(t, client.SaveHits([]Hit{
+		{ClientID: 1, Fingerprint: "fp", Time: now.Add(-time.Second * 20), Session: now.Add(-time.Second * 20), Path: "/path1", Desktop: true},
+		{ClientID: 1, Fingerprint: "fp", Time: now, Session: now, Path: "/path2"},
+		{ClientID: 1, Fingerprint: "fp", Time: now.Add(-time.Second * 10), Session: now.Add(-time.Second * 10), Path: "/path3"},
+	}))
+	assert.NoError(t, client.SaveEvents([]Event{
+		{Name: "event", MetaKeys: []string{"key"}, MetaValues: []string{"value"}, Hit: Hit{ClientID: 1, Fingerprint: "fp", Time: now}},
+		{Name: "event", Hit: Hit{ClientID: 1, Fingerprint: "fp", Time: now}},
+	}))
+	session, err := client.Session(1, "fp", time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, "/path2", session.Path)
+	assert.Equal(t, now.Unix(), session.Time.Unix())
+	assert.Equal(t, now.Unix(), session.Session.Unix())
+	assert.NoError(t, client.SaveBotHits([]BotHit{{ClientID: 1, Time: now, Path: "/path1", Reason: IgnoreBot, Rule: "curl"}}))
+	testQuerySavedData(t, client, now)
+}
+
+func TestSQLiteClient_Analyzer(t *testing.T) {
+	client := newTestSQLiteClient(t)
+	assert.NoError(t, client.SaveHits([]Hit{
+		{Fingerprint: "fp1", Time: pastDay(2), Path: "/"},
+		{Fingerprint: "fp1", Time: pastDay(2).Add(time.Minute), Path: "/simple/page", PreviousTimeOnPageSeconds: 60},
+		{Fingerprint: "fp2", Time: pastDay(2), Path: "/"},
+		{Fingerprint: "fp3", Time: pastDay(0), Path: "/siMple/page/"},
+	}))
+	assert.NoError(t, client.SaveEvents([]Event{
+		{Name: "event", MetaKeys: []string{"status", "price"}, MetaValues: []string{"in", "34.56"}, Hit: Hit{Fingerprint: "fp1", Time: pastDay(0)}},
+		{Name: "event", MetaKeys: []string{"status"}, MetaValues: []string{"out"}, Hit: Hit{Fingerprint: "fp2", Time: pastDay(0)}},
+	}))
+	analyzer := NewAnalyzer(client)
+	visitors, err := analyzer.Visitors(&Filter{From: pastDay(3), To: pastDay(0)})
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 4)
+	assert.Equal(t, pastDay(3), visitors[0].Day)
+	assert.Equal(t, pastDay(2), visitors[1].Day)
+	assert.Equal(t, 2, visitors[1].Visitors)
+	assert.Equal(t, 3, visitors[1].Views)
+	assert.Equal(t, 1, visitors[1].Bounces)
+	assert.InDelta(t, 0.5, visitors[1].BounceRate, 0.01)
+	assert.Equal(t, 1, visitors[3].Visitors)
+	pages, err := analyzer.Pages(&Filter{PathPattern: "(?i)^/simple/.*"})
+	assert.NoError(t, err)
+	assert.Len(t, pages, 2)
+	timeOnPage, err := analyzer.AvgTimeOnPages(nil)
+	assert.NoError(t, err)
+	assert.Len(t, timeOnPage, 1)
+	assert.Equal(t, "/", timeOnPage[0].Path)
+	assert.Equal(t, 60, timeOnPage[0].AverageTimeSpentSeconds)
+	entries, err := analyzer.EntryPages(nil)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "/", entries[0].Path)
+	assert.Equal(t, 2, entries[0].Entries)
+	events, err := analyzer.Events(nil)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, 2, events[0].Visitors)
+	assert.ElementsMatch(t, []string{"status", "price"}, events[0].MetaKeys)
+	breakdown, err := analyzer.EventBreakdown(&Filter{EventName: "event", EventMetaKey: "status"})
+	assert.NoError(t, err)
+	assert.Len(t, breakdown, 2)
+	assert.Equal(t, "in", breakdown[0].MetaValue)
+	assert.Equal(t, "out", breakdown[1].MetaValue)
+}
+
+func TestSQLiteClient_TimezoneDST(t *testing.T) {
+	client := newTestSQLiteClient(t)
+	assert.NoError(t, client.SaveHits([]Hit{
+		{Fingerprint: "fp1", Time: time.Date(2023, 1, 15, 22, 30, 0, 0, time.UTC), Path: "/"}, // 23:30 CET (UTC+1)
+		{Fingerprint: "fp2", Time: time.Date(2023, 7, 1, 22, 30, 0, 0, time.UTC), Path: "/"},  // 00:30 CEST (UTC+2) on the next day
+	}))
+	tz, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	analyzer := NewAnalyzer(client)
+
+	// both dates must be converted using the offset in effect at that time, not the current one
+	visitors, err := analyzer.Visitors(&Filter{From: time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC), To: time.Date(2023, 1, 16, 0, 0, 0, 0, time.UTC), Timezone: tz})
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 2)
+	assert.Equal(t, 1, visitors[0].Visitors)
+	assert.Equal(t, 0, visitors[1].Visitors)
+	visitors, err = analyzer.Visitors(&Filter{From: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2023, 7, 2, 0, 0, 0, 0, time.UTC), Timezone: tz})
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 2)
+	assert.Equal(t, 0, visitors[0].Visitors)
+	assert.Equal(t, 1, visitors[1].Visitors)
+	hours, err := analyzer.VisitorHours(&Filter{From: time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC), To: time.Date(2023, 7, 2, 0, 0, 0, 0, time.UTC), Timezone: tz})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, hours[23].Visitors)
+	assert.Equal(t, 1, hours[0].Visitors)
+}
+
+func TestSQLiteClient_EntriesAndExits(t *testing.T) {
+	testQueryEntriesAndExits(t, newTestSQLiteClient(t))
+}
+
+func TestSQLiteClient_Context(t *testing.T) {
+	client := newTestSQLiteClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.QueryContext(ctx, `SELECT count(*) FROM "hit"`)
+	assert.ErrorIs(t, err, context.Canceled)
+	var day []string
+	assert.NoError(t, client.DB.Select(&day, `SELECT 'Monday' AS day`))
+	assert.Equal(t, []string{"Monday"}, day)
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	connection := "sqlite://" + filepath.Join(t.TempDir(), "pirsch.db")
+	assert.NoError(t, Migrate(connection))
+	assert.NoError(t, Migrate(connection))
+}
+
+func newTestSQLiteClient(t *testing.T) *SQLiteClient {
+	file := filepath.Join(t.TempDir(), "pirsch.db")
+	assert.NoError(t, Migrate("sqlite://"+file))
+	client, err := NewSQLiteClient(file, nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, client.Close())
+	})
+	return client
+}
@@ -12,17 +12,13 @@ type Store interface {
 	// SaveEvents saves given events.
 	SaveEvents([]Event) error
 
+	// SaveBotHits saves given bot hits.
+	SaveBotHits([]BotHit) error
+
 	// Session returns the last path, time, and session timestamp for given client, fingerprint, and maximum age.
 	Session(int64, string, time.Time) (Session, error)
 
-	// Count returns the number of results for given query.
-	Count(string, ...interface{}) (int, error)
-
-	// Get returns a single result for given query.
-	// The result must be a pointer.
-	Get(interface{}, string, ...interface{}) error
-
-	// Select returns the results for given query.
-	// The results must be a pointer to a slice.
-	Select(interface{}, string, ...interface{}) error
+	// Query returns the results for given query.
+	// The results must be a pointer to a slice of structs, with fields for the dimensions and metric columns.
+	Query(interface{}, *Query) error
 }
@@ -2,6 +2,7 @@ package pirsch
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -13,13 +14,60 @@ import (
 )
 
 const (
-	defaultWorkerBufferSize = 100
-	defaultWorkerTimeout    = time.Second * 10
-	maxWorkerTimeout        = time.Second * 60
+	defaultWorkerBufferSize      = 100
+	defaultWorkerTimeout         = time.Second * 10
+	maxWorkerTimeout             = time.Second * 60
+	defaultSpoolRetryInterval    = time.Second
+	defaultSpoolMaxRetryInterval = time.Minute
+	defaultSpoolMaxAttempts      = 100
 )
 
 var logger = log.New(os.Stdout, "[pirsch] ", log.LstdFlags)
 
+// ErrQueueFull is passed to TrackerConfig.OnDrop for hits and events that were dropped because the queue was full.
+var ErrQueueFull = errors.New("queue is full")
+
+// OverflowPolicy defines what the Tracker does with a hit or event if the queue is full,
+// because the workers cannot save them fast enough.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until there is space in the queue.
+	// This is the default, but it will block the caller while the Store is slow or unavailable.
+	OverflowBlock = OverflowPolicy(iota)
+
+	// OverflowDropNewest drops the hit or event that is being tracked.
+	OverflowDropNewest
+
+	// OverflowDropOldest drops the oldest hit or event in the queue to make space for the one that is being tracked.
+	OverflowDropOldest
+
+	// OverflowSpill appends the hit or event to the spool (see TrackerConfig.SpoolDir), to be saved later.
+	// Spilled hits and events are saved once the spill log is rotated (see TrackerConfig.SpoolRetryInterval).
+	// It is dropped if the spool is not enabled or cannot be written.
+	OverflowSpill
+)
+
+// EnqueueResult is the outcome of tracking a hit or event.
+type EnqueueResult int
+
+const (
+	// Enqueued means the hit or event has been added to the queue.
+	Enqueued = EnqueueResult(iota)
+
+	// Ignored means the request has been ignored, because it's a bot for example, or the Tracker has been stopped.
+	Ignored
+
+	// Dropped means the hit or event has been dropped, because the queue was full.
+	Dropped
+
+	// DroppedOldest means the hit or event has been added to the queue after the oldest one has been dropped.
+	DroppedOldest
+
+	// Spilled means the hit or event has been written to the spool, because the queue was full.
+	Spilled
+)
+
 // TrackerConfig is the optional configuration for the Tracker.
 type TrackerConfig struct {
 	// Worker sets the number of workers that are used to client hits.
@@ -43,15 +91,91 @@ type TrackerConfig struct {
 	ReferrerDomainBlacklistIncludesSubdomains bool
 
 	// MaxSessions sets the maximum size for the session cache.
+	// The least recently used sessions are evicted once it's full.
 	// If you leave it 0, the default will be used.
 	MaxSessions int
 
+	// SaltRotation sets how the salt passed to NewTracker is used to generate fingerprints.
+	// Rotating the salt daily prevents tracking visitors across days.
+	// The salt is rotated at midnight (UTC), sessions started before are continued for up to the SessionMaxAge.
+	// If you leave it 0, the salt is used as is.
+	SaltRotation SaltRotation
+
+	// IPExtractor sets the IPExtractor used for all hits and events, unless it's set in the HitOptions.
+	// It's recommended to set it, as the client IP cannot be spoofed using headers if your proxies are configured as trusted.
+	// If you leave it nil, the IP is read from a list of headers in a fixed order.
+	IPExtractor *IPExtractor
+
+	// AnonymizeIP enables a privacy mode, in which the IP is truncated to /24 (IPv4) or /48 (IPv6)
+	// before it is used to generate the fingerprint and look up the geo location (see AnonymizeIP).
+	// Note that this will merge visitors using the same User-Agent within the same network.
+	AnonymizeIP bool
+
+	// Fingerprinter sets the Fingerprinter used for all hits and events, unless it's set in the HitOptions.
+	// If you leave it nil, Fingerprint is used.
+	Fingerprinter Fingerprinter
+
+	// SessionStore sets the SessionStore used to look up sessions.
+	// If you leave it nil, a SessionCache is created using MaxSessions.
+	// Use a shared store, like the RedisSessionStore, if multiple instances of your application track the same website.
+	SessionStore SessionStore
+
 	// SessionMaxAge see HitOptions.SessionMaxAge.
 	SessionMaxAge time.Duration
 
-	// GeoDB enables/disabled mapping IPs to country codes.
+	// GeoDB enables/disabled mapping IPs to geo locations.
+	// It accepts any GeoResolver, like the GeoDB (MaxMind), CSVGeoResolver, CDNGeoResolver, or GeoResolverFunc.
 	// Can be set/updated at runtime by calling Tracker.SetGeoDB.
-	GeoDB *GeoDB
+	GeoDB GeoResolver
+
+	// SpoolDir enables the spool, a write-ahead buffer on disk, if set.
+	// Each hit and event is appended to a log in this directory when it is enqueued, and acknowledged once it has been saved.
+	// Batches that could not be saved are kept in this directory and saved later.
+	// Hits and events that have not been saved when the process stops or crashes, including those still in the queue,
+	// are saved when the next Tracker using the directory is created.
+	// The directory is created if it does not exist and must not be shared with other Trackers.
+	// The logs are synced to disk when they are rotated (every SpoolRetryInterval or 1 MB), so that hits and events
+	// survive a crash of the process, but the last ones might be lost if the system crashes.
+	// A hit or event might be saved twice if the process stops right after it has been saved.
+	SpoolDir string
+
+	// SpoolRetryInterval sets the interval to retry saving spooled batches.
+	// It is doubled after each failed attempt, up to the SpoolMaxRetryInterval, and reset once the batches have been saved.
+	// If you leave it 0, the default interval of one second is used.
+	SpoolRetryInterval time.Duration
+
+	// SpoolMaxRetryInterval sets the maximum interval to retry saving spooled batches.
+	// If you leave it 0, the default interval of one minute is used.
+	SpoolMaxRetryInterval time.Duration
+
+	// SpoolMaxAttempts sets how often saving a spooled batch is attempted while the Tracker is running.
+	// After the last attempt, the batch is passed to OnDrop and its segment file is renamed to the .dead extension,
+	// so that it does not block the batches spooled after it. Dead segments are kept, but never replayed.
+	// If you leave it 0, the default of 100 attempts is used.
+	SpoolMaxAttempts int
+
+	// RetryPolicy sets how often saving a batch of hits or events is retried if the Store returns an error.
+	// If you leave it nil, batches are not retried.
+	RetryPolicy *RetryPolicy
+
+	// OnDrop is called with the hits or events of a batch that could not be saved after all attempts, and the last error.
+	// It can be used to write the batch to a fallback queue or to alert on lost data.
+	// Batches that are kept in the spool (see SpoolDir) are not dropped, unless they could not be saved after SpoolMaxAttempts.
+	OnDrop func([]Hit, []Event, error)
+
+	// OverflowPolicy sets what happens to hits and events if the queue is full.
+	// If you leave it 0, Tracker.Hit and Tracker.Event block until there is space in the queue.
+	// Dropped hits and events are counted (see Tracker.Dropped) and passed to OnDrop together with ErrQueueFull.
+	// Note that OnDrop is called by Tracker.Hit and Tracker.Event in this case, so it should return quickly.
+	OverflowPolicy OverflowPolicy
+
+	// RecordBots enables saving requests classified as bots (see ClassifyRequest) as a BotHit, instead of just ignoring them.
+	// Bot hits are saved in batches by a separate worker and are not spooled. They are dropped if the queue is full,
+	// so that bots never block tracking.
+	RecordBots bool
+
+	// Metrics receives metrics about the Tracker, its SessionCache, and GeoDB lookups, if set.
+	Metrics MetricsCollector
 
 	// Logger is the log.Logger used for logging.
 	// The default log will be used printing to os.Stdout with "pirsch" in its prefix in case it is not set.
@@ -79,6 +203,32 @@ func (config *TrackerConfig) validate() {
 		config.SessionMaxAge = 0
 	}
 
+	if config.SpoolRetryInterval <= 0 {
+		config.SpoolRetryInterval = defaultSpoolRetryInterval
+	}
+
+	if config.SpoolMaxRetryInterval <= 0 {
+		config.SpoolMaxRetryInterval = defaultSpoolMaxRetryInterval
+	}
+
+	if config.SpoolMaxRetryInterval < config.SpoolRetryInterval {
+		config.SpoolMaxRetryInterval = config.SpoolRetryInterval
+	}
+
+	if config.SpoolMaxAttempts <= 0 {
+		config.SpoolMaxAttempts = defaultSpoolMaxAttempts
+	}
+
+	if config.RetryPolicy == nil {
+		config.RetryPolicy = &RetryPolicy{}
+	}
+
+	config.RetryPolicy.validate()
+
+	if config.Metrics == nil {
+		config.Metrics = noopMetrics{}
+	}
+
 	if config.Logger == nil {
 		config.Logger = logger
 	}
@@ -88,10 +238,17 @@ func (config *TrackerConfig) validate() {
 // Make sure you call Stop to make sure the hits get stored before shutting down the server.
 type Tracker struct {
 	store                                     Store
+	sessionStore                              SessionStore
 	sessionCache                              *SessionCache
-	salt                                      string
+	salt                                      *saltRotator
+	fingerprinter                             Fingerprinter
+	ipExtractor                               *IPExtractor
+	anonymizeIP                               bool
+	saltCancel                                context.CancelFunc
 	hits                                      chan Hit
 	events                                    chan Event
+	botHits                                   chan BotHit
+	recordBots                                bool
 	stopped                                   int32
 	worker                                    int
 	workerBufferSize                          int
@@ -101,13 +258,25 @@ type Tracker struct {
 	referrerDomainBlacklist                   []string
 	referrerDomainBlacklistIncludesSubdomains bool
 	sessionMaxAge                             time.Duration
-	geoDB                                     *GeoDB
+	geoDB                                     GeoResolver
 	geoDBMutex                                sync.RWMutex
+	spool                                     *spool
+	spoolRetryInterval                        time.Duration
+	spoolMaxRetryInterval                     time.Duration
+	spoolMaxAttempts                          int
+	spoolCancel                               context.CancelFunc
+	spoolDone                                 chan bool
+	retryPolicy                               *RetryPolicy
+	onDrop                                    func([]Hit, []Event, error)
+	overflowPolicy                            OverflowPolicy
+	dropped                                   uint64
+	spilled                                   uint64
+	metrics                                   MetricsCollector
 	logger                                    *log.Logger
 }
 
 // NewTracker creates a new tracker for given client, salt and config.
-// Pass nil for the config to use the defaults. The salt is mandatory.
+// Pass nil for the config to use the defaults. The salt is mandatory, unless the TrackerConfig.SaltRotation is set to SaltRandom.
 // It creates the same amount of workers for both, hits and events.
 func NewTracker(client Store, salt string, config *TrackerConfig) *Tracker {
 	if config == nil {
@@ -115,35 +284,92 @@ func NewTracker(client Store, salt string, config *TrackerConfig) *Tracker {
 	}
 
 	config.validate()
+	var sessionCache *SessionCache
+
+	if config.SessionStore == nil {
+		sessionCache = NewSessionCache(client, config.MaxSessions)
+		sessionCache.metrics = config.Metrics
+		sessionCache.StartSweeper(sessionCacheSweepInterval)
+		config.SessionStore = sessionCache
+	}
+
 	tracker := &Tracker{
 		store:                   client,
-		sessionCache:            NewSessionCache(client, config.MaxSessions),
-		salt:                    salt,
+		sessionStore:            config.SessionStore,
+		sessionCache:            sessionCache,
+		salt:                    newSaltRotator(salt, config.SaltRotation),
+		fingerprinter:           config.Fingerprinter,
+		ipExtractor:             config.IPExtractor,
+		anonymizeIP:             config.AnonymizeIP,
 		hits:                    make(chan Hit, config.Worker*config.WorkerBufferSize),
 		events:                  make(chan Event, config.Worker*config.WorkerBufferSize),
+		recordBots:              config.RecordBots,
 		worker:                  config.Worker,
 		workerBufferSize:        config.WorkerBufferSize,
 		workerTimeout:           config.WorkerTimeout,
 		workerDone:              make(chan bool),
 		referrerDomainBlacklist: config.ReferrerDomainBlacklist,
 		referrerDomainBlacklistIncludesSubdomains: config.ReferrerDomainBlacklistIncludesSubdomains,
-		sessionMaxAge: config.SessionMaxAge,
-		geoDB:         config.GeoDB,
-		logger:        config.Logger,
+		sessionMaxAge:         config.SessionMaxAge,
+		geoDB:                 config.GeoDB,
+		spoolRetryInterval:    config.SpoolRetryInterval,
+		spoolMaxRetryInterval: config.SpoolMaxRetryInterval,
+		spoolMaxAttempts:      config.SpoolMaxAttempts,
+		spoolDone:             make(chan bool),
+		retryPolicy:           config.RetryPolicy,
+		onDrop:                config.OnDrop,
+		overflowPolicy:        config.OverflowPolicy,
+		metrics:               config.Metrics,
+		logger:                config.Logger,
+	}
+
+	if config.RecordBots {
+		tracker.botHits = make(chan BotHit, config.Worker*config.WorkerBufferSize)
+	}
+
+	if config.SpoolDir != "" {
+		s, err := newSpool(config.SpoolDir, config.Logger)
+
+		if err != nil {
+			tracker.logger.Printf("error opening spool, hits and events won't be spooled: %s", err)
+		} else {
+			tracker.spool = s
+			tracker.startSpool()
+		}
+	}
+
+	if config.SaltRotation != SaltStatic {
+		tracker.saltCancel = RunAtMidnight(func() {
+			tracker.salt.rotate(time.Now().UTC())
+		})
 	}
+
 	tracker.startWorker()
 	return tracker
 }
 
-// Hit stores the given request.
+// Hit stores the given request and returns whether it has been added to the queue.
 // The request might be ignored if it meets certain conditions. The HitOptions, if passed, will overwrite the Tracker configuration.
-// It's save (and recommended!) to call this function in its own goroutine.
-func (tracker *Tracker) Hit(r *http.Request, options *HitOptions) {
+// It's save (and recommended!) to call this function in its own goroutine, unless the TrackerConfig.OverflowPolicy is set to not block.
+func (tracker *Tracker) Hit(r *http.Request, options *HitOptions) EnqueueResult {
+	hit, ok := tracker.newHit(r, options)
+
+	if !ok {
+		return Ignored
+	}
+
+	return tracker.enqueueHit(hit)
+}
+
+// newHit creates a new hit for given request, or returns false if the request is ignored.
+func (tracker *Tracker) newHit(r *http.Request, options *HitOptions) (Hit, bool) {
 	if atomic.LoadInt32(&tracker.stopped) > 0 {
-		return
+		return Hit{}, false
 	}
 
-	if !IgnoreHit(r) {
+	classification := ClassifyRequest(r)
+
+	if classification.Reason == "" {
 		if options == nil {
 			options = &HitOptions{
 				ReferrerDomainBlacklist:                   tracker.referrerDomainBlacklist,
@@ -152,26 +378,50 @@ func (tracker *Tracker) Hit(r *http.Request, options *HitOptions) {
 			}
 		}
 
-		if tracker.geoDB != nil {
+		if options.GeoResolver == nil {
 			tracker.geoDBMutex.RLock()
-			options.geoDB = tracker.geoDB
+			options.GeoResolver = tracker.geoDB
 			tracker.geoDBMutex.RUnlock()
 		}
 
-		options.SessionCache = tracker.sessionCache
-		tracker.hits <- HitFromRequest(r, tracker.salt, options)
+		options.SessionCache = tracker.sessionStore
+		options.metrics = tracker.metrics
+		salt := tracker.setSalt(options)
+		return HitFromRequest(r, salt, options), true
 	}
+
+	tracker.recordBot(r, options, classification)
+	tracker.metrics.Ignored(KindHit, classification.Reason)
+	return Hit{}, false
 }
 
-// Event stores the given request as a new event. The event name in the options must be set, or otherwise the request will be ignored.
+// Event stores the given request as a new event and returns whether it has been added to the queue.
+// The event name in the options must be set, or otherwise the request will be ignored.
 // The request might be ignored if it meets certain conditions. The HitOptions, if passed, will overwrite the Tracker configuration.
-// It's save (and recommended!) to call this function in its own goroutine.
-func (tracker *Tracker) Event(r *http.Request, eventOptions EventOptions, options *HitOptions) {
+// It's save (and recommended!) to call this function in its own goroutine, unless the TrackerConfig.OverflowPolicy is set to not block.
+func (tracker *Tracker) Event(r *http.Request, eventOptions EventOptions, options *HitOptions) EnqueueResult {
+	event, ok := tracker.newEvent(r, eventOptions, options)
+
+	if !ok {
+		return Ignored
+	}
+
+	return tracker.enqueueEvent(event)
+}
+
+// newEvent creates a new event for given request, or returns false if the request is ignored.
+func (tracker *Tracker) newEvent(r *http.Request, eventOptions EventOptions, options *HitOptions) (Event, bool) {
 	if atomic.LoadInt32(&tracker.stopped) > 0 {
-		return
+		return Event{}, false
 	}
 
-	if strings.TrimSpace(eventOptions.Name) != "" && !IgnoreHit(r) {
+	if strings.TrimSpace(eventOptions.Name) == "" {
+		return Event{}, false
+	}
+
+	classification := ClassifyRequest(r)
+
+	if classification.Reason == "" {
 		if options == nil {
 			options = &HitOptions{
 				ReferrerDomainBlacklist:                   tracker.referrerDomainBlacklist,
@@ -179,22 +429,28 @@ func (tracker *Tracker) Event(r *http.Request, eventOptions EventOptions, option
 			}
 		}
 
-		if tracker.geoDB != nil {
+		if options.GeoResolver == nil {
 			tracker.geoDBMutex.RLock()
-			options.geoDB = tracker.geoDB
+			options.GeoResolver = tracker.geoDB
 			tracker.geoDBMutex.RUnlock()
 		}
 
-		options.SessionCache = tracker.sessionCache
+		options.SessionCache = tracker.sessionStore
+		options.metrics = tracker.metrics
+		salt := tracker.setSalt(options)
 		metaKeys, metaValues := eventOptions.getMetaData()
-		tracker.events <- Event{
-			Hit:             HitFromRequest(r, tracker.salt, options),
+		return Event{
+			Hit:             HitFromRequest(r, salt, options),
 			Name:            strings.TrimSpace(eventOptions.Name),
 			DurationSeconds: eventOptions.Duration,
 			MetaKeys:        metaKeys,
 			MetaValues:      metaValues,
-		}
+		}, true
 	}
+
+	tracker.recordBot(r, options, classification)
+	tracker.metrics.Ignored(KindEvent, classification.Reason)
+	return Event{}, false
 }
 
 // Flush flushes all hits to client that are currently buffered by the workers.
@@ -209,20 +465,297 @@ func (tracker *Tracker) Stop() {
 	if atomic.LoadInt32(&tracker.stopped) == 0 {
 		atomic.StoreInt32(&tracker.stopped, 1)
 		tracker.stopWorker()
-		tracker.flushHits()
-		tracker.flushEvents()
+
+		// don't retry saving the remaining hits and events, but write them to the spool (or drop them) instead
+		ctx, cancelFunc := context.WithCancel(context.Background())
+		cancelFunc()
+		tracker.flushHits(ctx)
+		tracker.flushEvents(ctx)
+		tracker.flushBotHits(ctx)
+
+		if tracker.spool != nil {
+			tracker.stopSpool()
+		}
+
+		if tracker.sessionCache != nil {
+			tracker.sessionCache.Stop()
+		}
+
+		if tracker.saltCancel != nil {
+			tracker.saltCancel()
+		}
 	}
 }
 
-// SetGeoDB sets the GeoDB for the Tracker.
+// Dropped returns the number of hits and events that have been dropped,
+// because the queue was full or they could not be saved from the spool after TrackerConfig.SpoolMaxAttempts.
+func (tracker *Tracker) Dropped() uint64 {
+	return atomic.LoadUint64(&tracker.dropped)
+}
+
+// Spilled returns the number of hits and events that have been written to the spool, because the queue was full.
+func (tracker *Tracker) Spilled() uint64 {
+	return atomic.LoadUint64(&tracker.spilled)
+}
+
+// SetGeoDB sets the GeoDB (or any other GeoResolver) for the Tracker.
 // The call to this function is thread safe to enable live updates of the database.
 // Pass nil to disable the feature.
-func (tracker *Tracker) SetGeoDB(geoDB *GeoDB) {
+func (tracker *Tracker) SetGeoDB(geoDB GeoResolver) {
 	tracker.geoDBMutex.Lock()
 	defer tracker.geoDBMutex.Unlock()
 	tracker.geoDB = geoDB
 }
 
+// setSalt sets the previous salt in given HitOptions to continue sessions across midnight and returns the current salt.
+// The Fingerprinter and IPExtractor are set too, if they haven't been set in the HitOptions, and the IP is anonymized if enabled.
+func (tracker *Tracker) setSalt(options *HitOptions) string {
+	if options.Fingerprinter == nil {
+		options.Fingerprinter = tracker.fingerprinter
+	}
+
+	if options.IPExtractor == nil {
+		options.IPExtractor = tracker.ipExtractor
+	}
+
+	if tracker.anonymizeIP {
+		options.AnonymizeIP = true
+	}
+
+	salt, previousSalt, rotated := tracker.salt.get()
+	options.previousSalt = previousSalt
+	options.saltRotated = rotated
+	return salt
+}
+
+// hitOptions sets the Tracker configuration for all fields that have not been set in given HitOptions.
+func (tracker *Tracker) hitOptions(options *HitOptions) *HitOptions {
+	if options.ReferrerDomainBlacklist == nil {
+		options.ReferrerDomainBlacklist = tracker.referrerDomainBlacklist
+		options.ReferrerDomainBlacklistIncludesSubdomains = tracker.referrerDomainBlacklistIncludesSubdomains
+	}
+
+	if options.SessionMaxAge == 0 {
+		options.SessionMaxAge = tracker.sessionMaxAge
+	}
+
+	return options
+}
+
+// recordBot adds a BotHit for given request to the queue if recording bots is enabled and the request has been classified as a bot.
+// The bot hit is dropped if the queue is full.
+func (tracker *Tracker) recordBot(r *http.Request, options *HitOptions, classification RequestClassification) {
+	if !tracker.recordBots || !classification.IsBot() {
+		return
+	}
+
+	requestOptions := new(HitOptions)
+
+	if options != nil {
+		requestOptions.ClientID = options.ClientID
+		requestOptions.URL = options.URL
+		requestOptions.Path = options.Path
+	}
+
+	getRequestURI(r, requestOptions)
+	hit := BotHit{
+		ClientID:  requestOptions.ClientID,
+		Time:      time.Now().UTC(),
+		UserAgent: shortenString(r.UserAgent(), 200),
+		Path:      shortenString(requestOptions.Path, 2000),
+		URL:       shortenString(requestOptions.URL, 2000),
+		Reason:    classification.Reason,
+		Rule:      classification.Rule,
+	}
+
+	select {
+	case tracker.botHits <- hit:
+		tracker.metrics.Enqueued(KindBot)
+	default:
+		tracker.metrics.Dropped(KindBot)
+	}
+
+	tracker.metrics.QueueDepth(KindBot, len(tracker.botHits))
+}
+
+// enqueueHit writes given hit to the spool if enabled, adds it to the queue, and reports the result to the MetricsCollector.
+func (tracker *Tracker) enqueueHit(hit Hit) EnqueueResult {
+	if tracker.spool != nil {
+		journal, err := tracker.spool.journalHit(hit)
+
+		if err != nil {
+			tracker.logger.Printf("error writing hit to spool: %s", err)
+		}
+
+		hit.journal = journal
+	}
+
+	result := tracker.pushHit(hit)
+
+	if result == Enqueued || result == DroppedOldest {
+		tracker.metrics.Enqueued(KindHit)
+	}
+
+	tracker.metrics.QueueDepth(KindHit, len(tracker.hits))
+	return result
+}
+
+// pushHit adds given hit to the queue and applies the overflow policy if it's full.
+func (tracker *Tracker) pushHit(hit Hit) EnqueueResult {
+	if tracker.overflowPolicy == OverflowBlock {
+		tracker.hits <- hit
+		return Enqueued
+	}
+
+	select {
+	case tracker.hits <- hit:
+		return Enqueued
+	default:
+	}
+
+	switch tracker.overflowPolicy {
+	case OverflowDropOldest:
+		for {
+			select {
+			case oldest := <-tracker.hits:
+				tracker.discard([]Hit{oldest}, nil, ErrQueueFull)
+				tracker.ack([]Hit{oldest}, nil)
+			default:
+			}
+
+			select {
+			case tracker.hits <- hit:
+				return DroppedOldest
+			default:
+			}
+		}
+	case OverflowSpill:
+		if tracker.spill([]Hit{hit}, nil) {
+			tracker.ack([]Hit{hit}, nil)
+			return Spilled
+		}
+	}
+
+	tracker.discard([]Hit{hit}, nil, ErrQueueFull)
+	tracker.ack([]Hit{hit}, nil)
+	return Dropped
+}
+
+// enqueueEvent writes given event to the spool if enabled, adds it to the queue, and reports the result to the MetricsCollector.
+func (tracker *Tracker) enqueueEvent(event Event) EnqueueResult {
+	if tracker.spool != nil {
+		journal, err := tracker.spool.journalEvent(event)
+
+		if err != nil {
+			tracker.logger.Printf("error writing event to spool: %s", err)
+		}
+
+		event.journal = journal
+	}
+
+	result := tracker.pushEvent(event)
+
+	if result == Enqueued || result == DroppedOldest {
+		tracker.metrics.Enqueued(KindEvent)
+	}
+
+	tracker.metrics.QueueDepth(KindEvent, len(tracker.events))
+	return result
+}
+
+// pushEvent adds given event to the queue and applies the overflow policy if it's full.
+func (tracker *Tracker) pushEvent(event Event) EnqueueResult {
+	if tracker.overflowPolicy == OverflowBlock {
+		tracker.events <- event
+		return Enqueued
+	}
+
+	select {
+	case tracker.events <- event:
+		return Enqueued
+	default:
+	}
+
+	switch tracker.overflowPolicy {
+	case OverflowDropOldest:
+		for {
+			select {
+			case oldest := <-tracker.events:
+				tracker.discard(nil, []Event{oldest}, ErrQueueFull)
+				tracker.ack(nil, []Event{oldest})
+			default:
+			}
+
+			select {
+			case tracker.events <- event:
+				return DroppedOldest
+			default:
+			}
+		}
+	case OverflowSpill:
+		if tracker.spill(nil, []Event{event}) {
+			tracker.ack(nil, []Event{event})
+			return Spilled
+		}
+	}
+
+	tracker.discard(nil, []Event{event}, ErrQueueFull)
+	tracker.ack(nil, []Event{event})
+	return Dropped
+}
+
+// spill appends given hits or events to the spill log, so that they are saved by the replay loop, and returns true on success.
+func (tracker *Tracker) spill(hits []Hit, events []Event) bool {
+	if tracker.spool == nil {
+		return false
+	}
+
+	for _, hit := range hits {
+		if err := tracker.spool.spillHit(hit); err != nil {
+			tracker.logger.Printf("error spilling hit to spool: %s", err)
+			return false
+		}
+	}
+
+	for _, event := range events {
+		if err := tracker.spool.spillEvent(event); err != nil {
+			tracker.logger.Printf("error spilling event to spool: %s", err)
+			return false
+		}
+	}
+
+	atomic.AddUint64(&tracker.spilled, uint64(len(hits)+len(events)))
+	return true
+}
+
+// ack acknowledges the entries for given hits and events in the spool, once they have been saved, spooled, or dropped.
+func (tracker *Tracker) ack(hits []Hit, events []Event) {
+	if tracker.spool != nil {
+		for i := range hits {
+			tracker.spool.ack(hits[i].journal)
+		}
+
+		for i := range events {
+			tracker.spool.ack(events[i].journal)
+		}
+	}
+}
+
+// discard counts given hits or events as dropped and passes them to the OnDrop callback together with the error.
+func (tracker *Tracker) discard(hits []Hit, events []Event, err error) {
+	atomic.AddUint64(&tracker.dropped, uint64(len(hits)+len(events)))
+
+	for range hits {
+		tracker.metrics.Dropped(KindHit)
+	}
+
+	for range events {
+		tracker.metrics.Dropped(KindEvent)
+	}
+
+	tracker.drop(hits, events, err)
+}
+
 func (tracker *Tracker) startWorker() {
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	tracker.workerCancel = cancelFunc
@@ -231,17 +764,26 @@ func (tracker *Tracker) startWorker() {
 		go tracker.aggregateHits(ctx)
 		go tracker.aggregateEvents(ctx)
 	}
+
+	if tracker.recordBots {
+		go tracker.aggregateBotHits(ctx)
+	}
 }
 
 func (tracker *Tracker) stopWorker() {
 	tracker.workerCancel()
+	worker := tracker.worker * 2
 
-	for i := 0; i < tracker.worker*2; i++ {
+	if tracker.recordBots {
+		worker++
+	}
+
+	for i := 0; i < worker; i++ {
 		<-tracker.workerDone
 	}
 }
 
-func (tracker *Tracker) flushHits() {
+func (tracker *Tracker) flushHits(ctx context.Context) {
 	// this function will make sure all dangling hits will be saved in database before shutdown
 	// hits are buffered before saving
 	hits := make([]Hit, 0, tracker.workerBufferSize)
@@ -254,7 +796,7 @@ func (tracker *Tracker) flushHits() {
 			hits = append(hits, hit)
 
 			if len(hits) == tracker.workerBufferSize {
-				tracker.saveHits(hits)
+				tracker.saveHits(ctx, hits)
 				hits = hits[:0]
 			}
 		default:
@@ -266,7 +808,7 @@ func (tracker *Tracker) flushHits() {
 		}
 	}
 
-	tracker.saveHits(hits)
+	tracker.saveHits(ctx, hits)
 }
 
 func (tracker *Tracker) aggregateHits(ctx context.Context) {
@@ -282,29 +824,40 @@ func (tracker *Tracker) aggregateHits(ctx context.Context) {
 			hits = append(hits, hit)
 
 			if len(hits) == tracker.workerBufferSize {
-				tracker.saveHits(hits)
+				tracker.saveHits(ctx, hits)
 				hits = hits[:0]
 			}
 		case <-timer.C:
-			tracker.saveHits(hits)
+			tracker.saveHits(ctx, hits)
 			hits = hits[:0]
 		case <-ctx.Done():
-			tracker.saveHits(hits)
+			tracker.saveHits(ctx, hits)
 			tracker.workerDone <- true
 			return
 		}
 	}
 }
 
-func (tracker *Tracker) saveHits(hits []Hit) {
+func (tracker *Tracker) saveHits(ctx context.Context, hits []Hit) {
 	if len(hits) > 0 {
-		if err := tracker.store.SaveHits(hits); err != nil {
+		err := tracker.retryPolicy.retry(ctx, func() error {
+			return tracker.saveBatch(KindHit, len(hits), func() error {
+				return tracker.store.SaveHits(hits)
+			})
+		})
+
+		if err != nil {
 			tracker.logger.Printf("error saving hits: %s", err)
+			tracker.keep(hits, nil, err)
 		}
+
+		tracker.ack(hits, nil)
 	}
+
+	tracker.metrics.QueueDepth(KindHit, len(tracker.hits))
 }
 
-func (tracker *Tracker) flushEvents() {
+func (tracker *Tracker) flushEvents(ctx context.Context) {
 	// this function will make sure all dangling events will be saved in database before shutdown
 	// events are buffered before saving
 	events := make([]Event, 0, tracker.workerBufferSize)
@@ -317,7 +870,7 @@ func (tracker *Tracker) flushEvents() {
 			events = append(events, event)
 
 			if len(events) == tracker.workerBufferSize {
-				tracker.saveEvents(events)
+				tracker.saveEvents(ctx, events)
 				events = events[:0]
 			}
 		default:
@@ -329,7 +882,7 @@ func (tracker *Tracker) flushEvents() {
 		}
 	}
 
-	tracker.saveEvents(events)
+	tracker.saveEvents(ctx, events)
 }
 
 func (tracker *Tracker) aggregateEvents(ctx context.Context) {
@@ -345,24 +898,277 @@ func (tracker *Tracker) aggregateEvents(ctx context.Context) {
 			events = append(events, event)
 
 			if len(events) == tracker.workerBufferSize {
-				tracker.saveEvents(events)
+				tracker.saveEvents(ctx, events)
 				events = events[:0]
 			}
 		case <-timer.C:
-			tracker.saveEvents(events)
+			tracker.saveEvents(ctx, events)
 			events = events[:0]
 		case <-ctx.Done():
-			tracker.saveEvents(events)
+			tracker.saveEvents(ctx, events)
 			tracker.workerDone <- true
 			return
 		}
 	}
 }
 
-func (tracker *Tracker) saveEvents(events []Event) {
+func (tracker *Tracker) saveEvents(ctx context.Context, events []Event) {
 	if len(events) > 0 {
-		if err := tracker.store.SaveEvents(events); err != nil {
+		err := tracker.retryPolicy.retry(ctx, func() error {
+			return tracker.saveBatch(KindEvent, len(events), func() error {
+				return tracker.store.SaveEvents(events)
+			})
+		})
+
+		if err != nil {
 			tracker.logger.Printf("error saving events: %s", err)
+			tracker.keep(nil, events, err)
+		}
+
+		tracker.ack(nil, events)
+	}
+
+	tracker.metrics.QueueDepth(KindEvent, len(tracker.events))
+}
+
+func (tracker *Tracker) flushBotHits(ctx context.Context) {
+	if !tracker.recordBots {
+		return
+	}
+
+	hits := make([]BotHit, 0, tracker.workerBufferSize)
+
+	for {
+		stop := false
+
+		select {
+		case hit := <-tracker.botHits:
+			hits = append(hits, hit)
+
+			if len(hits) == tracker.workerBufferSize {
+				tracker.saveBotHits(ctx, hits)
+				hits = hits[:0]
+			}
+		default:
+			stop = true
+		}
+
+		if stop {
+			break
+		}
+	}
+
+	tracker.saveBotHits(ctx, hits)
+}
+
+func (tracker *Tracker) aggregateBotHits(ctx context.Context) {
+	hits := make([]BotHit, 0, tracker.workerBufferSize)
+	timer := time.NewTimer(tracker.workerTimeout)
+	defer timer.Stop()
+
+	for {
+		timer.Reset(tracker.workerTimeout)
+
+		select {
+		case hit := <-tracker.botHits:
+			hits = append(hits, hit)
+
+			if len(hits) == tracker.workerBufferSize {
+				tracker.saveBotHits(ctx, hits)
+				hits = hits[:0]
+			}
+		case <-timer.C:
+			tracker.saveBotHits(ctx, hits)
+			hits = hits[:0]
+		case <-ctx.Done():
+			tracker.saveBotHits(ctx, hits)
+			tracker.workerDone <- true
+			return
+		}
+	}
+}
+
+// saveBotHits saves given bot hits, which are dropped if they could not be saved after all attempts or the worker has been stopped.
+func (tracker *Tracker) saveBotHits(ctx context.Context, hits []BotHit) {
+	if len(hits) > 0 {
+		err := tracker.retryPolicy.retry(ctx, func() error {
+			return tracker.saveBatch(KindBot, len(hits), func() error {
+				return tracker.store.SaveBotHits(hits)
+			})
+		})
+
+		if err != nil {
+			tracker.logger.Printf("error saving bot hits: %s", err)
+		}
+	}
+
+	tracker.metrics.QueueDepth(KindBot, len(tracker.botHits))
+}
+
+// saveBatch calls save and reports the batch size, duration, and error to the MetricsCollector.
+func (tracker *Tracker) saveBatch(kind TrackingKind, size int, save func() error) error {
+	start := time.Now()
+	err := save()
+	tracker.metrics.BatchSaved(kind, size, time.Since(start), err)
+	return err
+}
+
+// keep writes given hits or events that could not be saved to a segment in the spool, so that they are saved later.
+// They are dropped if the spool is not enabled or cannot be written.
+func (tracker *Tracker) keep(hits []Hit, events []Event, err error) {
+	if tracker.spool != nil {
+		var segment string
+		var spoolErr error
+
+		if len(hits) > 0 {
+			segment, spoolErr = tracker.spool.writeHits(hits)
+		} else {
+			segment, spoolErr = tracker.spool.writeEvents(events)
+		}
+
+		if spoolErr == nil {
+			tracker.closeSegment(segment, false)
+			return
+		}
+
+		tracker.logger.Printf("error spooling batch: %s", spoolErr)
+	}
+
+	tracker.drop(hits, events, err)
+}
+
+// drop passes a copy of given hits or events to the OnDrop callback, as the workers reuse their buffers.
+func (tracker *Tracker) drop(hits []Hit, events []Event, err error) {
+	if tracker.onDrop != nil {
+		var hitsCopy []Hit
+		var eventsCopy []Event
+
+		if len(hits) > 0 {
+			hitsCopy = make([]Hit, len(hits))
+			copy(hitsCopy, hits)
+		}
+
+		if len(events) > 0 {
+			eventsCopy = make([]Event, len(events))
+			copy(eventsCopy, events)
+		}
+
+		tracker.onDrop(hitsCopy, eventsCopy, err)
+	}
+}
+
+func (tracker *Tracker) startSpool() {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	tracker.spoolCancel = cancelFunc
+	go tracker.replaySpool(ctx)
+}
+
+func (tracker *Tracker) stopSpool() {
+	tracker.spoolCancel()
+	<-tracker.spoolDone
+	tracker.spool.close()
+}
+
+// replaySpool saves the unsaved segments in the spool, starting with the segments left from a previous run.
+// The logs are rotated before, so that spilled hits and events are saved too.
+// The retry interval is doubled after each failed attempt.
+func (tracker *Tracker) replaySpool(ctx context.Context) {
+	interval := tracker.spoolRetryInterval
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			tracker.spool.rotate(tracker.spoolRetryInterval)
+
+			if tracker.saveSpool(ctx) {
+				interval = tracker.spoolRetryInterval
+			} else {
+				interval *= 2
+
+				if interval > tracker.spoolMaxRetryInterval {
+					interval = tracker.spoolMaxRetryInterval
+				}
+			}
+
+			timer.Reset(interval)
+		case <-ctx.Done():
+			tracker.spoolDone <- true
+			return
+		}
+	}
+}
+
+// saveSpool saves all unsaved segments in order and returns false if one of them could not be saved.
+func (tracker *Tracker) saveSpool(ctx context.Context) bool {
+	segments, err := tracker.spool.segments()
+
+	if err != nil {
+		tracker.logger.Printf("error reading spool: %s", err)
+		return false
+	}
+
+	for _, segment := range segments {
+		if ctx.Err() != nil {
+			return true
+		}
+
+		segment, err := tracker.spool.acquire(segment)
+
+		if err != nil {
+			tracker.logger.Printf("error reading spooled segment: %s", err)
+			return false
+		}
+
+		hits, events, err := tracker.spool.read(segment)
+
+		if err == nil {
+			if len(hits) > 0 {
+				err = tracker.saveBatch(KindHit, len(hits), func() error {
+					return tracker.store.SaveHits(hits)
+				})
+			} else if len(events) > 0 {
+				err = tracker.saveBatch(KindEvent, len(events), func() error {
+					return tracker.store.SaveEvents(events)
+				})
+			}
+		}
+
+		if err != nil {
+			tracker.logger.Printf("error saving spooled segment: %s", err)
+
+			// give up on the segment, so that it doesn't block the spool forever
+			if tracker.spool.failed(segment) >= tracker.spoolMaxAttempts {
+				if buryErr := tracker.spool.bury(segment); buryErr != nil {
+					tracker.logger.Printf("error burying spooled segment: %s", buryErr)
+				} else {
+					tracker.logger.Printf("gave up saving spooled segment after %d attempts: %s", tracker.spoolMaxAttempts, segment)
+					tracker.discard(hits, events, err)
+					continue
+				}
+			}
+		}
+
+		tracker.closeSegment(segment, err == nil)
+
+		if err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// closeSegment removes given segment if it has been saved, or marks it as unsaved otherwise.
+func (tracker *Tracker) closeSegment(segment string, saved bool) {
+	if segment != "" {
+		if saved {
+			if err := tracker.spool.remove(segment); err != nil {
+				tracker.logger.Printf("error removing spooled segment: %s", err)
+			}
+		} else if err := tracker.spool.release(segment); err != nil {
+			tracker.logger.Printf("error releasing spooled segment: %s", err)
 		}
 	}
 }
@@ -1,11 +1,17 @@
 package pirsch
 
 import (
+	"bytes"
+	"errors"
 	"github.com/stretchr/testify/assert"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -34,6 +40,13 @@ func TestTrackerConfigValidate(t *testing.T) {
 	cfg = &TrackerConfig{WorkerTimeout: time.Second * 142}
 	cfg.validate()
 	assert.Equal(t, maxWorkerTimeout, cfg.WorkerTimeout)
+	assert.Equal(t, defaultSpoolRetryInterval, cfg.SpoolRetryInterval)
+	assert.Equal(t, defaultSpoolMaxRetryInterval, cfg.SpoolMaxRetryInterval)
+	cfg = &TrackerConfig{SpoolRetryInterval: time.Minute * 2}
+	cfg.validate()
+	assert.Equal(t, time.Minute*2, cfg.SpoolMaxRetryInterval)
+	assert.NotNil(t, cfg.RetryPolicy)
+	assert.Equal(t, 1, cfg.RetryPolicy.MaxAttempts)
 }
 
 func TestTrackerHitTimeout(t *testing.T) {
@@ -127,6 +140,50 @@ func TestTrackerHitCountryCode(t *testing.T) {
 	assert.True(t, foundEmpty)
 }
 
+func TestTrackerHitGeoLocation(t *testing.T) {
+	dir := t.TempDir()
+	cityFile := filepath.Join(dir, GeoLite2CityFilename)
+	asnFile := filepath.Join(dir, GeoLite2ASNFilename)
+	writeTestGeoDB(t, cityFile, map[string]interface{}{
+		"81.2.69.0/24": map[string]interface{}{
+			"country":      map[string]interface{}{"iso_code": "GB"},
+			"subdivisions": []interface{}{map[string]interface{}{"names": map[string]interface{}{"en": "England"}}},
+			"city":         map[string]interface{}{"names": map[string]interface{}{"en": "London"}},
+		},
+	})
+	writeTestGeoDB(t, asnFile, map[string]interface{}{
+		"81.2.69.0/24": map[string]interface{}{
+			"autonomous_system_number":       uint32(20712),
+			"autonomous_system_organization": "Andrews & Arnold Ltd",
+		},
+	})
+	geoDB, err := NewGeoDB(GeoDBConfig{
+		File:    cityFile,
+		ASNFile: asnFile,
+	})
+	assert.NoError(t, err)
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker: 1,
+		GeoDB:  geoDB,
+	})
+	req := overflowRequest("/")
+	req.RemoteAddr = "81.2.69.142"
+	tracker.Hit(req, nil)
+	tracker.Event(req, EventOptions{Name: "event"}, nil)
+	tracker.Stop()
+	assert.Len(t, client.Hits, 1)
+	assert.Len(t, client.Events, 1)
+
+	for _, hit := range []Hit{client.Hits[0], client.Events[0].Hit} {
+		assert.Equal(t, "gb", hit.CountryCode)
+		assert.Equal(t, "England", hit.Region)
+		assert.Equal(t, "London", hit.City)
+		assert.Equal(t, 20712, hit.ASN)
+		assert.Equal(t, "Andrews & Arnold Ltd", hit.ASOrganization)
+	}
+}
+
 func TestTrackerHitSession(t *testing.T) {
 	req1 := httptest.NewRequest(http.MethodGet, "/", nil)
 	req1.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
@@ -343,3 +400,682 @@ func TestTrackerEventIgnoreSubdomain(t *testing.T) {
 		assert.Empty(t, hit.Referrer)
 	}
 }
+
+func TestTrackerSpool(t *testing.T) {
+	dir := t.TempDir()
+	client := &failingStore{MockClient: NewMockClient(), fail: 1}
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker:                1,
+		WorkerTimeout:         time.Millisecond * 10,
+		SpoolDir:              dir,
+		SpoolRetryInterval:    time.Millisecond * 10,
+		SpoolMaxRetryInterval: time.Millisecond * 20,
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	tracker.Hit(req, nil)
+	tracker.Event(req, EventOptions{Name: "event"}, nil)
+	time.Sleep(time.Millisecond * 50)
+	assert.Len(t, client.Hits, 0)
+	assert.Len(t, client.Events, 0)
+	segments, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.NoError(t, err)
+	assert.Len(t, segments, 2)
+	atomic.StoreInt32(&client.fail, 0)
+	time.Sleep(time.Millisecond * 100)
+	tracker.Stop()
+	assert.Len(t, client.Hits, 1)
+	assert.Len(t, client.Events, 1)
+	segments, err = filepath.Glob(filepath.Join(dir, "*"))
+	assert.NoError(t, err)
+	assert.Empty(t, segments)
+}
+
+func TestTrackerSpoolReplay(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSpool(dir, logger)
+	assert.NoError(t, err)
+	_, err = s.writeHits([]Hit{{Fingerprint: "fp1"}, {Fingerprint: "fp2"}})
+	assert.NoError(t, err)
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", &TrackerConfig{SpoolDir: dir})
+	time.Sleep(time.Millisecond * 50)
+	tracker.Stop()
+	assert.Len(t, client.Hits, 2)
+}
+
+func TestTrackerSpoolCrash(t *testing.T) {
+	dir := t.TempDir()
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker:             1,
+		WorkerTimeout:      time.Minute,
+		SpoolDir:           dir,
+		SpoolRetryInterval: time.Hour,
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	assert.Equal(t, Enqueued, tracker.Hit(req, nil))
+	assert.Equal(t, Enqueued, tracker.Event(req, EventOptions{Name: "event"}, nil))
+
+	// the hit and event are still in the queue or buffer when the process crashes, so they must be saved by the next Tracker
+	// (wait for the first attempt of the crashed Tracker to replay the spool, as the directory must not be shared)
+	time.Sleep(time.Millisecond * 20)
+	restarted := NewMockClient()
+	restartedTracker := NewTracker(restarted, "salt", &TrackerConfig{SpoolDir: dir})
+	assert.Eventually(t, func() bool {
+		restarted.m.Lock()
+		defer restarted.m.Unlock()
+		return len(restarted.Hits) == 1 && len(restarted.Events) == 1
+	}, time.Second*5, time.Millisecond)
+	restartedTracker.Stop()
+	assert.Equal(t, "/", restarted.Hits[0].Path)
+	assert.Equal(t, "event", restarted.Events[0].Name)
+	tracker.Stop()
+}
+
+func TestTrackerSpoolMaxAttempts(t *testing.T) {
+	dir := t.TempDir()
+	client := &failingStore{MockClient: NewMockClient(), fail: 1}
+	metrics := newTestMetrics()
+	var dropped []Hit
+	var dropErr error
+	var m sync.Mutex
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker:                1,
+		WorkerTimeout:         time.Millisecond * 10,
+		SpoolDir:              dir,
+		SpoolRetryInterval:    time.Millisecond * 10,
+		SpoolMaxRetryInterval: time.Millisecond * 10,
+		SpoolMaxAttempts:      2,
+		OnDrop: func(hits []Hit, events []Event, err error) {
+			m.Lock()
+			defer m.Unlock()
+			dropped = append(dropped, hits...)
+			dropErr = err
+		},
+		Metrics: metrics,
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	tracker.Hit(req, nil)
+	assert.Eventually(t, func() bool {
+		m.Lock()
+		defer m.Unlock()
+		return len(dropped) == 1
+	}, time.Second*5, time.Millisecond)
+	assert.Error(t, dropErr)
+	assert.Equal(t, uint64(1), tracker.Dropped())
+	metrics.m.Lock()
+	assert.Equal(t, 1, metrics.dropped[KindHit])
+	metrics.m.Unlock()
+	dead, err := filepath.Glob(filepath.Join(dir, "*"+spoolDeadExt))
+	assert.NoError(t, err)
+	assert.Len(t, dead, 1)
+
+	// the dead segment doesn't block the spool
+	atomic.StoreInt32(&client.fail, 0)
+	tracker.Hit(req, nil)
+	tracker.Stop()
+	assert.Len(t, client.Hits, 1)
+	segments, err := filepath.Glob(filepath.Join(dir, "*"+spoolSegmentExt))
+	assert.NoError(t, err)
+	assert.Empty(t, segments)
+}
+
+func TestTrackerRetry(t *testing.T) {
+	client := &failingStore{MockClient: NewMockClient(), failTimes: 2}
+	var dropped int32
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker:        1,
+		WorkerTimeout: time.Millisecond * 10,
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+		},
+		OnDrop: func(hits []Hit, events []Event, err error) {
+			atomic.AddInt32(&dropped, 1)
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	tracker.Hit(req, nil)
+	assert.Eventually(t, func() bool {
+		client.m.Lock()
+		defer client.m.Unlock()
+		return len(client.Hits) == 1
+	}, time.Second, time.Millisecond)
+	tracker.Stop()
+	assert.Len(t, client.Hits, 1)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&dropped))
+}
+
+func TestTrackerRetryStop(t *testing.T) {
+	client := &failingStore{MockClient: NewMockClient(), fail: 1}
+	metrics := newTestMetrics()
+	var dropped int32
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker:        1,
+		WorkerTimeout: time.Millisecond * 10,
+		Metrics:       metrics,
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Hour,
+		},
+		OnDrop: func(hits []Hit, events []Event, err error) {
+			atomic.AddInt32(&dropped, int32(len(hits)))
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	tracker.Hit(req, nil)
+
+	// the worker waits an hour for the next attempt, which must be interrupted by Stop
+	assert.Eventually(t, func() bool {
+		metrics.m.Lock()
+		defer metrics.m.Unlock()
+		return metrics.saveErrors > 0
+	}, time.Second, time.Millisecond)
+	start := time.Now()
+	tracker.Stop()
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Len(t, client.Hits, 0)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dropped))
+}
+
+func TestTrackerOnDrop(t *testing.T) {
+	client := &failingStore{MockClient: NewMockClient(), fail: 1}
+	var droppedHits []Hit
+	var droppedEvents []Event
+	var dropErr error
+	var m sync.Mutex
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker: 1,
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+		},
+		OnDrop: func(hits []Hit, events []Event, err error) {
+			// hits and events are saved by separate workers
+			m.Lock()
+			defer m.Unlock()
+			droppedHits = append(droppedHits, hits...)
+			droppedEvents = append(droppedEvents, events...)
+			dropErr = err
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	tracker.Hit(req, nil)
+	tracker.Hit(req, nil)
+	tracker.Event(req, EventOptions{Name: "event"}, nil)
+	tracker.Stop()
+	assert.Len(t, client.Hits, 0)
+	assert.Len(t, droppedHits, 2)
+	assert.Len(t, droppedEvents, 1)
+	assert.Equal(t, "/", droppedHits[0].Path)
+	assert.Equal(t, "event", droppedEvents[0].Name)
+	assert.Error(t, dropErr)
+}
+
+func TestTrackerOnDropSpool(t *testing.T) {
+	client := &failingStore{MockClient: NewMockClient(), fail: 1}
+	var dropped int32
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker:   1,
+		SpoolDir: t.TempDir(),
+		OnDrop: func(hits []Hit, events []Event, err error) {
+			atomic.AddInt32(&dropped, 1)
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	tracker.Hit(req, nil)
+	tracker.Stop()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&dropped))
+}
+
+func TestTrackerOverflowDropNewest(t *testing.T) {
+	client := newBlockingStore()
+	var dropped []Hit
+	var dropErr error
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker:           1,
+		WorkerBufferSize: 1,
+		OverflowPolicy:   OverflowDropNewest,
+		OnDrop: func(hits []Hit, events []Event, err error) {
+			dropped = append(dropped, hits...)
+			dropErr = err
+		},
+	})
+	assert.Equal(t, Enqueued, tracker.Hit(overflowRequest("/1"), nil))
+	<-client.saving
+	assert.Equal(t, Enqueued, tracker.Hit(overflowRequest("/2"), nil))
+	assert.Equal(t, Dropped, tracker.Hit(overflowRequest("/3"), nil))
+	assert.Equal(t, uint64(1), tracker.Dropped())
+	assert.Len(t, dropped, 1)
+	assert.Equal(t, "/3", dropped[0].Path)
+	assert.Equal(t, ErrQueueFull, dropErr)
+	close(client.release)
+	tracker.Stop()
+	assert.Len(t, client.Hits, 2)
+	assert.Equal(t, "/1", client.Hits[0].Path)
+	assert.Equal(t, "/2", client.Hits[1].Path)
+}
+
+func TestTrackerOverflowDropOldest(t *testing.T) {
+	client := newBlockingStore()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker:           1,
+		WorkerBufferSize: 1,
+		OverflowPolicy:   OverflowDropOldest,
+	})
+	assert.Equal(t, Enqueued, tracker.Hit(overflowRequest("/1"), nil))
+	<-client.saving
+	assert.Equal(t, Enqueued, tracker.Hit(overflowRequest("/2"), nil))
+	assert.Equal(t, DroppedOldest, tracker.Hit(overflowRequest("/3"), nil))
+	assert.Equal(t, uint64(1), tracker.Dropped())
+	close(client.release)
+	tracker.Stop()
+	assert.Len(t, client.Hits, 2)
+	assert.Equal(t, "/1", client.Hits[0].Path)
+	assert.Equal(t, "/3", client.Hits[1].Path)
+}
+
+func TestTrackerOverflowSpill(t *testing.T) {
+	dir := t.TempDir()
+	client := newBlockingStore()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker:           1,
+		WorkerBufferSize: 1,
+		OverflowPolicy:   OverflowSpill,
+	})
+	assert.Equal(t, Enqueued, tracker.Hit(overflowRequest("/1"), nil))
+	<-client.saving
+	assert.Equal(t, Enqueued, tracker.Hit(overflowRequest("/2"), nil))
+	assert.Equal(t, Dropped, tracker.Hit(overflowRequest("/3"), nil), "must be dropped without spool")
+	close(client.release)
+	tracker.Stop()
+
+	client = newBlockingStore()
+	tracker = NewTracker(client, "salt", &TrackerConfig{
+		Worker:             1,
+		WorkerBufferSize:   1,
+		OverflowPolicy:     OverflowSpill,
+		SpoolDir:           dir,
+		SpoolRetryInterval: time.Millisecond * 10,
+	})
+	assert.Equal(t, Enqueued, tracker.Hit(overflowRequest("/1"), nil))
+	<-client.saving
+	assert.Equal(t, Enqueued, tracker.Hit(overflowRequest("/2"), nil))
+	assert.Equal(t, Spilled, tracker.Hit(overflowRequest("/3"), nil))
+	assert.Equal(t, uint64(1), tracker.Spilled())
+	assert.Equal(t, uint64(0), tracker.Dropped())
+	close(client.release)
+	time.Sleep(time.Millisecond * 100)
+	tracker.Stop()
+	assert.Len(t, client.Hits, 3)
+}
+
+func TestTrackerOverflowIgnored(t *testing.T) {
+	tracker := NewTracker(NewMockClient(), "salt", &TrackerConfig{OverflowPolicy: OverflowDropNewest})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Add("User-Agent", "Googlebot/2.1 (+http://www.google.com/bot.html)")
+	assert.Equal(t, Ignored, tracker.Hit(req, nil))
+	assert.Equal(t, Ignored, tracker.Event(overflowRequest("/"), EventOptions{}, nil))
+	tracker.Stop()
+	assert.Equal(t, Ignored, tracker.Hit(overflowRequest("/"), nil))
+}
+
+func TestTrackerMetrics(t *testing.T) {
+	metrics := newTestMetrics()
+	client := &failingStore{MockClient: NewMockClient(), failTimes: 1}
+	geoDB, err := NewGeoDB(GeoDBConfig{
+		File: filepath.Join("geodb/GeoIP2-Country-Test.mmdb"),
+	})
+	assert.NoError(t, err)
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker:           1,
+		WorkerBufferSize: 2,
+		GeoDB:            geoDB,
+		RetryPolicy: &RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+		},
+		Metrics: metrics,
+	})
+	tracker.Hit(overflowRequest("/"), nil)
+	req := overflowRequest("/foo")
+	req.RemoteAddr = "invalid"
+	tracker.Hit(req, nil)
+	tracker.Event(overflowRequest("/"), EventOptions{Name: "event"}, nil)
+	req = overflowRequest("/")
+	req.Header.Set("DNT", "1")
+	tracker.Hit(req, nil)
+	tracker.Event(req, EventOptions{Name: "event"}, nil)
+
+	// the hits are retried by the worker, as Stop doesn't retry
+	assert.Eventually(t, func() bool {
+		client.m.Lock()
+		defer client.m.Unlock()
+		return len(client.Hits) == 2
+	}, time.Second, time.Millisecond)
+	tracker.Stop()
+	metrics.m.Lock()
+	defer metrics.m.Unlock()
+	assert.Equal(t, 2, metrics.enqueued[KindHit])
+	assert.Equal(t, 1, metrics.enqueued[KindEvent])
+	assert.Equal(t, 1, metrics.ignored[string(KindHit)+string(IgnoreDNT)])
+	assert.Equal(t, 1, metrics.ignored[string(KindEvent)+string(IgnoreDNT)])
+	assert.Equal(t, 0, metrics.queueDepth[KindHit])
+	assert.NotEmpty(t, metrics.batchSizes[KindHit])
+	assert.NotEmpty(t, metrics.batchSizes[KindEvent])
+	assert.Equal(t, 1, metrics.saveErrors)
+	assert.Len(t, client.Hits, 2)
+	assert.Len(t, client.Events, 1)
+	assert.Equal(t, 3, metrics.sessionCache[SessionCacheMiss]+metrics.sessionCache[SessionCacheHit])
+	assert.Equal(t, 1, metrics.geoDBLookupFailed)
+}
+
+func TestTrackerRecordBots(t *testing.T) {
+	metrics := newTestMetrics()
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker:     1,
+		RecordBots: true,
+		Metrics:    metrics,
+	})
+	req := overflowRequest("/bot")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)")
+	assert.Equal(t, Ignored, tracker.Hit(req, &HitOptions{ClientID: 42}))
+	req = overflowRequest("/empty")
+	req.Header.Del("User-Agent")
+	assert.Equal(t, Ignored, tracker.Hit(req, nil))
+	req = overflowRequest("/dnt")
+	req.Header.Set("DNT", "1")
+	assert.Equal(t, Ignored, tracker.Hit(req, nil))
+	req = overflowRequest("/event")
+	req.Header.Set("User-Agent", "curl/8.4.0")
+	assert.Equal(t, Ignored, tracker.Event(req, EventOptions{Name: "event"}, nil))
+	assert.Equal(t, Enqueued, tracker.Hit(overflowRequest("/"), nil))
+	tracker.Stop()
+	assert.Len(t, client.Hits, 1)
+	assert.Len(t, client.BotHits, 3)
+	sort.Slice(client.BotHits, func(i, j int) bool {
+		return client.BotHits[i].Path < client.BotHits[j].Path
+	})
+	assert.Equal(t, int64(42), client.BotHits[0].ClientID)
+	assert.Equal(t, "/bot", client.BotHits[0].Path)
+	assert.Equal(t, IgnoreBot, client.BotHits[0].Reason)
+	assert.Equal(t, "Googlebot", client.BotHits[0].Rule)
+	assert.Contains(t, client.BotHits[0].UserAgent, "Googlebot")
+	assert.False(t, client.BotHits[0].Time.IsZero())
+	assert.Equal(t, "/empty", client.BotHits[1].Path)
+	assert.Equal(t, IgnoreEmptyUserAgent, client.BotHits[1].Reason)
+	assert.Empty(t, client.BotHits[1].Rule)
+	assert.Equal(t, "/event", client.BotHits[2].Path)
+	assert.Equal(t, IgnoreBot, client.BotHits[2].Reason)
+	assert.Equal(t, "curl", client.BotHits[2].Rule)
+	metrics.m.Lock()
+	defer metrics.m.Unlock()
+	assert.Equal(t, 3, metrics.enqueued[KindBot])
+	assert.NotEmpty(t, metrics.batchSizes[KindBot])
+
+	client = NewMockClient()
+	tracker = NewTracker(client, "salt", &TrackerConfig{Worker: 1})
+	req = overflowRequest("/bot")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)")
+	assert.Equal(t, Ignored, tracker.Hit(req, nil))
+	tracker.Stop()
+	assert.Empty(t, client.BotHits)
+}
+
+func TestTrackerSaltRotation(t *testing.T) {
+	client := NewMockClient()
+	client.ReturnSession = &Session{}
+	tracker := NewTracker(client, "secret", &TrackerConfig{
+		Worker:       1,
+		SaltRotation: SaltDaily,
+	})
+	tracker.Hit(overflowRequest("/"), nil)
+	tracker.salt.rotate(time.Now().UTC().Add(time.Hour * 24))
+	tracker.Hit(overflowRequest("/foo"), nil)
+	tracker.salt.rotate(time.Now().UTC().Add(time.Hour * 48))
+	tracker.salt.rotated = time.Now().UTC().Add(-time.Hour)
+	tracker.Hit(overflowRequest("/bar"), nil)
+	tracker.Stop()
+	assert.Len(t, client.Hits, 3)
+	assert.NotEqual(t, client.Hits[0].Fingerprint, client.Hits[1].Fingerprint)
+	assert.NotEqual(t, client.Hits[1].Fingerprint, client.Hits[2].Fingerprint)
+	assert.Equal(t, client.Hits[0].Session, client.Hits[1].Session, "the session must be continued after the salt has been rotated")
+	assert.NotEqual(t, client.Hits[1].Session, client.Hits[2].Session, "the session must not be continued after the SessionMaxAge")
+}
+
+func TestTrackerFingerprinter(t *testing.T) {
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker: 1,
+		Fingerprinter: &RequestFingerprinter{
+			Inputs: []FingerprintInput{FingerprintUserAgent},
+			Hash:   FingerprintHMAC,
+		},
+	})
+	req := overflowRequest("/")
+	req.RemoteAddr = "127.0.0.1:80"
+	tracker.Hit(req, nil)
+	req = overflowRequest("/")
+	req.RemoteAddr = "127.0.0.2:80"
+	tracker.Hit(req, nil)
+	tracker.Hit(req, &HitOptions{Fingerprinter: &RequestFingerprinter{}})
+	tracker.Stop()
+	assert.Len(t, client.Hits, 3)
+	assert.Equal(t, client.Hits[0].Fingerprint, client.Hits[1].Fingerprint)
+	assert.Equal(t, Fingerprint(req, "salt"), client.Hits[2].Fingerprint)
+}
+
+func TestTrackerIPExtractor(t *testing.T) {
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker:      1,
+		IPExtractor: NginxIPExtractor(),
+	})
+	req := overflowRequest("/")
+	req.RemoteAddr = "23.21.45.67:29302"
+	tracker.Hit(req, nil)
+	req.Header.Set("X-Real-IP", "103.0.53.43")
+	tracker.Hit(req, nil)
+	tracker.Hit(req, &HitOptions{IPExtractor: AWSALBIPExtractor()})
+	tracker.Stop()
+	assert.Len(t, client.Hits, 3)
+	assert.Equal(t, client.Hits[0].Fingerprint, client.Hits[1].Fingerprint, "the spoofed header must be ignored")
+	assert.Equal(t, client.Hits[0].Fingerprint, client.Hits[2].Fingerprint)
+}
+
+func TestTrackerAnonymizeIP(t *testing.T) {
+	geoDB, err := NewGeoDB(GeoDBConfig{
+		File: filepath.Join("geodb/GeoIP2-Country-Test.mmdb"),
+	})
+	assert.NoError(t, err)
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker:      1,
+		AnonymizeIP: true,
+	})
+	tracker.SetGeoDB(geoDB)
+	req := overflowRequest("/")
+	req.RemoteAddr = "67.43.156.142:29302"
+	tracker.Hit(req, nil)
+	req.RemoteAddr = "67.43.156.143:29302"
+	tracker.Hit(req, nil)
+	req.RemoteAddr = "67.43.157.142:29302"
+	tracker.Hit(req, nil)
+	tracker.Stop()
+	assert.Len(t, client.Hits, 3)
+	assert.Equal(t, client.Hits[0].Fingerprint, client.Hits[1].Fingerprint, "the IPs must be truncated to /24")
+	assert.NotEqual(t, client.Hits[0].Fingerprint, client.Hits[2].Fingerprint)
+	assert.Equal(t, "bt", client.Hits[0].CountryCode)
+	assert.Equal(t, fingerprint(req.Header.Get("User-Agent"), "67.43.156.0", "salt"), client.Hits[0].Fingerprint)
+}
+
+func TestTrackerAnonymizeIPLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := log.New(&buffer, "", 0)
+	geoDB, err := NewGeoDB(GeoDBConfig{
+		File:   filepath.Join("geodb/GeoIP2-Country-Test.mmdb"),
+		Logger: logger,
+	})
+	assert.NoError(t, err)
+	client := &failingStore{MockClient: NewMockClient(), failTimes: 1}
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		Worker:      1,
+		AnonymizeIP: true,
+		Logger:      logger,
+	})
+	tracker.SetGeoDB(geoDB)
+	req := overflowRequest("/")
+	req.RemoteAddr = "81.2.69.142:29302"
+	tracker.Hit(req, nil)
+	req.RemoteAddr = "81.2.69.142.1"
+	tracker.Hit(req, nil)
+	tracker.Stop()
+	assert.NotEmpty(t, buffer.String())
+	assert.NotContains(t, buffer.String(), "81.2.69.142", "the IP must never be logged")
+}
+
+func overflowRequest(path string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	return req
+}
+
+// blockingStore blocks saving hits and events until release is closed.
+// The first call signals on saving.
+type blockingStore struct {
+	*MockClient
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func newBlockingStore() *blockingStore {
+	return &blockingStore{
+		MockClient: NewMockClient(),
+		saving:     make(chan struct{}, 1),
+		release:    make(chan struct{}),
+	}
+}
+
+func (store *blockingStore) SaveHits(hits []Hit) error {
+	store.wait()
+	return store.MockClient.SaveHits(hits)
+}
+
+func (store *blockingStore) SaveEvents(events []Event) error {
+	store.wait()
+	return store.MockClient.SaveEvents(events)
+}
+
+func (store *blockingStore) wait() {
+	select {
+	case store.saving <- struct{}{}:
+	default:
+	}
+
+	<-store.release
+}
+
+// testMetrics counts the metrics reported by the Tracker.
+type testMetrics struct {
+	enqueued          map[TrackingKind]int
+	ignored           map[string]int
+	dropped           map[TrackingKind]int
+	queueDepth        map[TrackingKind]int
+	batchSizes        map[TrackingKind][]int
+	saveErrors        int
+	sessionCache      map[SessionCacheResult]int
+	geoDBLookupFailed int
+	m                 sync.Mutex
+}
+
+func newTestMetrics() *testMetrics {
+	return &testMetrics{
+		enqueued:     make(map[TrackingKind]int),
+		ignored:      make(map[string]int),
+		dropped:      make(map[TrackingKind]int),
+		queueDepth:   make(map[TrackingKind]int),
+		batchSizes:   make(map[TrackingKind][]int),
+		sessionCache: make(map[SessionCacheResult]int),
+	}
+}
+
+func (metrics *testMetrics) Enqueued(kind TrackingKind) {
+	metrics.m.Lock()
+	defer metrics.m.Unlock()
+	metrics.enqueued[kind]++
+}
+
+func (metrics *testMetrics) Ignored(kind TrackingKind, reason IgnoreReason) {
+	metrics.m.Lock()
+	defer metrics.m.Unlock()
+	metrics.ignored[string(kind)+string(reason)]++
+}
+
+func (metrics *testMetrics) Dropped(kind TrackingKind) {
+	metrics.m.Lock()
+	defer metrics.m.Unlock()
+	metrics.dropped[kind]++
+}
+
+func (metrics *testMetrics) QueueDepth(kind TrackingKind, depth int) {
+	metrics.m.Lock()
+	defer metrics.m.Unlock()
+	metrics.queueDepth[kind] = depth
+}
+
+func (metrics *testMetrics) BatchSaved(kind TrackingKind, size int, duration time.Duration, err error) {
+	metrics.m.Lock()
+	defer metrics.m.Unlock()
+	metrics.batchSizes[kind] = append(metrics.batchSizes[kind], size)
+
+	if err != nil {
+		metrics.saveErrors++
+	}
+}
+
+func (metrics *testMetrics) SessionCache(result SessionCacheResult) {
+	metrics.m.Lock()
+	defer metrics.m.Unlock()
+	metrics.sessionCache[result]++
+}
+
+func (metrics *testMetrics) GeoDBLookupFailed() {
+	metrics.m.Lock()
+	defer metrics.m.Unlock()
+	metrics.geoDBLookupFailed++
+}
+
+// failingStore fails saving hits and events while fail is set, or for the next failTimes calls.
+type failingStore struct {
+	*MockClient
+	fail      int32
+	failTimes int32
+}
+
+func (store *failingStore) SaveHits(hits []Hit) error {
+	if store.failed() {
+		return errors.New("error saving hits")
+	}
+
+	return store.MockClient.SaveHits(hits)
+}
+
+func (store *failingStore) SaveEvents(events []Event) error {
+	if store.failed() {
+		return errors.New("error saving events")
+	}
+
+	return store.MockClient.SaveEvents(events)
+}
+
+func (store *failingStore) failed() bool {
+	return atomic.LoadInt32(&store.fail) > 0 || atomic.AddInt32(&store.failTimes, -1) >= 0
+}
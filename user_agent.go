@@ -24,6 +24,30 @@ const (
 	// BrowserIE represents the Internet Explorer browser.
 	BrowserIE = "IE"
 
+	// BrowserSamsung represents the Samsung Internet browser.
+	BrowserSamsung = "Samsung Internet"
+
+	// BrowserYandex represents the Yandex browser.
+	BrowserYandex = "Yandex"
+
+	// BrowserBrave represents the Brave browser.
+	BrowserBrave = "Brave"
+
+	// BrowserVivaldi represents the Vivaldi browser.
+	BrowserVivaldi = "Vivaldi"
+
+	// BrowserUC represents the UC browser.
+	BrowserUC = "UC Browser"
+
+	// BrowserFacebook represents the Facebook in-app browser.
+	BrowserFacebook = "Facebook"
+
+	// BrowserInstagram represents the Instagram in-app browser.
+	BrowserInstagram = "Instagram"
+
+	// BrowserAndroidWebView represents an Android WebView embedded in an app.
+	BrowserAndroidWebView = "Android WebView"
+
 	// OSWindows represents the Windows operating system.
 	OSWindows = "Windows"
 
@@ -42,6 +66,27 @@ const (
 	// OSWindowsMobile represents the Windows Mobile operating system.
 	OSWindowsMobile = "Windows Mobile"
 
+	// OSiPadOS represents the iPadOS operating system.
+	OSiPadOS = "iPadOS"
+
+	// OSChromeOS represents the Chrome operating system.
+	OSChromeOS = "Chrome OS"
+
+	// OSTizen represents the Tizen operating system used by Samsung smart TVs.
+	OSTizen = "Tizen"
+
+	// OSWebOS represents the webOS operating system used by LG smart TVs.
+	OSWebOS = "webOS"
+
+	// OSPlayStation represents the PlayStation game consoles.
+	OSPlayStation = "PlayStation"
+
+	// OSXbox represents the Xbox game consoles.
+	OSXbox = "Xbox"
+
+	// OSNintendo represents the Nintendo game consoles.
+	OSNintendo = "Nintendo"
+
 	// used to parse the User-Agent header
 	uaSystemLeftDelimiter     = '('
 	uaSystemRightDelimiter    = ')'
@@ -67,22 +112,35 @@ type UserAgent struct {
 
 // IsDesktop returns true if the user agent is a desktop device.
 func (ua *UserAgent) IsDesktop() bool {
-	return ua.OS == OSWindows || ua.OS == OSMac || ua.OS == OSLinux
+	return ua.OS == OSWindows || ua.OS == OSMac || ua.OS == OSLinux || ua.OS == OSChromeOS
 }
 
 // IsMobile returns true if the user agent is a mobile device.
 func (ua *UserAgent) IsMobile() bool {
-	return ua.OS == OSAndroid || ua.OS == OSiOS || ua.OS == OSWindowsMobile
+	return ua.OS == OSAndroid || ua.OS == OSiOS || ua.OS == OSiPadOS || ua.OS == OSWindowsMobile
 }
 
 // ParseUserAgent parses given User-Agent header and returns the extracted information.
 // This just supports major browsers and operating systems, we don't care about browsers and OSes that have no market share,
-// unless you prove us wrong.
+// unless you prove us wrong. Less common browsers and operating systems are detected using the UserAgentRules,
+// which can be extended using SetUserAgentRules.
 func ParseUserAgent(ua string) UserAgent {
 	system, products := parseUserAgent(ua)
 	userAgent := UserAgent{}
-	userAgent.OS, userAgent.OSVersion = getOS(system)
-	userAgent.Browser, userAgent.BrowserVersion = getBrowser(products, system, userAgent.OS)
+	rules := getUserAgentRules()
+	var found bool
+	userAgent.OS, userAgent.OSVersion, found = matchUserAgentRules(ua, 2, rules.OS, defaultUserAgentRules.OS)
+
+	if !found {
+		userAgent.OS, userAgent.OSVersion = getOS(system)
+	}
+
+	userAgent.Browser, userAgent.BrowserVersion, found = matchUserAgentRules(ua, 1, rules.Browser, defaultUserAgentRules.Browser)
+
+	if !found {
+		userAgent.Browser, userAgent.BrowserVersion = getBrowser(products, system, userAgent.OS)
+	}
+
 	return userAgent
 }
 
@@ -158,7 +216,7 @@ func getBrowser(products []string, system []string, os string) (string, string)
 
 	// When we made it to this point, it's gone get ugly and inaccurate, as Safari and Chrome send almost identical
 	// user agents most of the time. But anything coming from Mac or iOS is most likely Safari, I guess...
-	if (os == OSMac || os == OSiOS) && productSafari != "" && productChrome == "" {
+	if (os == OSMac || os == OSiOS || os == OSiPadOS) && productSafari != "" && productChrome == "" {
 		browser = BrowserSafari
 		version = getSafariVersion(products, productSafari)
 	} else if productChrome != "" {
@@ -13,6 +13,76 @@ var (
 		"QtWebEngine/",
 	}
 
+	// defaultUserAgentRules are the built-in rules to detect browsers and operating systems not covered by the parser.
+	// They are checked in order, so more specific rules (like in-app browsers) must come first.
+	defaultUserAgentRules = UserAgentRules{
+		Browser: []UserAgentRule{
+			{Name: BrowserFacebook, Contains: []string{"[FBAN/", "[FB_IAB/"}, Version: "FBAV/"},
+			{Name: BrowserInstagram, Contains: []string{" Instagram "}, Version: " Instagram "},
+			{Name: BrowserSamsung, Contains: []string{"SamsungBrowser/"}, Version: "SamsungBrowser/"},
+			{Name: BrowserYandex, Contains: []string{"YaBrowser/"}, Version: "YaBrowser/"},
+			{Name: BrowserVivaldi, Contains: []string{"Vivaldi/"}, Version: "Vivaldi/"},
+			{Name: BrowserUC, Contains: []string{"UCBrowser/"}, Version: "UCBrowser/"},
+			{Name: BrowserAndroidWebView, Contains: []string{"; wv)"}, Version: "Chrome/"},
+		},
+		OS: []UserAgentRule{
+			{Name: OSXbox, Contains: []string{"Xbox"}},
+			{Name: OSPlayStation, Contains: []string{"PlayStation"}},
+			{Name: OSNintendo, Contains: []string{"Nintendo"}},
+			{Name: OSTizen, Contains: []string{"Tizen "}, Version: "Tizen "},
+			{Name: OSWebOS, Contains: []string{"Web0S", "webOS"}},
+			{Name: OSChromeOS, Contains: []string{" CrOS "}},
+			{Name: OSiPadOS, Contains: []string{"(iPad;"}, Version: "CPU OS "},
+		},
+		Device: []UserAgentRule{
+			{Name: DeviceTypeConsole, Contains: []string{"Xbox", "PlayStation", "Nintendo"}},
+			{Name: DeviceTypeTV, Contains: []string{"SMART-TV", "SmartTV", "Smart-TV", "GoogleTV", "Android TV", "AndroidTV", "CrKey", "Roku", "BRAVIA", "HbbTV", "Web0S", "AppleTV"}},
+			{Name: DeviceTypeWearable, Contains: []string{"Wear OS", "Watch", "Glass"}},
+			{Name: DeviceTypeTablet, Contains: []string{"iPad", "Tablet", "Kindle", "Silk/", "PlayBook"}},
+		},
+	}
+
+	// knownBots are keywords (in lowercase User-Agents) of well-known bots and their names, checked in order.
+	// This is used to report bots by name instead of the generic keyword on the User-Agent blacklist.
+	knownBots = []struct {
+		keyword string
+		name    string
+	}{
+		{"googlebot", "Googlebot"},
+		{"adsbot-google", "AdsBot-Google"},
+		{"mediapartners-google", "Mediapartners-Google"},
+		{"google-inspectiontool", "Google-InspectionTool"},
+		{"bingbot", "Bingbot"},
+		{"bingpreview", "BingPreview"},
+		{"yandex", "YandexBot"},
+		{"baiduspider", "Baiduspider"},
+		{"duckduckbot", "DuckDuckBot"},
+		{"applebot", "Applebot"},
+		{"petalbot", "PetalBot"},
+		{"facebookexternalhit", "Facebook"},
+		{"twitterbot", "Twitterbot"},
+		{"linkedinbot", "LinkedInBot"},
+		{"slackbot", "Slackbot"},
+		{"discordbot", "Discordbot"},
+		{"telegrambot", "TelegramBot"},
+		{"whatsapp", "WhatsApp"},
+		{"ahrefsbot", "AhrefsBot"},
+		{"semrushbot", "SemrushBot"},
+		{"mj12bot", "MJ12bot"},
+		{"dotbot", "DotBot"},
+		{"gptbot", "GPTBot"},
+		{"chatgpt-user", "ChatGPT-User"},
+		{"claudebot", "ClaudeBot"},
+		{"ccbot", "CCBot"},
+		{"bytespider", "Bytespider"},
+		{"headlesschrome", "HeadlessChrome"},
+		{"python-requests", "python-requests"},
+		{"python-urllib", "Python-urllib"},
+		{"go-http-client", "Go-http-client"},
+		{"curl/", "curl"},
+		{"wget/", "Wget"},
+	}
+
 	// windowsVersions maps a Windows user agent versions to the product versions.
 	// https://en.wikipedia.org/wiki/List_of_Microsoft_Windows_versions
 	windowsVersions = map[string]string{
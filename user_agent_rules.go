@@ -0,0 +1,218 @@
+package pirsch
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var (
+	// ErrInvalidUserAgentRule is returned if a UserAgentRule has no name or no string to match.
+	ErrInvalidUserAgentRule = errors.New("user agent rules require a name and at least one string to match")
+
+	// ErrInvalidUserAgentBlacklist is returned if a keyword for the User-Agent blacklist is empty.
+	ErrInvalidUserAgentBlacklist = errors.New("user agent blacklist keywords must not be empty")
+
+	userAgentRules      UserAgentRules
+	userAgentRulesMutex sync.RWMutex
+
+	userAgentBlacklistMatcher      = newAhoCorasick(userAgentBlacklist)
+	userAgentBlacklistMatcherMutex sync.RWMutex
+)
+
+// UserAgentRule maps user agents to a browser, operating system, or device type.
+type UserAgentRule struct {
+	// Name is the browser, operating system, or device type stored for matching user agents.
+	Name string `json:"name"`
+
+	// Contains is a list of strings of which one must be contained in the User-Agent header for the rule to match.
+	Contains []string `json:"contains"`
+
+	// Version is the string preceding the version number in the User-Agent header, like "SamsungBrowser/".
+	// The version is left empty if not set.
+	Version string `json:"version"`
+}
+
+// UserAgentRules is a list of rules to detect browsers, operating systems, and device types.
+// Rules are checked in order and the first matching rule is used.
+type UserAgentRules struct {
+	// Browser is the list of rules to detect browsers.
+	Browser []UserAgentRule `json:"browser"`
+
+	// OS is the list of rules to detect operating systems.
+	OS []UserAgentRule `json:"os"`
+
+	// Device is the list of rules to detect device types (see DeviceTypeTablet, DeviceTypeTV, ...).
+	// Versions are ignored for device types.
+	Device []UserAgentRule `json:"device"`
+}
+
+// LoadUserAgentRules loads the UserAgentRules from given JSON file.
+func LoadUserAgentRules(file string) (*UserAgentRules, error) {
+	data, err := os.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rules := new(UserAgentRules)
+
+	if err := json.Unmarshal(data, rules); err != nil {
+		return nil, err
+	}
+
+	if err := rules.validate(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// SetUserAgentRules sets additional rules used by ParseUserAgent and GetDeviceType to detect browsers, operating systems, and device types.
+// The rules are checked before the built-in rules, so they can also be used to override them.
+// Passing nil removes all additional rules.
+func SetUserAgentRules(rules *UserAgentRules) error {
+	if rules == nil {
+		rules = new(UserAgentRules)
+	} else if err := rules.validate(); err != nil {
+		return err
+	}
+
+	userAgentRulesMutex.Lock()
+	defer userAgentRulesMutex.Unlock()
+	userAgentRules = *rules
+	return nil
+}
+
+func getUserAgentRules() UserAgentRules {
+	userAgentRulesMutex.RLock()
+	defer userAgentRulesMutex.RUnlock()
+	return userAgentRules
+}
+
+// SetUserAgentBlacklist replaces the list of keywords used to detect bots by their User-Agent.
+// The keywords are matched case-insensitive against the User-Agent header. Passing nil restores the built-in list.
+func SetUserAgentBlacklist(keywords []string) error {
+	if keywords == nil {
+		keywords = userAgentBlacklist
+	}
+
+	matcher, err := newUserAgentBlacklistMatcher(keywords)
+
+	if err != nil {
+		return err
+	}
+
+	userAgentBlacklistMatcherMutex.Lock()
+	defer userAgentBlacklistMatcherMutex.Unlock()
+	userAgentBlacklistMatcher = matcher
+	return nil
+}
+
+// AddUserAgentBlacklist adds given keywords to the list used to detect bots by their User-Agent, like the names of your own crawlers.
+// The keywords are matched case-insensitive against the User-Agent header.
+func AddUserAgentBlacklist(keywords ...string) error {
+	userAgentBlacklistMatcherMutex.Lock()
+	defer userAgentBlacklistMatcherMutex.Unlock()
+	list := make([]string, 0, len(userAgentBlacklistMatcher.keywords)+len(keywords))
+	list = append(list, userAgentBlacklistMatcher.keywords...)
+	matcher, err := newUserAgentBlacklistMatcher(append(list, keywords...))
+
+	if err != nil {
+		return err
+	}
+
+	userAgentBlacklistMatcher = matcher
+	return nil
+}
+
+func newUserAgentBlacklistMatcher(keywords []string) (*ahoCorasick, error) {
+	list := make([]string, len(keywords))
+
+	for i, keyword := range keywords {
+		if keyword == "" {
+			return nil, ErrInvalidUserAgentBlacklist
+		}
+
+		list[i] = strings.ToLower(keyword)
+	}
+
+	return newAhoCorasick(list), nil
+}
+
+func getUserAgentBlacklistMatcher() *ahoCorasick {
+	userAgentBlacklistMatcherMutex.RLock()
+	defer userAgentBlacklistMatcherMutex.RUnlock()
+	return userAgentBlacklistMatcher
+}
+
+func (rules *UserAgentRules) validate() error {
+	for _, list := range [][]UserAgentRule{rules.Browser, rules.OS, rules.Device} {
+		for _, rule := range list {
+			if rule.Name == "" || len(rule.Contains) == 0 {
+				return ErrInvalidUserAgentRule
+			}
+
+			for _, str := range rule.Contains {
+				if str == "" {
+					return ErrInvalidUserAgentRule
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// returns the name and version (with up to n dots) of the first rule matching given User-Agent
+func matchUserAgentRules(ua string, n int, rules ...[]UserAgentRule) (string, string, bool) {
+	for _, list := range rules {
+		for _, rule := range list {
+			for _, str := range rule.Contains {
+				if strings.Contains(ua, str) {
+					return rule.Name, getUserAgentRuleVersion(ua, rule.Version, n), true
+				}
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+func getUserAgentRuleVersion(ua, prefix string, n int) string {
+	if prefix == "" {
+		return ""
+	}
+
+	i := strings.Index(ua, prefix)
+
+	if i == -1 {
+		return ""
+	}
+
+	out := make([]rune, 0, 10)
+	dots := 0
+
+	for _, r := range ua[i+len(prefix):] {
+		if r == '_' {
+			r = uaVersionDelimiter
+		}
+
+		if r == uaVersionDelimiter {
+			dots++
+
+			if dots > n {
+				break
+			}
+		} else if !unicode.IsNumber(r) {
+			break
+		}
+
+		out = append(out, r)
+	}
+
+	return strings.TrimRight(string(out), ".")
+}
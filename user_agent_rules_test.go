@@ -0,0 +1,101 @@
+package pirsch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadUserAgentRules(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.json")
+	assert.NoError(t, os.WriteFile(file, []byte(`{
+		"browser": [{"name": "Crawler", "contains": ["InternalCrawler/"], "version": "InternalCrawler/"}],
+		"os": [{"name": "KaiOS", "contains": ["KAIOS/"], "version": "KAIOS/"}]
+	}`), 0644))
+	rules, err := LoadUserAgentRules(file)
+	assert.NoError(t, err)
+	assert.Len(t, rules.Browser, 1)
+	assert.Equal(t, "Crawler", rules.Browser[0].Name)
+	assert.Equal(t, []string{"InternalCrawler/"}, rules.Browser[0].Contains)
+	assert.Equal(t, "InternalCrawler/", rules.Browser[0].Version)
+	assert.Len(t, rules.OS, 1)
+	assert.Equal(t, "KaiOS", rules.OS[0].Name)
+	assert.NoError(t, os.WriteFile(file, []byte(`{"browser": [{"name": "Crawler"}]}`), 0644))
+	rules, err = LoadUserAgentRules(file)
+	assert.ErrorIs(t, err, ErrInvalidUserAgentRule)
+	assert.Nil(t, rules)
+	assert.NoError(t, os.WriteFile(file, []byte(`{"browser": [`), 0644))
+	_, err = LoadUserAgentRules(file)
+	assert.Error(t, err)
+	_, err = LoadUserAgentRules(filepath.Join(t.TempDir(), "not-found.json"))
+	assert.Error(t, err)
+}
+
+func TestSetUserAgentRules(t *testing.T) {
+	defer func() {
+		assert.NoError(t, SetUserAgentRules(nil))
+	}()
+	kaiOS := "Mozilla/5.0 (Mobile; LYF/F300B/LYF-F300B-001-01-15-130718-i; Android; rv:48.0) Gecko/48.0 Firefox/48.0 KAIOS/2.5"
+	samsung := "Mozilla/5.0 (Linux; Android 13; SAMSUNG SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Mobile Safari/537.36"
+	ua := ParseUserAgent(kaiOS)
+	assert.Equal(t, OSAndroid, ua.OS)
+	assert.Equal(t, BrowserFirefox, ua.Browser)
+	assert.NoError(t, SetUserAgentRules(&UserAgentRules{
+		Browser: []UserAgentRule{{Name: "Samsung", Contains: []string{"SamsungBrowser/"}}},
+		OS:      []UserAgentRule{{Name: "KaiOS", Contains: []string{"KAIOS/"}, Version: "KAIOS/"}},
+	}))
+	ua = ParseUserAgent(kaiOS)
+	assert.Equal(t, "KaiOS", ua.OS)
+	assert.Equal(t, "2.5", ua.OSVersion)
+	assert.Equal(t, BrowserFirefox, ua.Browser)
+	assert.Equal(t, "48.0", ua.BrowserVersion)
+	ua = ParseUserAgent(samsung)
+	assert.Equal(t, "Samsung", ua.Browser)
+	assert.Empty(t, ua.BrowserVersion)
+	assert.ErrorIs(t, SetUserAgentRules(&UserAgentRules{
+		OS: []UserAgentRule{{Name: "KaiOS", Contains: []string{""}}},
+	}), ErrInvalidUserAgentRule)
+	assert.Equal(t, "KaiOS", ParseUserAgent(kaiOS).OS)
+	assert.NoError(t, SetUserAgentRules(nil))
+	assert.Equal(t, OSAndroid, ParseUserAgent(kaiOS).OS)
+	assert.Equal(t, BrowserSamsung, ParseUserAgent(samsung).Browser)
+}
+
+func TestSetUserAgentBlacklist(t *testing.T) {
+	defer func() {
+		assert.NoError(t, SetUserAgentBlacklist(nil))
+	}()
+	crawler := "Mozilla/5.0 (compatible; Acme-Uptime/1.0)"
+	assert.Empty(t, matchBotUserAgent(strings.ToLower(crawler)))
+	assert.NoError(t, AddUserAgentBlacklist("Acme-Uptime"))
+	assert.Equal(t, "acme-uptime", matchBotUserAgent(strings.ToLower(crawler)))
+	assert.Equal(t, "bot", matchBotUserAgent("this is a bot request"))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", crawler)
+	assert.Equal(t, RequestClassification{IgnoreBot, "acme-uptime"}, ClassifyRequest(req))
+	assert.NoError(t, SetUserAgentBlacklist([]string{"Acme-Uptime"}))
+	assert.Equal(t, "acme-uptime", matchBotUserAgent(strings.ToLower(crawler)))
+	assert.Empty(t, matchBotUserAgent("this is a bot request"))
+	assert.ErrorIs(t, SetUserAgentBlacklist([]string{"bot", ""}), ErrInvalidUserAgentBlacklist)
+	assert.ErrorIs(t, AddUserAgentBlacklist(""), ErrInvalidUserAgentBlacklist)
+	assert.Empty(t, matchBotUserAgent("this is a bot request"))
+	assert.NoError(t, SetUserAgentBlacklist(nil))
+	assert.Empty(t, matchBotUserAgent(strings.ToLower(crawler)))
+	assert.Equal(t, "bot", matchBotUserAgent("this is a bot request"))
+}
+
+func TestGetUserAgentRuleVersion(t *testing.T) {
+	ua := "Mozilla/5.0 (iPad; CPU OS 16_7_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/119.0.6045.109 Mobile/15E148 Safari/604.1"
+	assert.Equal(t, "16.7.2", getUserAgentRuleVersion(ua, "CPU OS ", 2))
+	assert.Equal(t, "16.7", getUserAgentRuleVersion(ua, "CPU OS ", 1))
+	assert.Equal(t, "16", getUserAgentRuleVersion(ua, "CPU OS ", 0))
+	assert.Equal(t, "119.0", getUserAgentRuleVersion(ua, "CriOS/", 1))
+	assert.Equal(t, "604.1", getUserAgentRuleVersion(ua, "Safari/", 2))
+	assert.Empty(t, getUserAgentRuleVersion(ua, "Firefox/", 1))
+	assert.Empty(t, getUserAgentRuleVersion(ua, "", 1))
+	assert.Empty(t, getUserAgentRuleVersion(ua, "(", 1))
+}
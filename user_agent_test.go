@@ -14,6 +14,16 @@ func TestParseUserAgent(t *testing.T) {
 	assert.Equal(t, "79.0", ua.BrowserVersion)
 }
 
+func TestParseUserAgentRules(t *testing.T) {
+	for _, ua := range userAgentsRules {
+		userAgent := ParseUserAgent(ua.ua)
+		assert.Equal(t, ua.browser, userAgent.Browser, ua.ua)
+		assert.Equal(t, ua.browserVersion, userAgent.BrowserVersion, ua.ua)
+		assert.Equal(t, ua.os, userAgent.OS, ua.ua)
+		assert.Equal(t, ua.osVersion, userAgent.OSVersion, ua.ua)
+	}
+}
+
 func TestGetBrowser(t *testing.T) {
 	for _, ua := range userAgentsAll {
 		system, products := parseUserAgent(ua.ua)
@@ -446,6 +446,156 @@ var userAgentsIE = []testUserAgent{
 	},
 }
 
+var userAgentsRules = []testUserAgent{
+	{
+		ua:             "Mozilla/5.0 (Linux; Android 13; SAMSUNG SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Mobile Safari/537.36",
+		browser:        BrowserSamsung,
+		browserVersion: "23.0",
+		os:             OSAndroid,
+		osVersion:      "13",
+	},
+	{
+		ua:             "Mozilla/5.0 (Linux; Android 10; SAMSUNG SM-T510) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/14.2 Chrome/87.0.4280.141 Safari/537.36",
+		browser:        BrowserSamsung,
+		browserVersion: "14.2",
+		os:             OSAndroid,
+		osVersion:      "10",
+	},
+	{
+		ua:             "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 YaBrowser/23.11.0.0 Safari/537.36",
+		browser:        BrowserYandex,
+		browserVersion: "23.11",
+		os:             OSWindows,
+		osVersion:      "10",
+	},
+	{
+		ua:             "Mozilla/5.0 (Linux; arm_64; Android 12; M2101K6G) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.5735.295 YaBrowser/23.7.5.91.00 SA/3 Mobile Safari/537.36",
+		browser:        BrowserYandex,
+		browserVersion: "23.7",
+		os:             OSAndroid,
+		osVersion:      "12",
+	},
+	{
+		ua:             "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/92.0.4515.159 Safari/537.36 Vivaldi/4.1.2369.21",
+		browser:        BrowserVivaldi,
+		browserVersion: "4.1",
+		os:             OSWindows,
+		osVersion:      "10",
+	},
+	{
+		ua:             "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/90.0.4430.212 Safari/537.36 Vivaldi/3.8.2259.42",
+		browser:        BrowserVivaldi,
+		browserVersion: "3.8",
+		os:             OSLinux,
+		osVersion:      "",
+	},
+	{
+		ua:             "Mozilla/5.0 (Linux; U; Android 10; en-US; RMX1851 Build/QKQ1.190918.001) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/78.0.3904.108 UCBrowser/13.4.0.1306 Mobile Safari/537.36",
+		browser:        BrowserUC,
+		browserVersion: "13.4",
+		os:             OSAndroid,
+		osVersion:      "10",
+	},
+	{
+		ua:             "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 [FBAN/FBIOS;FBAV/437.0.0.39.110;FBBV/544566786;FBDV/iPhone14,5;FBMD/iPhone;FBSN/iOS;FBSV/17.0.3;FBSS/3;FBID/phone;FBLC/de_DE;FBOP/5]",
+		browser:        BrowserFacebook,
+		browserVersion: "437.0",
+		os:             OSiOS,
+		osVersion:      "17.0.3",
+	},
+	{
+		ua:             "Mozilla/5.0 (Linux; Android 13; Pixel 7 Build/TQ3A.230901.001; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/117.0.5938.60 Mobile Safari/537.36 [FB_IAB/FB4A;FBAV/438.0.0.33.118;]",
+		browser:        BrowserFacebook,
+		browserVersion: "438.0",
+		os:             OSAndroid,
+		osVersion:      "13",
+	},
+	{
+		ua:             "Mozilla/5.0 (Linux; Android 13; SM-G991B Build/TP1A.220624.014; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/117.0.5938.60 Mobile Safari/537.36 Instagram 305.0.0.34.110 Android (33/13; 420dpi; 1080x2176; samsung; SM-G991B; o1s; exynos2100; de_DE; 529083166)",
+		browser:        BrowserInstagram,
+		browserVersion: "305.0",
+		os:             OSAndroid,
+		osVersion:      "13",
+	},
+	{
+		ua:             "Mozilla/5.0 (iPhone; CPU iPhone OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Instagram 302.0.0.23.114 (iPhone13,2; iOS 16_6; de_DE; de; scale=3.00; 1170x2532; 522954766)",
+		browser:        BrowserInstagram,
+		browserVersion: "302.0",
+		os:             OSiOS,
+		osVersion:      "16.6",
+	},
+	{
+		ua:             "Mozilla/5.0 (Linux; Android 11; moto g(30) Build/RRMS31.Q1-14-15; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/116.0.5845.163 Mobile Safari/537.36",
+		browser:        BrowserAndroidWebView,
+		browserVersion: "116.0",
+		os:             OSAndroid,
+		osVersion:      "11",
+	},
+	{
+		ua:             "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36",
+		browser:        BrowserChrome,
+		browserVersion: "118.0",
+		os:             OSChromeOS,
+		osVersion:      "",
+	},
+	{
+		ua:             "Mozilla/5.0 (iPad; CPU OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+		browser:        BrowserSafari,
+		browserVersion: "17.1",
+		os:             OSiPadOS,
+		osVersion:      "17.1",
+	},
+	{
+		ua:             "Mozilla/5.0 (iPad; CPU OS 16_7_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/119.0.6045.109 Mobile/15E148 Safari/604.1",
+		browser:        BrowserChrome,
+		browserVersion: "119.0",
+		os:             OSiPadOS,
+		osVersion:      "16.7.2",
+	},
+	{
+		ua:             "Mozilla/5.0 (SMART-TV; LINUX; Tizen 6.0) AppleWebKit/537.36 (KHTML, like Gecko) 76.0.3809.146/6.0 TV Safari/537.36",
+		browser:        "",
+		browserVersion: "",
+		os:             OSTizen,
+		osVersion:      "6.0",
+	},
+	{
+		ua:             "Mozilla/5.0 (SMART-TV; Linux; Tizen 5.5) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/3.0 Chrome/69.0.3497.106 TV Safari/537.36",
+		browser:        BrowserSamsung,
+		browserVersion: "3.0",
+		os:             OSTizen,
+		osVersion:      "5.5",
+	},
+	{
+		ua:             "Mozilla/5.0 (Web0S; Linux/SmartTV) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/79.0.3945.79 Safari/537.36 WebAppManager",
+		browser:        BrowserChrome,
+		browserVersion: "79.0",
+		os:             OSWebOS,
+		osVersion:      "",
+	},
+	{
+		ua:             "Mozilla/5.0 (PlayStation; PlayStation 5/2.26) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.0 Safari/605.1.15",
+		browser:        "",
+		browserVersion: "",
+		os:             OSPlayStation,
+		osVersion:      "",
+	},
+	{
+		ua:             "Mozilla/5.0 (Windows NT 10.0; Win64; x64; Xbox; Xbox One) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/84.0.4147.135 Safari/537.36 Edge/44.18363.8131",
+		browser:        BrowserEdge,
+		browserVersion: "44.18363",
+		os:             OSXbox,
+		osVersion:      "",
+	},
+	{
+		ua:             "Mozilla/5.0 (Nintendo Switch; WifiWebAuthApplet) AppleWebKit/606.4 (KHTML, like Gecko) NF/6.0.1.15.4 NintendoBrowser/5.1.0.20393",
+		browser:        "",
+		browserVersion: "",
+		os:             OSNintendo,
+		osVersion:      "",
+	},
+}
+
 var userAgentsAll = mergeUserAgentLists(userAgentsEdge,
 	userAgentsOpera,
 	userAgentsFirefox,